@@ -0,0 +1,2 @@
+// Package aperturetest provides utilities for testing code that uses aperture.
+package aperturetest
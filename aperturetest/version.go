@@ -0,0 +1,67 @@
+package aperturetest
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/onsi/gomega/types"
+)
+
+// BeVersion returns a matcher that succeeds if the actual value is the
+// resource version used by ordered.Projector to record that o is the next
+// offset to be read from the stream.
+//
+// It decodes the version independently of the ordered/resource package so
+// that it detects changes to the version encoding.
+func BeVersion(o uint64) types.GomegaMatcher {
+	return &versionMatcher{expected: o}
+}
+
+type versionMatcher struct {
+	expected uint64
+}
+
+func (m *versionMatcher) Match(actual interface{}) (bool, error) {
+	o, err := decodeVersion(actual)
+	if err != nil {
+		return false, err
+	}
+
+	return o == m.expected, nil
+}
+
+func (m *versionMatcher) FailureMessage(actual interface{}) string {
+	o, _ := decodeVersion(actual)
+	return fmt.Sprintf(
+		"expected version for offset %d, got offset %d",
+		m.expected,
+		o,
+	)
+}
+
+func (m *versionMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf(
+		"expected version for any offset other than %d",
+		m.expected,
+	)
+}
+
+// decodeVersion returns the next offset encoded in the version v.
+func decodeVersion(v interface{}) (uint64, error) {
+	buf, ok := v.([]byte)
+	if !ok {
+		return 0, fmt.Errorf("BeVersion matcher expects a []byte, got %T", v)
+	}
+
+	switch len(buf) {
+	case 0:
+		return 0, nil
+	case 8:
+		return binary.BigEndian.Uint64(buf) + 1, nil
+	default:
+		return 0, fmt.Errorf(
+			"BeVersion matcher expects a version of 0 or 8 bytes, got %d byte(s)",
+			len(buf),
+		)
+	}
+}
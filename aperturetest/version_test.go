@@ -0,0 +1,45 @@
+package aperturetest_test
+
+import (
+	. "github.com/dogmatiq/aperture/aperturetest"
+	"github.com/dogmatiq/aperture/ordered/resource"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("func BeVersion()", func() {
+	It("matches the version for the given offset", func() {
+		Expect(resource.MarshalOffset(3)).To(BeVersion(3))
+		Expect(resource.MarshalOffset(3)).NotTo(BeVersion(4))
+	})
+
+	It("matches the empty version for offset zero", func() {
+		Expect([]byte(nil)).To(BeVersion(0))
+		Expect([]byte{}).To(BeVersion(0))
+	})
+
+	It("produces a readable failure message", func() {
+		m := BeVersion(2)
+
+		ok, err := m.Match(resource.MarshalOffset(4))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(m.FailureMessage(resource.MarshalOffset(4))).To(Equal(
+			"expected version for offset 2, got offset 4",
+		))
+	})
+
+	It("returns an error if the version is not the expected length", func() {
+		_, err := BeVersion(1).Match([]byte{0x01})
+		Expect(err).To(MatchError(
+			"BeVersion matcher expects a version of 0 or 8 bytes, got 1 byte(s)",
+		))
+	})
+
+	It("returns an error if the actual value is not a byte slice", func() {
+		_, err := BeVersion(1).Match("<version>")
+		Expect(err).To(MatchError(
+			"BeVersion matcher expects a []byte, got string",
+		))
+	})
+})
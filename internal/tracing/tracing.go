@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// HandlerName is the attribute key for the name of a projection handler.
+	HandlerName = attribute.Key("aperture.handler.name")
+
+	// HandlerKey is the attribute key for the identity key of a projection
+	// handler.
+	HandlerKey = attribute.Key("aperture.handler.key")
+
+	// StreamID is the attribute key for the ID of a stream.
+	StreamID = attribute.Key("aperture.stream.id")
+
+	// StreamOffset is the attribute key for an offset within a stream.
+	StreamOffset = attribute.Key("aperture.stream.offset")
+
+	// MessageType is the attribute key for the type of an event message.
+	MessageType = attribute.Key("aperture.message.type")
+
+	// BatchSize is the attribute key for the number of events in a batch.
+	BatchSize = attribute.Key("aperture.batch.size")
+)
+
+// InstrumentationName is the name of the tracer used when no tracer is
+// provided explicitly.
+const InstrumentationName = "github.com/dogmatiq/aperture"
+
+// Start starts a new span using t.
+//
+// If t is nil the span is started using a tracer from the provider of the span
+// in ctx, such that spans are recorded as children of the caller's span. If ctx
+// has no span, the resulting span is a no-op.
+func Start(
+	ctx context.Context,
+	t trace.Tracer,
+	name string,
+	attrs ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	if t == nil {
+		t = trace.SpanFromContext(ctx).TracerProvider().Tracer(InstrumentationName)
+	}
+
+	return t.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends the span, marking it as failed if err is non-nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// EndHandle ends a span that covers the handling of the event at the given
+// offset, marking it as failed if err is non-nil or if ok is false, which
+// indicates an optimistic concurrency conflict.
+//
+// A conflict is also recorded as a span event so that it is visible alongside
+// the event that caused the consumer to restart.
+func EndHandle(span trace.Span, offset uint64, ok bool, err error) {
+	if err == nil && !ok {
+		span.AddEvent(
+			"occ conflict, restarting consumer",
+			trace.WithAttributes(StreamOffset.Int64(int64(offset))),
+		)
+		span.SetStatus(codes.Error, "optimistic concurrency conflict")
+	}
+
+	End(span, err)
+}
@@ -0,0 +1,3 @@
+// Package marshaling provides an abstraction for converting event messages to
+// and from binary data, for use by stream implementations that persist events.
+package marshaling
@@ -0,0 +1,79 @@
+package marshaling
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/dogmatiq/dogma"
+	"github.com/dogmatiq/marshalkit"
+	"github.com/dogmatiq/marshalkit/codec"
+	"github.com/dogmatiq/marshalkit/codec/json"
+	"github.com/dogmatiq/marshalkit/codec/protobuf"
+)
+
+// KitMarshaler is an implementation of Marshaler that uses a marshalkit
+// marshaler.
+//
+// The type ID of each message is the MIME media-type of the marshaled packet,
+// which encodes both the message's portable type name and its encoding.
+type KitMarshaler struct {
+	// Marshaler is the underlying marshalkit marshaler.
+	Marshaler marshalkit.ValueMarshaler
+}
+
+var _ Marshaler = (*KitMarshaler)(nil)
+
+// NewMarshaler returns a marshaler that supports the types of the given
+// messages.
+//
+// Protocol Buffers messages are marshaled using the native binary encoding, all
+// other types are marshaled as JSON.
+func NewMarshaler(messages ...dogma.Message) (*KitMarshaler, error) {
+	types := make([]reflect.Type, len(messages))
+	for i, m := range messages {
+		types[i] = reflect.TypeOf(m)
+	}
+
+	m, err := codec.NewMarshaler(
+		types,
+		[]codec.Codec{
+			protobuf.DefaultNativeCodec,
+			json.DefaultCodec,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KitMarshaler{m}, nil
+}
+
+// Marshal returns a binary representation of m.
+func (k *KitMarshaler) Marshal(m dogma.Message) ([]byte, string, error) {
+	p, err := k.Marshaler.Marshal(m)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return p.Data, p.MediaType, nil
+}
+
+// Unmarshal produces a message from its binary representation.
+func (k *KitMarshaler) Unmarshal(typeID string, data []byte) (dogma.Message, error) {
+	v, err := k.Marshaler.Unmarshal(
+		marshalkit.Packet{
+			MediaType: typeID,
+			Data:      data,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := v.(dogma.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a dogma.Message", v)
+	}
+
+	return m, nil
+}
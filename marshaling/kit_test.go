@@ -0,0 +1,69 @@
+package marshaling_test
+
+import (
+	. "github.com/dogmatiq/aperture/marshaling"
+	. "github.com/dogmatiq/dogma/fixtures"
+	"github.com/dogmatiq/marshalkit"
+	"github.com/dogmatiq/marshalkit/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type KitMarshaler", func() {
+	var marshaler *KitMarshaler
+
+	BeforeEach(func() {
+		var err error
+		marshaler, err = NewMarshaler(MessageA{}, MessageB{})
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	Describe("func Marshal()", func() {
+		It("returns a type ID that depends only on the message type", func() {
+			_, a1, err := marshaler.Marshal(MessageA1)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			_, a2, err := marshaler.Marshal(MessageA2)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			_, b1, err := marshaler.Marshal(MessageB1)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			_, zero, err := marshaler.Marshal(MessageA{})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(a1).To(Equal(a2))
+			Expect(a1).To(Equal(zero))
+			Expect(a1).NotTo(Equal(b1))
+		})
+
+		It("returns an error if the message type is not supported", func() {
+			_, _, err := marshaler.Marshal(MessageC1)
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	Describe("func Unmarshal()", func() {
+		It("unmarshals the message", func() {
+			data, typeID, err := marshaler.Marshal(MessageA1)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			m, err := marshaler.Unmarshal(typeID, data)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(m).To(Equal(MessageA1))
+		})
+
+		It("returns an error if the type ID is not recognized", func() {
+			_, err := marshaler.Unmarshal("application/json; type=<unknown>", []byte(`{}`))
+			Expect(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if the value is not a message", func() {
+			marshaler.Marshaler = fixtures.Marshaler
+			p := marshalkit.MustMarshal(fixtures.Marshaler, &ProcessRoot{})
+
+			_, err := marshaler.Unmarshal(p.MediaType, p.Data)
+			Expect(err).To(MatchError("*fixtures.ProcessRoot is not a dogma.Message"))
+		})
+	})
+})
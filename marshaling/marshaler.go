@@ -0,0 +1,21 @@
+package marshaling
+
+import (
+	"github.com/dogmatiq/dogma"
+)
+
+// Marshaler is an interface for marshaling and unmarshaling event messages.
+//
+// Implementations must be safe for concurrent use.
+type Marshaler interface {
+	// Marshal returns a binary representation of m.
+	//
+	// typeID is an identifier for the type and encoding of the data, which
+	// must be passed to Unmarshal() along with the data. typeID must depend
+	// only on the message's type, not on its value, so that stream
+	// implementations can use it to filter events by type.
+	Marshal(m dogma.Message) (data []byte, typeID string, err error)
+
+	// Unmarshal produces a message from its binary representation.
+	Unmarshal(typeID string, data []byte) (dogma.Message, error)
+}
@@ -0,0 +1,294 @@
+package ordered
+
+import (
+	"context"
+	"time"
+
+	"github.com/dogmatiq/aperture/internal/tracing"
+	"github.com/dogmatiq/aperture/ordered/resource"
+	"github.com/dogmatiq/dodeca/logging"
+	"github.com/dogmatiq/dogma"
+	"github.com/dogmatiq/linger"
+)
+
+// BatchProjectionMessageHandler is a projection message handler that can apply
+// several events to a projection in a single operation, such as a single
+// database transaction.
+type BatchProjectionMessageHandler interface {
+	dogma.ProjectionMessageHandler
+
+	// HandleEventBatch updates the projection to reflect the occurrence of
+	// each of the events in the batch, in order.
+	//
+	// The OCC semantics are the same as those of HandleEvent(), except that
+	// c is the version of the resource before the first event in the batch is
+	// applied, and n is the version after the last event in the batch is
+	// applied. The batch is applied atomically; if ok is false none of the
+	// events are applied and the whole batch is delivered again.
+	HandleEventBatch(
+		ctx context.Context,
+		r, c, n []byte,
+		batch []BatchEvent,
+	) (ok bool, err error)
+}
+
+// BatchEvent is an event within a batch passed to
+// BatchProjectionMessageHandler.HandleEventBatch().
+type BatchEvent struct {
+	// Scope is the scope in which the event is handled.
+	Scope dogma.ProjectionEventScope
+
+	// Message is the event message.
+	Message dogma.Message
+}
+
+// consumeBatch reads a batch of messages from the stream then applies them to
+// the projection in a single call to the handler.
+//
+// The batch timer starts once the first event of the batch has been read. It
+// blocks until that first event is available, and hence never passes an empty
+// batch to the handler.
+func (p *Projector) consumeBatch(
+	ctx context.Context,
+	cur Cursor,
+	st *handlerState,
+	h BatchProjectionMessageHandler,
+) (bool, error) {
+	envs, readErr := p.readBatch(ctx, cur)
+	if len(envs) == 0 {
+		return false, readErr
+	}
+
+	if err := p.checkOrder(envs...); err != nil {
+		return false, err
+	}
+
+	p.reached(envs[0].Offset)
+
+	for _, env := range envs {
+		if !st.consumes(env) {
+			p.logReopen(env.Offset)
+			return false, nil
+		}
+	}
+
+	if p.next == nil {
+		p.next = make([]byte, 8)
+	}
+
+	last := envs[len(envs)-1]
+
+	// The version is advanced past the last real event in the batch, synthetic
+	// events do not advance the version.
+	var (
+		end        uint64
+		recordedAt time.Time
+		real       int
+	)
+	for _, env := range envs {
+		if !env.Synthetic {
+			end = env.Offset + 1
+			recordedAt = env.RecordedAt
+			real++
+		}
+	}
+
+	n := p.current
+	if real > 0 {
+		n = resource.MarshalOffsetInto(p.next, end)
+	}
+
+	var timeout time.Duration
+	batch := make([]BatchEvent, len(envs))
+	for i, env := range envs {
+		t, err := p.timeout(h, env)
+		if err != nil {
+			p.Metrics.handled(ctx, statusError, len(envs))
+			return false, &HandleError{env.Offset, err}
+		}
+
+		timeout += t
+		batch[i] = BatchEvent{
+			Scope:   p.eventScope(ctx, env),
+			Message: env.Message,
+		}
+	}
+
+	release, err := p.acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	ctx, stop := p.graceful(ctx)
+	defer stop()
+
+	ctx, cancel := context.WithTimeoutCause(
+		withEvent(ctx, p.name, envs[0].Offset),
+		timeout,
+		ErrHandleTimeout,
+	)
+	defer cancel()
+
+	ok, err := p.handleEventBatch(ctx, h, envs, n, batch)
+	if err != nil {
+		p.Metrics.handled(ctx, statusError, len(envs))
+		return false, &HandleError{envs[0].Offset, err}
+	}
+
+	if ok {
+		if real > 0 {
+			if err := p.save(ctx, end); err != nil {
+				return false, err
+			}
+
+			p.advance(real, end)
+			p.reached(end)
+			p.Metrics.lagged(ctx, recordedAt)
+		}
+
+		p.Metrics.handled(ctx, statusHandled, len(envs))
+		return readErr == nil, readErr
+	}
+
+	p.Metrics.handled(ctx, statusConflict, len(envs))
+
+	if err := p.conflict(envs[0].Offset); err != nil {
+		return false, err
+	}
+
+	logging.Log(
+		p.Logger,
+		"[%s %s@%d-%d] an optimisitic concurrency conflict occurred, restarting the consumer",
+		p.name,
+		p.resource,
+		envs[0].Offset,
+		last.Offset,
+	)
+
+	return false, nil
+}
+
+// handleEventBatch calls the handler's HandleEventBatch() method within a
+// span, recovering from panics if p.RecoverHandlerPanics is true.
+func (p *Projector) handleEventBatch(
+	ctx context.Context,
+	h BatchProjectionMessageHandler,
+	envs []Envelope,
+	n []byte,
+	batch []BatchEvent,
+) (ok bool, err error) {
+	ctx, span := tracing.Start(
+		ctx,
+		p.Tracer,
+		"aperture.handle.batch",
+		tracing.HandlerName.String(p.name),
+		tracing.HandlerKey.String(p.key),
+		tracing.StreamID.String(p.Stream.ID()),
+		tracing.StreamOffset.Int64(int64(envs[0].Offset)),
+		tracing.BatchSize.Int(len(envs)),
+	)
+	defer func() {
+		tracing.EndHandle(span, envs[0].Offset, ok, err)
+	}()
+
+	// The batch may contain events of several types, so the panic is not
+	// attributed to the type of any single event.
+	defer p.recoverPanic(&err, "HandleEventBatch", Envelope{Offset: envs[0].Offset})
+
+	p.handling.Store(true)
+	defer p.handling.Store(false)
+
+	return h.HandleEventBatch(
+		ctx,
+		p.resource,
+		p.current,
+		n,
+		batch,
+	)
+}
+
+// readBatch reads up to p.BatchSize events from the cursor.
+//
+// It blocks until at least one event is available, then continues to read
+// until the batch is full or the batch timeout elapses. If the batch timeout is
+// negative it only reads the events that are immediately available.
+//
+// If an error occurs after some events have been read, those events are
+// returned along with the error so that they may be applied before the error
+// is reported. If the error occurred because ctx was canceled, the partial
+// batch is discarded instead, as it can not be applied; the events are read
+// again when the consumer restarts.
+func (p *Projector) readBatch(ctx context.Context, cur Cursor) ([]Envelope, error) {
+	envs, err := p.fillBatch(ctx, cur)
+	if err != nil && ctx.Err() != nil {
+		return nil, err
+	}
+
+	return envs, err
+}
+
+// fillBatch reads up to p.BatchSize events from the cursor, as described by
+// readBatch().
+func (p *Projector) fillBatch(ctx context.Context, cur Cursor) ([]Envelope, error) {
+	env, err := cur.Next(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	envs := []Envelope{env}
+
+	if p.BatchTimeout < 0 {
+		return p.drainBatch(ctx, cur, envs)
+	}
+
+	bctx, cancel := linger.ContextWithTimeout(
+		ctx,
+		p.BatchTimeout,
+		DefaultBatchTimeout,
+	)
+	defer cancel()
+
+	for len(envs) < p.BatchSize {
+		env, err := cur.Next(bctx)
+		if err != nil {
+			if ctx.Err() == nil && bctx.Err() != nil {
+				// The batch timeout elapsed, flush the partial batch.
+				return envs, nil
+			}
+
+			return envs, err
+		}
+
+		envs = append(envs, env)
+	}
+
+	return envs, nil
+}
+
+// drainBatch adds the events that are immediately available from the cursor to
+// envs, until the batch is full.
+//
+// If the cursor does not implement NonBlockingCursor no further events are
+// added to the batch.
+func (p *Projector) drainBatch(
+	ctx context.Context,
+	cur Cursor,
+	envs []Envelope,
+) ([]Envelope, error) {
+	c, ok := cur.(NonBlockingCursor)
+	if !ok {
+		return envs, nil
+	}
+
+	for len(envs) < p.BatchSize {
+		env, ok, err := c.TryNext(ctx)
+		if err != nil || !ok {
+			return envs, err
+		}
+
+		envs = append(envs, env)
+	}
+
+	return envs, nil
+}
@@ -0,0 +1,356 @@
+package ordered_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/dogmatiq/aperture/aperturetest"
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// batchHandler is a test implementation of BatchProjectionMessageHandler.
+type batchHandler struct {
+	ProjectionMessageHandler
+
+	HandleEventBatchFunc func(
+		ctx context.Context,
+		r, c, n []byte,
+		batch []BatchEvent,
+	) (bool, error)
+}
+
+func (h *batchHandler) HandleEventBatch(
+	ctx context.Context,
+	r, c, n []byte,
+	batch []BatchEvent,
+) (bool, error) {
+	if h.HandleEventBatchFunc != nil {
+		return h.HandleEventBatchFunc(ctx, r, c, n, batch)
+	}
+
+	return true, nil
+}
+
+// messagesOf returns the messages in a batch.
+func messagesOf(batch []BatchEvent) []dogma.Message {
+	var messages []dogma.Message
+	for _, ev := range batch {
+		messages = append(messages, ev.Message)
+	}
+	return messages
+}
+
+var _ = Describe("type Projector (batching)", func() {
+	var (
+		now     time.Time
+		ctx     context.Context
+		cancel  func()
+		stream  *MemoryStream
+		handler *batchHandler
+		proj    *Projector
+	)
+
+	BeforeEach(func() {
+		now = time.Now()
+
+		ctx, cancel = context.WithTimeout(context.Background(), DefaultTimeout*2)
+
+		stream = &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		stream.Append(
+			now,
+			MessageA1,
+			MessageB1,
+			MessageA2,
+			MessageB2,
+			MessageA3,
+			MessageB3,
+		)
+
+		handler = &batchHandler{
+			ProjectionMessageHandler: ProjectionMessageHandler{
+				ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+					c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+					c.ConsumesEventType(MessageA{})
+				},
+			},
+		}
+
+		proj = &Projector{
+			Stream:       stream,
+			Handler:      handler,
+			BatchSize:    2,
+			BatchTimeout: 50 * time.Millisecond,
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Describe("func Run()", func() {
+		It("passes the filtered events to the handler in batches", func() {
+			var batches [][]dogma.Message
+			handler.HandleEventBatchFunc = func(
+				_ context.Context,
+				_, _, _ []byte,
+				batch []BatchEvent,
+			) (bool, error) {
+				batches = append(batches, messagesOf(batch))
+
+				if len(batches) == 2 {
+					cancel()
+				}
+
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+			Expect(batches).To(Equal(
+				[][]dogma.Message{
+					{MessageA1, MessageA2},
+					{MessageA3},
+				},
+			))
+		})
+
+		It("flushes a partial batch when the batch timeout elapses", func() {
+			proj.BatchSize = 10
+
+			start := time.Now()
+			handler.HandleEventBatchFunc = func(
+				_ context.Context,
+				_, _, _ []byte,
+				batch []BatchEvent,
+			) (bool, error) {
+				Expect(time.Since(start)).To(BeNumerically(">=", proj.BatchTimeout))
+				Expect(messagesOf(batch)).To(Equal(
+					[]dogma.Message{MessageA1, MessageA2, MessageA3},
+				))
+				cancel()
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+		})
+
+		Context("when the batch timeout is negative", func() {
+			BeforeEach(func() {
+				proj.BatchSize = 10
+				proj.BatchTimeout = -1
+			})
+
+			It("passes the events that are immediately available without waiting", func() {
+				start := time.Now()
+				handler.HandleEventBatchFunc = func(
+					_ context.Context,
+					_, _, _ []byte,
+					batch []BatchEvent,
+				) (bool, error) {
+					Expect(time.Since(start)).To(BeNumerically("<", DefaultBatchTimeout))
+					Expect(messagesOf(batch)).To(Equal(
+						[]dogma.Message{MessageA1, MessageA2, MessageA3},
+					))
+					cancel()
+					return true, nil
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+			})
+
+			It("does not exceed the batch size", func() {
+				proj.BatchSize = 2
+
+				var batches [][]dogma.Message
+				handler.HandleEventBatchFunc = func(
+					_ context.Context,
+					_, _, _ []byte,
+					batch []BatchEvent,
+				) (bool, error) {
+					batches = append(batches, messagesOf(batch))
+
+					if len(batches) == 2 {
+						cancel()
+					}
+
+					return true, nil
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+				Expect(batches).To(Equal(
+					[][]dogma.Message{
+						{MessageA1, MessageA2},
+						{MessageA3},
+					},
+				))
+			})
+		})
+
+		It("passes a scope for each event in the batch", func() {
+			handler.HandleEventBatchFunc = func(
+				_ context.Context,
+				_, _, _ []byte,
+				batch []BatchEvent,
+			) (bool, error) {
+				for _, ev := range batch {
+					Expect(ev.Scope.RecordedAt()).To(BeTemporally("==", now))
+				}
+				cancel()
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+		})
+
+		It("passes versions that span the whole batch", func() {
+			var calls int
+			handler.HandleEventBatchFunc = func(
+				_ context.Context,
+				r, c, n []byte,
+				_ []BatchEvent,
+			) (bool, error) {
+				calls++
+				Expect(r).To(Equal([]byte("<id>")))
+
+				if calls == 1 {
+					Expect(c).To(BeEmpty())
+					Expect(n).To(BeVersion(3))
+					return true, nil
+				}
+
+				Expect(c).To(BeVersion(3))
+				Expect(n).To(BeVersion(5))
+				cancel()
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+		})
+
+		It("delivers the whole batch again when a conflict occurs", func() {
+			var batches [][]dogma.Message
+			handler.HandleEventBatchFunc = func(
+				_ context.Context,
+				_, _, _ []byte,
+				batch []BatchEvent,
+			) (bool, error) {
+				batches = append(batches, messagesOf(batch))
+
+				if len(batches) == 2 {
+					cancel()
+				}
+
+				return false, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+			Expect(batches).To(Equal(
+				[][]dogma.Message{
+					{MessageA1, MessageA2},
+					{MessageA1, MessageA2},
+				},
+			))
+		})
+
+		It("returns an error if the handler returns an error", func() {
+			handler.HandleEventBatchFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				[]BatchEvent,
+			) (bool, error) {
+				return false, errors.New("<error>")
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(MatchError(
+				"unable to consume from '<id>' for the '<proj>' projection: <error>",
+			))
+		})
+
+		It("does not pass a partial batch to the handler if the context is canceled while reading", func() {
+			proj.BatchSize = 10
+			proj.BatchTimeout = time.Minute
+
+			handler.HandleEventBatchFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				[]BatchEvent,
+			) (bool, error) {
+				Fail("unexpected call to HandleEventBatch()")
+				return false, nil
+			}
+
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				cancel()
+			}()
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+		})
+
+		It("returns a PanicError if the handler panics and RecoverHandlerPanics is true", func() {
+			proj.RecoverHandlerPanics = true
+
+			handler.HandleEventBatchFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				[]BatchEvent,
+			) (bool, error) {
+				panic("<panic>")
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(MatchError(
+				"unable to consume from '<id>' for the '<proj>' projection: handler panicked while handling a batch of events starting at offset 0: <panic>",
+			))
+
+			var panicErr *PanicError
+			Expect(errors.As(err, &panicErr)).To(BeTrue())
+			Expect(panicErr.Method).To(Equal("HandleEventBatch"))
+			Expect(panicErr.Offset).To(BeNumerically("==", 0))
+			Expect(panicErr.Stack).NotTo(BeEmpty())
+		})
+
+		It("handles events individually if the batch size is not greater than one", func() {
+			proj.BatchSize = 1
+
+			handler.HandleEventBatchFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				[]BatchEvent,
+			) (bool, error) {
+				Fail("unexpected call to HandleEventBatch()")
+				return false, nil
+			}
+
+			handler.HandleEventFunc = func(
+				_ context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				m dogma.Message,
+			) (bool, error) {
+				Expect(m).To(Equal(MessageA1))
+				cancel()
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+		})
+	})
+})
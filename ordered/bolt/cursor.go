@@ -0,0 +1,164 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/dogmatiq/aperture/ordered"
+	"go.etcd.io/bbolt"
+)
+
+var errCursorClosed = errors.New("cursor is closed")
+
+var _ ordered.NonBlockingCursor = (*cursor)(nil)
+
+// cursor is an implementation of ordered.Cursor that reads events from a
+// bbolt database.
+type cursor struct {
+	stream    *Stream
+	offset    uint64
+	filter    map[string]struct{}
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// Next returns the next relevant event in the stream.
+//
+// If the end of the stream is reached it blocks until a relevant event is
+// appended to the stream, ctx is canceled or the stream is sealed. If the
+// stream is sealed, ordered.ErrStreamSealed is returned.
+func (c *cursor) Next(ctx context.Context) (ordered.Envelope, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return ordered.Envelope{}, ctx.Err()
+		case <-c.closed:
+			return ordered.Envelope{}, errCursorClosed
+		default:
+		}
+
+		// The channel is obtained before reading so that an append that
+		// commits after the read transaction begins is not missed.
+		ready := c.stream.wait()
+
+		env, ok, err := c.get()
+		if err != nil || ok {
+			return env, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ordered.Envelope{}, ctx.Err()
+		case <-c.closed:
+			return ordered.Envelope{}, errCursorClosed
+		case <-ready:
+		}
+	}
+}
+
+// TryNext returns the next relevant event in the stream, if one is immediately
+// available.
+//
+// ok is false if the end of the stream has been reached. If the stream is
+// sealed, ordered.ErrStreamSealed is returned.
+func (c *cursor) TryNext(ctx context.Context) (ordered.Envelope, bool, error) {
+	select {
+	case <-ctx.Done():
+		return ordered.Envelope{}, false, ctx.Err()
+	case <-c.closed:
+		return ordered.Envelope{}, false, errCursorClosed
+	default:
+	}
+
+	return c.get()
+}
+
+// Close stops the cursor.
+//
+// Any current or future calls to Next() return a non-nil error.
+func (c *cursor) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+
+	return nil
+}
+
+// get reads the next relevant event within a read-only transaction and
+// advances the cursor past it.
+//
+// It advances the cursor past any irrelevant events. ok is false if there are
+// no more relevant events.
+func (c *cursor) get() (env ordered.Envelope, ok bool, err error) {
+	err = c.stream.DB.View(func(tx *bbolt.Tx) error {
+		b := c.stream.bucket(tx)
+		m := readMeta(b)
+
+		if c.offset < m.first {
+			return &ordered.TruncatedError{
+				RequestedOffset: c.offset,
+				FirstOffset:     m.first,
+			}
+		}
+
+		if b != nil {
+			cur := b.Bucket(eventsBucket).Cursor()
+
+			for k, v := cur.Seek(offsetKey(c.offset)); k != nil; k, v = cur.Next() {
+				offset, err := keyOffset(k)
+				if err != nil {
+					return err
+				}
+
+				e, err := unmarshalEvent(v)
+				if err != nil {
+					return fmt.Errorf("unable to read event at offset %d: %w", offset, err)
+				}
+
+				if c.filter != nil {
+					if _, ok := c.filter[e.TypeID]; !ok {
+						continue
+					}
+				}
+
+				// The data is only valid for the lifetime of the transaction,
+				// so it is copied in case the marshaler retains it.
+				msg, err := c.stream.Marshaler.Unmarshal(e.TypeID, bytes.Clone(e.Data))
+				if err != nil {
+					return fmt.Errorf("unable to unmarshal event at offset %d: %w", offset, err)
+				}
+
+				env = ordered.Envelope{
+					Offset:     offset,
+					RecordedAt: e.RecordedAt,
+					Message:    msg,
+					MessageID:  ordered.MessageID(c.stream.ID(), offset),
+				}
+				ok = true
+
+				return nil
+			}
+		}
+
+		// Every remaining event is irrelevant, there is no need to read them
+		// again.
+		if c.offset < m.next {
+			c.offset = m.next
+		}
+
+		if m.sealed {
+			return ordered.ErrStreamSealed
+		}
+
+		return nil
+	})
+
+	if ok {
+		c.offset = env.Offset + 1
+	}
+
+	return env, ok, err
+}
@@ -0,0 +1,3 @@
+// Package bolt provides an implementation of ordered.Stream that stores events
+// in an embedded bbolt database.
+package bolt
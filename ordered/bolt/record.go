@@ -0,0 +1,79 @@
+package bolt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Each event is stored in the stream's events bucket, keyed by its offset
+// encoded as an 8-byte big-endian integer, such that the events are sorted by
+// offset.
+//
+// The value contains the time at which the event was recorded as nanoseconds
+// since the Unix epoch (8 bytes), the length of the type ID (2 bytes), the
+// type ID itself and finally the marshaled message data.
+const eventHeaderSize = 8 + 2
+
+// event is a single event stored within the database.
+type event struct {
+	RecordedAt time.Time
+	TypeID     string
+	Data       []byte
+}
+
+// offsetKey returns the key of the event at the given offset.
+func offsetKey(offset uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, offset)
+}
+
+// keyOffset returns the offset of the event with the given key.
+func keyOffset(k []byte) (uint64, error) {
+	if len(k) != 8 {
+		return 0, fmt.Errorf("event key is %d byte(s), expected 8", len(k))
+	}
+
+	return binary.BigEndian.Uint64(k), nil
+}
+
+// marshalEvent returns the binary representation of e.
+func marshalEvent(e event) ([]byte, error) {
+	if len(e.TypeID) > 0xffff {
+		return nil, fmt.Errorf("type ID is too long (%d bytes)", len(e.TypeID))
+	}
+
+	buf := make([]byte, 0, eventHeaderSize+len(e.TypeID)+len(e.Data))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(e.RecordedAt.UnixNano()))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(e.TypeID)))
+	buf = append(buf, e.TypeID...)
+	buf = append(buf, e.Data...)
+
+	return buf, nil
+}
+
+// unmarshalEvent decodes the binary representation of an event.
+//
+// The returned event refers to the memory of v, which is only valid for the
+// lifetime of the transaction from which it was read.
+func unmarshalEvent(v []byte) (event, error) {
+	if len(v) < eventHeaderSize {
+		return event{}, errors.New("event is too short")
+	}
+
+	e := event{
+		RecordedAt: time.Unix(0, int64(binary.BigEndian.Uint64(v))),
+	}
+
+	n := int(binary.BigEndian.Uint16(v[8:]))
+	v = v[eventHeaderSize:]
+
+	if len(v) < n {
+		return event{}, errors.New("type ID is truncated")
+	}
+
+	e.TypeID = string(v[:n])
+	e.Data = v[n:]
+
+	return e, nil
+}
@@ -0,0 +1,376 @@
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dogmatiq/aperture/marshaling"
+	"github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+	"go.etcd.io/bbolt"
+)
+
+var (
+	// rootBucket is the name of the bucket that contains a bucket for each
+	// stream.
+	rootBucket = []byte("aperture")
+
+	// eventsBucket is the name of the bucket within each stream's bucket that
+	// contains the stream's events.
+	eventsBucket = []byte("events")
+
+	// Keys within each stream's bucket that hold the stream's metadata.
+	firstKey  = []byte("first")
+	nextKey   = []byte("next")
+	sealedKey = []byte("sealed")
+)
+
+// Stream is an implementation of ordered.Stream that stores events in a bbolt
+// database.
+//
+// Each stream is stored in its own bucket, so streams with different IDs may
+// share the same database. Events are keyed by their offset.
+//
+// Each call to a cursor's Next() method reads from its own read-only
+// transaction, and hence observes a consistent view of the stream. Cursors are
+// only notified of events appended via the same Stream value; events appended
+// by other processes are not observed until the cursor is next woken.
+type Stream struct {
+	// StreamID is a unique identifier for the stream, it must not be empty.
+	// The tuple of stream ID and event offset must uniquely identify a message.
+	StreamID string
+
+	// DB is the database in which the events are stored. The stream does not
+	// close the database.
+	DB *bbolt.DB
+
+	// Marshaler is used to marshal and unmarshal event messages. It must
+	// support every type of message appended to the stream.
+	Marshaler marshaling.Marshaler
+
+	m     sync.Mutex
+	ready chan struct{}
+}
+
+var (
+	_ ordered.HeadStream   = (*Stream)(nil)
+	_ ordered.BoundsStream = (*Stream)(nil)
+	_ ordered.SealedStream = (*Stream)(nil)
+)
+
+// ID returns a unique identifier for the stream.
+//
+// The tuple of stream ID and event offset must uniquely identify a message.
+func (s *Stream) ID() string {
+	if s.StreamID == "" {
+		panic("stream ID must not be empty")
+	}
+
+	return s.StreamID
+}
+
+// Open returns a cursor used to read events from this stream.
+//
+// offset is the position of the first event to read. The first event on a
+// stream is always at offset 0. If the given offset is beyond the end of a
+// sealed stream, a *ordered.SealedError is returned.
+//
+// filter is a set of zero-value event messages, the types of which indicate
+// which event types are returned by Cursor.Next(). If filter is empty, all
+// events types are returned. The type ID of each filter type is obtained
+// by marshaling the zero-value message. Types that are not supported by the
+// marshaler can never appear on the stream, and hence are ignored.
+func (s *Stream) Open(
+	ctx context.Context,
+	offset uint64,
+	filter []dogma.Message,
+) (ordered.Cursor, error) {
+	var m meta
+
+	if err := s.DB.View(func(tx *bbolt.Tx) error {
+		m = readMeta(s.bucket(tx))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if m.sealed && offset >= m.next {
+		err := &ordered.SealedError{RequestedOffset: offset}
+		if m.next > 0 {
+			err.LastOffset = m.next - 1
+		}
+		return nil, err
+	}
+
+	return &cursor{
+		stream: s,
+		offset: offset,
+		filter: s.typeIDs(filter),
+		closed: make(chan struct{}),
+	}, nil
+}
+
+// Head returns the offset of the stream's head, that is, the offset at which
+// the next event will be appended.
+//
+// final is true if the stream is sealed, in which case the head never changes.
+func (s *Stream) Head(ctx context.Context) (offset uint64, final bool, err error) {
+	err = s.DB.View(func(tx *bbolt.Tx) error {
+		m := readMeta(s.bucket(tx))
+		offset, final = m.next, m.sealed
+		return nil
+	})
+
+	return offset, final, err
+}
+
+// IsSealed returns true if the stream has been sealed.
+func (s *Stream) IsSealed(ctx context.Context) (sealed bool, err error) {
+	err = s.DB.View(func(tx *bbolt.Tx) error {
+		sealed = readMeta(s.bucket(tx)).sealed
+		return nil
+	})
+
+	return sealed, err
+}
+
+// Bounds returns the range of offsets of the events that are available on the
+// stream.
+//
+// first is the offset of the first event that has not been truncated. next is
+// the offset at which the next event will be appended.
+func (s *Stream) Bounds(ctx context.Context) (first, next uint64, err error) {
+	err = s.DB.View(func(tx *bbolt.Tx) error {
+		m := readMeta(s.bucket(tx))
+		first, next = m.first, m.next
+		return nil
+	})
+
+	return first, next, err
+}
+
+// Append appends messages to the end of the stream.
+//
+// Every message is recorded at the same time, t. The messages are written
+// within a single transaction, so either all of them are appended or none are.
+//
+// It returns an error if the stream is sealed.
+func (s *Stream) Append(t time.Time, messages ...dogma.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	values := make([][]byte, len(messages))
+	for i, m := range messages {
+		data, id, err := s.Marshaler.Marshal(m)
+		if err != nil {
+			return fmt.Errorf("unable to marshal %T message: %w", m, err)
+		}
+
+		values[i], err = marshalEvent(event{t, id, data})
+		if err != nil {
+			return fmt.Errorf("unable to append %T message: %w", m, err)
+		}
+	}
+
+	if err := s.update(func(b *bbolt.Bucket, m *meta) error {
+		if m.sealed {
+			return errors.New("can not append to a sealed stream")
+		}
+
+		events := b.Bucket(eventsBucket)
+
+		for _, v := range values {
+			if err := events.Put(offsetKey(m.next), v); err != nil {
+				return err
+			}
+			m.next++
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	s.notify()
+
+	return nil
+}
+
+// Truncate deletes any events before the given offset.
+//
+// It returns the number of deleted events. It returns an error if the offset
+// is greater than the offset of the stream's head. Cursors that attempt to
+// read a deleted event return an *ordered.TruncatedError.
+func (s *Stream) Truncate(offset uint64) (count uint64, err error) {
+	err = s.update(func(b *bbolt.Bucket, m *meta) error {
+		if offset > m.next {
+			return fmt.Errorf(
+				"can not truncate stream to offset %d, next offset is %d",
+				offset,
+				m.next,
+			)
+		}
+
+		events := b.Bucket(eventsBucket)
+
+		for ; m.first < offset; m.first++ {
+			if err := events.Delete(offsetKey(m.first)); err != nil {
+				return err
+			}
+			count++
+		}
+
+		return nil
+	})
+
+	return count, err
+}
+
+// Seal marks the stream as sealed, preventing new events from being appended.
+//
+// The sealed flag is stored in the database, so the stream remains sealed when
+// the database is opened again.
+func (s *Stream) Seal() error {
+	if err := s.update(func(_ *bbolt.Bucket, m *meta) error {
+		m.sealed = true
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	s.notify()
+
+	return nil
+}
+
+// typeIDs returns the set of type IDs of the messages in filter, or nil if
+// filter is empty.
+func (s *Stream) typeIDs(filter []dogma.Message) map[string]struct{} {
+	if len(filter) == 0 {
+		return nil
+	}
+
+	ids := map[string]struct{}{}
+
+	for _, m := range filter {
+		if _, id, err := s.Marshaler.Marshal(m); err == nil {
+			ids[id] = struct{}{}
+		}
+	}
+
+	return ids
+}
+
+// bucket returns the stream's bucket within tx, or nil if the stream has not
+// been written to.
+func (s *Stream) bucket(tx *bbolt.Tx) *bbolt.Bucket {
+	root := tx.Bucket(rootBucket)
+	if root == nil {
+		return nil
+	}
+
+	return root.Bucket([]byte(s.ID()))
+}
+
+// update calls fn within a read-write transaction, creating the stream's
+// buckets if necessary. The changes that fn makes to m are written to the
+// database when fn returns.
+func (s *Stream) update(fn func(b *bbolt.Bucket, m *meta) error) error {
+	return s.DB.Update(func(tx *bbolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists(rootBucket)
+		if err != nil {
+			return err
+		}
+
+		b, err := root.CreateBucketIfNotExists([]byte(s.ID()))
+		if err != nil {
+			return err
+		}
+
+		if _, err := b.CreateBucketIfNotExists(eventsBucket); err != nil {
+			return err
+		}
+
+		m := readMeta(b)
+
+		if err := fn(b, &m); err != nil {
+			return err
+		}
+
+		return m.write(b)
+	})
+}
+
+// wait returns a channel that is closed when the stream changes.
+func (s *Stream) wait() <-chan struct{} {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.ready == nil {
+		s.ready = make(chan struct{})
+	}
+
+	return s.ready
+}
+
+// notify wakes any cursors that are waiting for the stream to change.
+func (s *Stream) notify() {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.ready != nil {
+		close(s.ready)
+		s.ready = nil
+	}
+}
+
+// meta is the metadata stored in each stream's bucket.
+type meta struct {
+	first  uint64
+	next   uint64
+	sealed bool
+}
+
+// readMeta reads the stream's metadata from b. b may be nil, in which case the
+// stream is empty.
+func readMeta(b *bbolt.Bucket) meta {
+	var m meta
+
+	if b == nil {
+		return m
+	}
+
+	if v := b.Get(firstKey); len(v) == 8 {
+		m.first = binary.BigEndian.Uint64(v)
+	}
+
+	if v := b.Get(nextKey); len(v) == 8 {
+		m.next = binary.BigEndian.Uint64(v)
+	}
+
+	m.sealed = b.Get(sealedKey) != nil
+
+	return m
+}
+
+// write writes the metadata to b.
+func (m meta) write(b *bbolt.Bucket) error {
+	if err := b.Put(firstKey, binary.BigEndian.AppendUint64(nil, m.first)); err != nil {
+		return err
+	}
+
+	if err := b.Put(nextKey, binary.BigEndian.AppendUint64(nil, m.next)); err != nil {
+		return err
+	}
+
+	if m.sealed {
+		return b.Put(sealedKey, []byte{1})
+	}
+
+	return nil
+}
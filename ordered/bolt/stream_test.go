@@ -0,0 +1,428 @@
+package bolt_test
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/dogmatiq/aperture/marshaling"
+	"github.com/dogmatiq/aperture/ordered"
+	. "github.com/dogmatiq/aperture/ordered/bolt"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.etcd.io/bbolt"
+)
+
+var _ = Describe("type Stream", func() {
+	var (
+		ctx       context.Context
+		cancel    func()
+		path      string
+		db        *bbolt.DB
+		marshaler marshaling.Marshaler
+		stream    *Stream
+		now       time.Time
+	)
+
+	// reopen returns a new stream that uses a new connection to the same
+	// database as stream.
+	reopen := func() *Stream {
+		err := db.Close()
+		Expect(err).ShouldNot(HaveOccurred())
+
+		db, err = bbolt.Open(path, 0600, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		return &Stream{
+			StreamID:  "<id>",
+			DB:        db,
+			Marshaler: marshaler,
+		}
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+
+		var err error
+		marshaler, err = marshaling.NewMarshaler(
+			MessageA{},
+			MessageB{},
+			MessageC{},
+		)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		path = filepath.Join(GinkgoT().TempDir(), "stream.db")
+
+		db, err = bbolt.Open(path, 0600, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		stream = &Stream{
+			StreamID:  "<id>",
+			DB:        db,
+			Marshaler: marshaler,
+		}
+
+		now = time.Now()
+
+		err = stream.Append(
+			now,
+			MessageA1,
+			MessageB1,
+			MessageA2,
+			MessageB2,
+		)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		db.Close()
+		cancel()
+	})
+
+	Describe("func ID()", func() {
+		It("returns the stream ID", func() {
+			Expect(stream.ID()).To(Equal("<id>"))
+		})
+
+		It("panics if the stream ID is empty", func() {
+			stream.StreamID = ""
+
+			Expect(func() {
+				stream.ID()
+			}).To(Panic())
+		})
+	})
+
+	Describe("func Open()", func() {
+		It("honours the initial offset", func() {
+			cur, err := stream.Open(ctx, 2, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeNumerically("==", 2))
+			Expect(env.RecordedAt).To(BeTemporally("==", now))
+			Expect(env.Message).To(Equal(MessageA2))
+			Expect(env.MessageID).To(Equal("<id>@2"))
+		})
+
+		It("applies the message type filter", func() {
+			cur, err := stream.Open(ctx, 0, []dogma.Message{MessageB{}})
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeNumerically("==", 1))
+			Expect(env.Message).To(Equal(MessageB1))
+
+			env, err = cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeNumerically("==", 3))
+			Expect(env.Message).To(Equal(MessageB2))
+		})
+
+		It("does not return any events when the filter is FilterNone", func() {
+			cur, err := stream.Open(ctx, 0, ordered.FilterNone)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+			defer cancel()
+
+			_, err = cur.Next(ctx)
+			Expect(err).To(Equal(context.DeadlineExceeded))
+		})
+
+		It("reads events written by a previous connection", func() {
+			stream = reopen()
+
+			cur, err := stream.Open(ctx, 3, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeNumerically("==", 3))
+			Expect(env.Message).To(Equal(MessageB2))
+		})
+
+		It("isolates streams with different IDs", func() {
+			other := &Stream{
+				StreamID:  "<other>",
+				DB:        db,
+				Marshaler: marshaler,
+			}
+
+			err := other.Append(now, MessageC1)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			cur, err := other.Open(ctx, 0, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeNumerically("==", 0))
+			Expect(env.Message).To(Equal(MessageC1))
+		})
+
+		When("the stream is sealed", func() {
+			BeforeEach(func() {
+				err := stream.Seal()
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+
+			It("returns a cursor if the offset is already on the stream", func() {
+				cur, err := stream.Open(ctx, 3, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				cur.Close()
+			})
+
+			It("returns a *SealedError if offset is beyond the end of the stream", func() {
+				_, err := stream.Open(ctx, 4, nil)
+				Expect(err).To(Equal(
+					&ordered.SealedError{
+						RequestedOffset: 4,
+						LastOffset:      3,
+					},
+				))
+			})
+
+			It("remains sealed when the database is opened again", func() {
+				stream = reopen()
+
+				_, err := stream.Open(ctx, 4, nil)
+				Expect(err).To(MatchError(ordered.ErrStreamSealed))
+			})
+		})
+	})
+
+	Describe("func Head()", func() {
+		It("returns the offset of the next event", func() {
+			offset, final, err := stream.Head(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(offset).To(BeNumerically("==", 4))
+			Expect(final).To(BeFalse())
+		})
+
+		It("returns zero for a stream that has not been written to", func() {
+			stream.StreamID = "<other>"
+
+			offset, final, err := stream.Head(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(offset).To(BeZero())
+			Expect(final).To(BeFalse())
+		})
+	})
+
+	Describe("func IsSealed()", func() {
+		It("returns true only once the stream is sealed", func() {
+			sealed, err := stream.IsSealed(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(sealed).To(BeFalse())
+
+			err = stream.Seal()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			sealed, err = stream.IsSealed(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(sealed).To(BeTrue())
+		})
+	})
+
+	Describe("func Bounds()", func() {
+		It("returns the offsets of the first and next events", func() {
+			first, next, err := stream.Bounds(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(first).To(BeNumerically("==", 0))
+			Expect(next).To(BeNumerically("==", 4))
+		})
+
+		It("excludes truncated events", func() {
+			_, err := stream.Truncate(3)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			first, next, err := stream.Bounds(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(first).To(BeNumerically("==", 3))
+			Expect(next).To(BeNumerically("==", 4))
+		})
+	})
+
+	Describe("func Append()", func() {
+		It("wakes waiting consumers", func() {
+			cur, err := stream.Open(ctx, 4, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(20 * time.Millisecond)
+				err := stream.Append(now, MessageC1)
+				Expect(err).ShouldNot(HaveOccurred())
+			}()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeNumerically("==", 4))
+			Expect(env.Message).To(Equal(MessageC1))
+		})
+
+		It("does not append any events if a message can not be marshaled", func() {
+			err := stream.Append(now, MessageC1, MessageD1)
+			Expect(err).Should(HaveOccurred())
+
+			offset, _, err := stream.Head(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(offset).To(BeNumerically("==", 4))
+		})
+
+		It("returns an error if the stream is sealed", func() {
+			err := stream.Seal()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = stream.Append(now, MessageC1)
+			Expect(err).To(MatchError("can not append to a sealed stream"))
+		})
+	})
+
+	Describe("func Truncate()", func() {
+		It("returns the number of truncated events", func() {
+			count, err := stream.Truncate(3)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(count).To(BeNumerically("==", 3))
+
+			count, err = stream.Truncate(3)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(count).To(BeZero())
+		})
+
+		It("causes cursors that read truncated events to return an error", func() {
+			cur, err := stream.Open(ctx, 1, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			_, err = stream.Truncate(3)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			_, err = cur.Next(ctx)
+			Expect(err).To(Equal(
+				&ordered.TruncatedError{
+					RequestedOffset: 1,
+					FirstOffset:     3,
+				},
+			))
+		})
+
+		It("returns an error if the offset is beyond the head of the stream", func() {
+			_, err := stream.Truncate(5)
+			Expect(err).To(MatchError("can not truncate stream to offset 5, next offset is 4"))
+		})
+	})
+
+	Describe("func Seal()", func() {
+		It("wakes waiting consumers", func() {
+			cur, err := stream.Open(ctx, 4, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(20 * time.Millisecond)
+				err := stream.Seal()
+				Expect(err).ShouldNot(HaveOccurred())
+			}()
+
+			_, err = cur.Next(ctx)
+			Expect(err).To(Equal(ordered.ErrStreamSealed))
+		})
+	})
+
+	Describe("type cursor", func() {
+		Describe("func Next()", func() {
+			It("returns an error if the cursor is closed", func() {
+				cur, err := stream.Open(ctx, 0, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				cur.Close()
+
+				_, err = cur.Next(ctx)
+				Expect(err).To(MatchError("cursor is closed"))
+			})
+
+			It("does not advance the cursor if the context is canceled", func() {
+				cur, err := stream.Open(ctx, 4, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				wctx, wcancel := context.WithTimeout(ctx, 20*time.Millisecond)
+				defer wcancel()
+
+				_, err = cur.Next(wctx)
+				Expect(err).To(Equal(context.DeadlineExceeded))
+
+				err = stream.Append(now, MessageC1)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				env, err := cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Offset).To(BeNumerically("==", 4))
+			})
+		})
+
+		Describe("func TryNext()", func() {
+			It("returns false without blocking at the end of the stream", func() {
+				cur, err := stream.Open(ctx, 3, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				c := cur.(ordered.NonBlockingCursor)
+
+				env, ok, err := c.TryNext(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(ok).To(BeTrue())
+				Expect(env.Message).To(Equal(MessageB2))
+
+				_, ok, err = c.TryNext(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(ok).To(BeFalse())
+			})
+		})
+	})
+
+	It("can be used by a projector", func() {
+		handler := &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageB{})
+			},
+		}
+
+		var messages []dogma.Message
+		handler.HandleEventFunc = func(
+			_ context.Context,
+			_, _, _ []byte,
+			_ dogma.ProjectionEventScope,
+			m dogma.Message,
+		) (bool, error) {
+			messages = append(messages, m)
+			if len(messages) == 2 {
+				cancel()
+			}
+			return true, nil
+		}
+
+		proj := &ordered.Projector{
+			Stream:  stream,
+			Handler: handler,
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+		Expect(messages).To(Equal([]dogma.Message{MessageB1, MessageB2}))
+	})
+})
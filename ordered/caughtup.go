@@ -0,0 +1,141 @@
+package ordered
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// errCaughtUp is returned by a caughtUpCursor when it reaches the end of the
+// stream.
+var errCaughtUp = errors.New("caught up with the head of the stream")
+
+// RunUntilCaughtUp runs the projection until every event that is currently on
+// the stream has been applied, then compacts the projection once and returns
+// nil.
+//
+// It is intended for batch jobs and one-shot backfills. It behaves like Run(),
+// except that reaching the end of the stream, sealed or not, is treated as
+// completion instead of waiting for new events, and compaction is not
+// performed at p.CompactionInterval.
+//
+// The cursors returned by the stream must implement NonBlockingCursor, which
+// is used to detect the end of the stream. Use RunUntilCaughtUpWithResult() to
+// determine why the projector stopped.
+func (p *Projector) RunUntilCaughtUp(ctx context.Context) error {
+	_, err := p.RunUntilCaughtUpWithResult(ctx)
+	return err
+}
+
+// CaughtUpOffset returns the offset at which the projector caught up with the
+// head of the stream during the current (or most recent) call to Run().
+//
+// The offset is that of the head of the stream at the time the projector first
+// opened the stream, or the projection's own offset if it was already beyond
+// the head. Events before this offset were historical at the time the projector
+// started, events at or after it are live. It is computed once per call to
+// Run().
+//
+// ok is false if the projector has not yet caught up, or if the stream
+// implements neither HeadStream nor BoundsStream.
+//
+// The projector is considered to have caught up once all events before the
+// offset have been consumed. If the handler does not consume the events at the
+// end of the stream, this is not known until the next relevant event (or
+// heartbeat) is read from the stream.
+func (p *Projector) CaughtUpOffset() (offset uint64, ok bool) {
+	if o := p.caughtUp.Load(); o != nil {
+		return *o, true
+	}
+
+	return 0, false
+}
+
+// resetCatchUp forgets the catch-up boundary when Run() is called.
+func (p *Projector) resetCatchUp() {
+	p.boundary = nil
+	p.caughtUp.Store(nil)
+}
+
+// trackCatchUp records the catch-up boundary when the stream is opened at the
+// given offset, if it has not already been recorded during this run.
+func (p *Projector) trackCatchUp(offset, head uint64) {
+	if p.boundary == nil {
+		b := max(offset, head)
+		p.boundary = &b
+	}
+
+	p.reached(offset)
+}
+
+// reached records that the projector has consumed every event before the
+// given offset, and hence may have caught up with the head of the stream.
+func (p *Projector) reached(offset uint64) {
+	if p.boundary == nil || offset < *p.boundary {
+		return
+	}
+
+	if p.caughtUp.Load() != nil {
+		return
+	}
+
+	b := *p.boundary
+	p.caughtUp.Store(&b)
+
+	if p.OnCaughtUp != nil {
+		p.OnCaughtUp(b)
+	}
+}
+
+// caughtUp returns a cursor that returns errCaughtUp when it reaches the end
+// of the stream, instead of blocking.
+func caughtUp(cur Cursor) (Cursor, error) {
+	c, ok := cur.(NonBlockingCursor)
+	if !ok {
+		return nil, fmt.Errorf(
+			"unable to detect the end of the stream, %T does not implement NonBlockingCursor",
+			cur,
+		)
+	}
+
+	return caughtUpCursor{c}, nil
+}
+
+// caughtUpCursor is a Cursor that returns errCaughtUp from Next() when it
+// reaches the end of the stream.
+type caughtUpCursor struct {
+	NonBlockingCursor
+}
+
+// Next returns the next relevant event in the stream, or errCaughtUp if there
+// are no more events currently on the stream.
+func (c caughtUpCursor) Next(ctx context.Context) (Envelope, error) {
+	env, ok, err := c.TryNext(ctx)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	if !ok {
+		return Envelope{}, errCaughtUp
+	}
+
+	return env, nil
+}
+
+// compactOnce compacts the projection at the end of RunUntilCaughtUp(), unless
+// compaction is disabled.
+func (p *Projector) compactOnce(ctx context.Context) error {
+	if p.DryRun || p.compactionInterval() < 0 {
+		return nil
+	}
+
+	if err := p.compact(ctx); err != nil {
+		return fmt.Errorf(
+			"unable to compact the '%s' projection: %w",
+			p.name,
+			err,
+		)
+	}
+
+	return nil
+}
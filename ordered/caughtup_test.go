@@ -0,0 +1,307 @@
+package ordered_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/aperture/ordered/resource"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("func (*Projector) CaughtUpOffset()", func() {
+	var (
+		ctx     context.Context
+		cancel  func()
+		stream  *MemoryStream
+		handler *ProjectionMessageHandler
+		proj    *Projector
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), DefaultTimeout*2)
+
+		stream = &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		stream.Append(
+			time.Now(),
+			MessageA1,
+			MessageA2,
+			MessageA3,
+		)
+
+		handler = &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageA{})
+			},
+		}
+
+		proj = &Projector{
+			Stream:  stream,
+			Handler: handler,
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("returns false before the projector has caught up", func() {
+		handler.HandleEventFunc = func(
+			context.Context,
+			[]byte, []byte, []byte,
+			dogma.ProjectionEventScope,
+			dogma.Message,
+		) (bool, error) {
+			_, ok := proj.CaughtUpOffset()
+			Expect(ok).To(BeFalse())
+			cancel()
+			return true, nil
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+
+		_, ok := proj.CaughtUpOffset()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("returns the offset of the head once the historical events have been handled", func() {
+		var offsets []uint64
+		proj.OnCaughtUp = func(offset uint64) {
+			offsets = append(offsets, offset)
+			cancel()
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+		Expect(offsets).To(Equal([]uint64{3}))
+
+		offset, ok := proj.CaughtUpOffset()
+		Expect(ok).To(BeTrue())
+		Expect(offset).To(BeEquivalentTo(3))
+	})
+
+	It("catches up immediately if the projection is already at the head", func() {
+		handler.ResourceVersionFunc = func(context.Context, []byte) ([]byte, error) {
+			return resource.MarshalOffset(3), nil
+		}
+
+		proj.OnCaughtUp = func(offset uint64) {
+			Expect(offset).To(BeEquivalentTo(3))
+			cancel()
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+
+		_, ok := proj.CaughtUpOffset()
+		Expect(ok).To(BeTrue())
+	})
+
+	It("catches up when the next relevant event arrives if the last historical events are not consumed", func() {
+		stream.Append(time.Now(), MessageB1)
+
+		handler.HandleEventFunc = func(
+			_ context.Context,
+			_, _, _ []byte,
+			_ dogma.ProjectionEventScope,
+			m dogma.Message,
+		) (bool, error) {
+			if m == MessageA3 {
+				_, ok := proj.CaughtUpOffset()
+				Expect(ok).To(BeFalse())
+				stream.Append(time.Now(), MessageA1)
+			}
+			return true, nil
+		}
+
+		proj.OnCaughtUp = func(offset uint64) {
+			Expect(offset).To(BeEquivalentTo(4))
+			cancel()
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+
+		_, ok := proj.CaughtUpOffset()
+		Expect(ok).To(BeTrue())
+	})
+
+	It("is reset each time the projector is run", func() {
+		proj.OnCaughtUp = func(uint64) {
+			cancel()
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+
+		ctx, cancel = context.WithCancel(context.Background())
+		proj.OnCaughtUp = nil
+		handler.HandleEventFunc = func(
+			context.Context,
+			[]byte, []byte, []byte,
+			dogma.ProjectionEventScope,
+			dogma.Message,
+		) (bool, error) {
+			cancel()
+			return true, nil
+		}
+
+		err = proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+
+		_, ok := proj.CaughtUpOffset()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("returns false if the stream does not implement HeadStream", func() {
+		proj.Stream = struct{ Stream }{stream}
+
+		handler.HandleEventFunc = func(
+			_ context.Context,
+			_, _, _ []byte,
+			_ dogma.ProjectionEventScope,
+			m dogma.Message,
+		) (bool, error) {
+			if m == MessageA3 {
+				cancel()
+			}
+			return true, nil
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+
+		_, ok := proj.CaughtUpOffset()
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("func (*Projector) RunUntilCaughtUp()", func() {
+	var (
+		ctx      context.Context
+		cancel   func()
+		stream   *MemoryStream
+		handler  *ProjectionMessageHandler
+		proj     *Projector
+		messages []dogma.Message
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), DefaultTimeout*2)
+
+		stream = &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		stream.Append(
+			time.Now(),
+			MessageA1,
+			MessageB1,
+			MessageA2,
+		)
+
+		messages = nil
+
+		handler = &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageA{})
+			},
+			HandleEventFunc: func(
+				_ context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				m dogma.Message,
+			) (bool, error) {
+				messages = append(messages, m)
+				return true, nil
+			},
+		}
+
+		proj = &Projector{
+			Stream:  stream,
+			Handler: handler,
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("returns nil once every event on the stream has been applied", func() {
+		err := proj.RunUntilCaughtUp(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(messages).To(Equal([]dogma.Message{MessageA1, MessageA2}))
+	})
+
+	It("returns nil at the end of a sealed stream", func() {
+		stream.Seal()
+
+		err := proj.RunUntilCaughtUp(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(messages).To(Equal([]dogma.Message{MessageA1, MessageA2}))
+	})
+
+	It("compacts the projection once, after the events have been applied", func() {
+		compactions := 0
+		handler.CompactFunc = func(context.Context, dogma.ProjectionCompactScope) error {
+			Expect(messages).To(HaveLen(2))
+			compactions++
+			return nil
+		}
+
+		err := proj.RunUntilCaughtUp(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(compactions).To(Equal(1))
+	})
+
+	It("does not compact the projection if compaction is disabled", func() {
+		proj.CompactionInterval = -1
+
+		handler.CompactFunc = func(context.Context, dogma.ProjectionCompactScope) error {
+			Fail("unexpected compaction")
+			return nil
+		}
+
+		err := proj.RunUntilCaughtUp(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("returns an error if the stream's cursor does not implement NonBlockingCursor", func() {
+		proj.Stream = &ChannelStream{
+			StreamID: "<id>",
+			Events:   make(chan Envelope),
+		}
+
+		err := proj.RunUntilCaughtUp(ctx)
+		Expect(err).To(MatchError(
+			MatchRegexp(`unable to detect the end of the stream, \*ordered\.channelCursor does not implement NonBlockingCursor`),
+		))
+	})
+
+	It("does not affect subsequent calls to Run()", func() {
+		err := proj.RunUntilCaughtUp(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		handler.HandleEventFunc = func(
+			_ context.Context,
+			_, _, _ []byte,
+			_ dogma.ProjectionEventScope,
+			m dogma.Message,
+		) (bool, error) {
+			cancel()
+			return true, nil
+		}
+
+		err = proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+	})
+})
@@ -0,0 +1,183 @@
+package ordered
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/dogmatiq/configkit/message"
+	"github.com/dogmatiq/dogma"
+)
+
+// ChannelStream is an implementation of Stream that reads events from a Go
+// channel.
+//
+// It is intended for wiring a producer goroutine directly to a projector
+// without retaining every event in memory, as MemoryStream does.
+//
+// Because a channel can not be replayed, a cursor can only be opened at the
+// stream's current position, which is the offset after that of the last event
+// received from the channel. Each event is received by exactly one cursor, so
+// only one cursor should be open at a time.
+//
+// Closing the channel seals the stream.
+type ChannelStream struct {
+	// StreamID is a unique identifier for the stream, it must not be empty.
+	// The tuple of stream ID and event offset must uniquely identify a message.
+	StreamID string
+
+	// Events is the channel from which events are received.
+	//
+	// The producer is responsible for populating the offset of each envelope,
+	// which must be strictly sequential.
+	Events <-chan Envelope
+
+	// FirstOffset is the offset of the first event to be received from the
+	// channel.
+	FirstOffset uint64
+
+	m        sync.Mutex
+	received bool
+	next     uint64
+	sealed   bool
+}
+
+// ID returns a unique identifier for the stream.
+//
+// The tuple of stream ID and event offset must uniquely identify a message.
+func (s *ChannelStream) ID() string {
+	if s.StreamID == "" {
+		panic("stream ID must not be empty")
+	}
+
+	return s.StreamID
+}
+
+// Open returns a cursor used to read events from this stream.
+//
+// offset must be the stream's current position, otherwise an error is
+// returned. If the channel has been closed, a *SealedError is returned.
+//
+// filter is a set of zero-value event messages, the types of which indicate
+// which event types are returned by Cursor.Next(). If filter is empty, all
+// events types are returned. Events that do not match the filter are still
+// received from the channel, and are discarded.
+func (s *ChannelStream) Open(
+	ctx context.Context,
+	offset uint64,
+	filter []dogma.Message,
+) (Cursor, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	next := s.position()
+
+	if s.sealed && offset >= next {
+		return nil, sealedError(offset, next)
+	}
+
+	if offset != next {
+		return nil, fmt.Errorf(
+			"can not open channel stream at offset %d, the current position is %d",
+			offset,
+			next,
+		)
+	}
+
+	c := &channelCursor{
+		stream: s,
+		closed: make(chan struct{}),
+	}
+
+	if len(filter) > 0 {
+		c.filter = message.TypesOf(filter...)
+	}
+
+	return c, nil
+}
+
+// position returns the offset of the next event to be received from the
+// channel. s.m must be locked.
+func (s *ChannelStream) position() uint64 {
+	if s.received {
+		return s.next
+	}
+
+	return s.FirstOffset
+}
+
+// receive records that env has been received from the channel.
+func (s *ChannelStream) receive(env Envelope) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.received = true
+	s.next = env.Offset + 1
+}
+
+// seal records that the channel has been closed.
+func (s *ChannelStream) seal() {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.sealed = true
+}
+
+type channelCursor struct {
+	stream    *ChannelStream
+	filter    message.TypeSet
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// Next returns the next relevant event in the stream.
+//
+// If the end of the stream is reached it blocks until a relevant event is
+// sent on the channel, ctx is canceled or the channel is closed. If the channel
+// is closed, ErrStreamSealed is returned.
+func (c *channelCursor) Next(ctx context.Context) (Envelope, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return Envelope{}, ctx.Err()
+		case <-c.closed:
+			return Envelope{}, errCursorClosed
+		default:
+		}
+
+		select {
+		case <-ctx.Done():
+			return Envelope{}, ctx.Err()
+		case <-c.closed:
+			return Envelope{}, errCursorClosed
+		case env, ok := <-c.stream.Events:
+			if !ok {
+				c.stream.seal()
+				return Envelope{}, ErrStreamSealed
+			}
+
+			c.stream.receive(env)
+
+			if c.filter != nil && !c.filter.HasM(env.Message) {
+				continue
+			}
+
+			if env.MessageID == "" {
+				env.MessageID = MessageID(c.stream.ID(), env.Offset)
+			}
+
+			return env, nil
+		}
+	}
+}
+
+// Close stops the cursor.
+//
+// Any current or future calls to Next() return a non-nil error.
+func (c *channelCursor) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package ordered_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type ChannelStream", func() {
+	var (
+		now    time.Time
+		ctx    context.Context
+		cancel func()
+		events chan Envelope
+		stream *ChannelStream
+	)
+
+	BeforeEach(func() {
+		now = time.Now()
+
+		ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+
+		events = make(chan Envelope, 10)
+		events <- Envelope{Offset: 0, RecordedAt: now, Message: MessageA1}
+		events <- Envelope{Offset: 1, RecordedAt: now, Message: MessageB1}
+		events <- Envelope{Offset: 2, RecordedAt: now, Message: MessageA2}
+
+		stream = &ChannelStream{
+			StreamID: "<id>",
+			Events:   events,
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Describe("func ID()", func() {
+		It("returns the stream ID", func() {
+			Expect(stream.ID()).To(Equal("<id>"))
+		})
+
+		It("panics if the stream ID is empty", func() {
+			stream.StreamID = ""
+
+			Expect(func() {
+				stream.ID()
+			}).To(Panic())
+		})
+	})
+
+	Describe("func Open()", func() {
+		It("returns an error if the offset is not the current position", func() {
+			_, err := stream.Open(ctx, 1, nil)
+			Expect(err).To(MatchError(
+				"can not open channel stream at offset 1, the current position is 0",
+			))
+		})
+
+		It("honours the first offset", func() {
+			stream.FirstOffset = 10
+
+			cur, err := stream.Open(ctx, 10, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			cur.Close()
+		})
+
+		It("allows a cursor to be opened at the position after the last event received", func() {
+			cur, err := stream.Open(ctx, 0, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			_, err = cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			cur.Close()
+
+			cur, err = stream.Open(ctx, 1, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env).To(Equal(
+				Envelope{
+					Offset:     1,
+					RecordedAt: now,
+					Message:    MessageB1,
+					MessageID:  "<id>@1",
+				},
+			))
+		})
+
+		It("returns ErrStreamSealed if the channel has been closed", func() {
+			close(events)
+
+			cur, err := stream.Open(ctx, 0, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			for {
+				if _, err := cur.Next(ctx); err != nil {
+					Expect(err).To(MatchError(ErrStreamSealed))
+					break
+				}
+			}
+			cur.Close()
+
+			_, err = stream.Open(ctx, 3, nil)
+			Expect(err).To(MatchError(ErrStreamSealed))
+			Expect(err).To(Equal(
+				&SealedError{
+					RequestedOffset: 3,
+					LastOffset:      2,
+				},
+			))
+		})
+	})
+
+	Describe("type channelCursor", func() {
+		Describe("func Next()", func() {
+			It("applies the message type filter", func() {
+				cur, err := stream.Open(ctx, 0, []dogma.Message{MessageA{}})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				env, err := cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Message).To(Equal(MessageA1))
+
+				env, err = cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Message).To(Equal(MessageA2))
+			})
+
+			It("blocks until an event is sent on the channel", func() {
+				cur, err := stream.Open(ctx, 0, []dogma.Message{MessageB{}})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				_, err = cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				go func() {
+					time.Sleep(20 * time.Millisecond)
+					events <- Envelope{Offset: 3, RecordedAt: now, Message: MessageB2}
+				}()
+
+				env, err := cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Message).To(Equal(MessageB2))
+			})
+
+			It("returns an error if the context is canceled", func() {
+				cur, err := stream.Open(ctx, 0, []dogma.Message{MessageB{}})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				_, err = cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				cancel()
+
+				_, err = cur.Next(ctx)
+				Expect(err).To(Equal(context.Canceled))
+			})
+
+			It("returns an error if the cursor is closed", func() {
+				cur, err := stream.Open(ctx, 0, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				cur.Close()
+
+				_, err = cur.Next(ctx)
+				Expect(err).To(MatchError("cursor is closed"))
+			})
+		})
+	})
+
+	It("can be consumed by a projector", func() {
+		close(events)
+
+		var messages []dogma.Message
+		proj := &Projector{
+			Stream: stream,
+			Handler: &ProjectionMessageHandler{
+				ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+					c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+					c.ConsumesEventType(MessageA{})
+				},
+				HandleEventFunc: func(
+					_ context.Context,
+					_, _, _ []byte,
+					_ dogma.ProjectionEventScope,
+					m dogma.Message,
+				) (bool, error) {
+					messages = append(messages, m)
+					return true, nil
+				},
+			},
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(MatchError(
+			"unable to consume from '<id>' for the '<proj>' projection: stream sealed",
+		))
+		Expect(messages).To(Equal(
+			[]dogma.Message{MessageA1, MessageA2},
+		))
+	})
+})
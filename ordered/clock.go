@@ -0,0 +1,52 @@
+package ordered
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is a source of the current time, used by a projector to schedule
+// compaction and to measure the conflict timeout.
+//
+// It allows the timing of compaction and conflicts to be controlled by a fake
+// clock in tests. It is distinct from CompactionClock, which persists the time of the
+// last compaction.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After returns a channel that receives the current time once d has
+	// elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is a Clock that uses the system's real-time clock.
+var SystemClock Clock = systemClock{}
+
+// systemClock is an implementation of Clock that uses the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// sleep blocks until d has elapsed according to c, or until ctx is canceled.
+//
+// It returns immediately if d is not positive. If ctx is canceled before d has
+// elapsed it returns ctx.Err(), otherwise it returns nil.
+func sleep(ctx context.Context, c Clock, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.After(d):
+		return nil
+	}
+}
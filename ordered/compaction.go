@@ -0,0 +1,21 @@
+package ordered
+
+import (
+	"context"
+	"time"
+)
+
+// CompactionClock persists the time at which a projection was last compacted.
+//
+// It allows a projector to schedule compaction relative to the last time the
+// projection was compacted, even across restarts.
+type CompactionClock interface {
+	// LastCompactedAt returns the time at which the projection was last
+	// compacted.
+	//
+	// It returns the zero-value if the projection has never been compacted.
+	LastCompactedAt(ctx context.Context) (time.Time, error)
+
+	// RecordCompaction records that the projection was compacted at time t.
+	RecordCompaction(ctx context.Context, t time.Time) error
+}
@@ -0,0 +1,41 @@
+package ordered
+
+import "context"
+
+// contextKey is the type of keys used to store values in the context passed
+// to the handler.
+type contextKey int
+
+const (
+	handlerNameKey contextKey = iota
+	offsetKey
+)
+
+// withEvent returns a context that carries the name of the handler and the
+// offset of the event being handled.
+func withEvent(ctx context.Context, handler string, offset uint64) context.Context {
+	ctx = context.WithValue(ctx, handlerNameKey, handler)
+	return context.WithValue(ctx, offsetKey, offset)
+}
+
+// HandlerNameFromContext returns the name of the projection handler that is
+// handling an event.
+//
+// ctx must be (or be derived from) the context passed to the handler's
+// HandleEvent() method by a projector. ok is false if ctx does not carry a
+// handler name.
+func HandlerNameFromContext(ctx context.Context) (name string, ok bool) {
+	name, ok = ctx.Value(handlerNameKey).(string)
+	return name, ok
+}
+
+// OffsetFromContext returns the offset of the event that is being handled.
+//
+// ctx must be (or be derived from) the context passed to the handler's
+// HandleEvent() method by a projector. When handling a batch of events it is
+// the offset of the first event in the batch. ok is false if ctx does not
+// carry an offset.
+func OffsetFromContext(ctx context.Context) (offset uint64, ok bool) {
+	offset, ok = ctx.Value(offsetKey).(uint64)
+	return offset, ok
+}
@@ -0,0 +1,68 @@
+package ordered_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("func HandlerNameFromContext()", func() {
+	It("returns false if the context does not carry a handler name", func() {
+		_, ok := HandlerNameFromContext(context.Background())
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("func OffsetFromContext()", func() {
+	It("returns false if the context does not carry an offset", func() {
+		_, ok := OffsetFromContext(context.Background())
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("context passed to the handler", func() {
+	It("carries the handler name and the event offset", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout*2)
+		defer cancel()
+
+		stream := &MemoryStream{
+			StreamID: "<id>",
+		}
+		stream.Append(time.Now(), MessageB1, MessageA1)
+
+		proj := &Projector{
+			Stream: stream,
+			Handler: &ProjectionMessageHandler{
+				ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+					c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+					c.ConsumesEventType(MessageA{})
+				},
+				HandleEventFunc: func(
+					ctx context.Context,
+					_, _, _ []byte,
+					_ dogma.ProjectionEventScope,
+					_ dogma.Message,
+				) (bool, error) {
+					name, ok := HandlerNameFromContext(ctx)
+					Expect(ok).To(BeTrue())
+					Expect(name).To(Equal("<proj>"))
+
+					offset, ok := OffsetFromContext(ctx)
+					Expect(ok).To(BeTrue())
+					Expect(offset).To(BeNumerically("==", 1))
+
+					cancel()
+					return true, nil
+				},
+			},
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+	})
+})
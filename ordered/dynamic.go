@@ -0,0 +1,104 @@
+package ordered
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dogmatiq/configkit"
+	"github.com/dogmatiq/configkit/message"
+	"github.com/dogmatiq/dodeca/logging"
+	"github.com/dogmatiq/dogma"
+)
+
+// DynamicProjectionMessageHandler is a projection message handler that can
+// change the event types that it consumes while the projector is running.
+type DynamicProjectionMessageHandler interface {
+	dogma.ProjectionMessageHandler
+
+	// ConsumedTypesChanged returns a channel that is closed when the event
+	// types consumed by the handler have changed.
+	//
+	// Each call returns a channel that is closed by the next change, if any.
+	// When the channel is closed the projector calls Configure() again to
+	// obtain the new event types, then re-opens the stream at the
+	// projection's current offset with a filter that includes only those
+	// types. The handler's identity must not change.
+	ConsumedTypesChanged() <-chan struct{}
+}
+
+// watchConsumedTypes re-opens the stream each time the event types consumed
+// by the handler in st change, until ctx is canceled.
+//
+// restart is called to stop the current consumer. It returns nil if the types
+// changed or ctx was canceled.
+func (p *Projector) watchConsumedTypes(
+	ctx context.Context,
+	st *handlerState,
+	h DynamicProjectionMessageHandler,
+	restart func(),
+) error {
+	for {
+		// The channel is obtained before the types are compared so that a
+		// change made during the comparison is not missed.
+		changed := h.ConsumedTypesChanged()
+
+		ok, err := p.reconfigure(st)
+		if err != nil || ok {
+			restart()
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+		}
+	}
+}
+
+// reconfigure replaces the projector's handler state with the event types
+// currently consumed by the handler in st.
+//
+// ok is false if the types have not changed, or if the handler has been
+// swapped since st was loaded.
+func (p *Projector) reconfigure(st *handlerState) (ok bool, err error) {
+	defer configkit.Recover(&err)
+
+	cfg := configkit.FromProjection(st.handler)
+
+	if id := cfg.Identity(); id.Name != p.name || id.Key != p.key {
+		return false, fmt.Errorf(
+			"unable to reconfigure the '%s' projection: the handler's identity has changed (%s)",
+			p.name,
+			id,
+		)
+	}
+
+	types := cfg.MessageTypes().Consumed
+	if message.IsEqualSetT(types, st.types) {
+		return false, nil
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.state.Load() != st {
+		// The handler has been swapped, the new handler's types take
+		// precedence.
+		return false, nil
+	}
+
+	p.state.Store(&handlerState{
+		handler: st.handler,
+		types:   types,
+	})
+
+	logging.Log(
+		p.Logger,
+		"[%s %s] the handler's consumed event types have changed, re-opening the stream",
+		p.name,
+		p.resource,
+	)
+
+	return true, nil
+}
@@ -0,0 +1,173 @@
+package ordered_test
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// dynamicHandler is a test implementation of DynamicProjectionMessageHandler.
+type dynamicHandler struct {
+	ProjectionMessageHandler
+
+	m       sync.Mutex
+	changed chan struct{}
+}
+
+func (h *dynamicHandler) ConsumedTypesChanged() <-chan struct{} {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	if h.changed == nil {
+		h.changed = make(chan struct{})
+	}
+
+	return h.changed
+}
+
+// Change replaces the handler's Configure() implementation with fn, and
+// notifies the projector of the change.
+func (h *dynamicHandler) Change(fn func(dogma.ProjectionConfigurer)) {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	h.ConfigureFunc = fn
+
+	if h.changed != nil {
+		close(h.changed)
+		h.changed = nil
+	}
+}
+
+func (h *dynamicHandler) Configure(c dogma.ProjectionConfigurer) {
+	h.m.Lock()
+	fn := h.ConfigureFunc
+	h.m.Unlock()
+
+	fn(c)
+}
+
+var _ = Describe("type Projector (dynamic event types)", func() {
+	var (
+		ctx     context.Context
+		cancel  func()
+		stream  *MemoryStream
+		handler *dynamicHandler
+		proj    *Projector
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), DefaultTimeout*2)
+
+		stream = &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		stream.Append(
+			time.Now(),
+			MessageA1,
+			MessageB1,
+			MessageA2,
+		)
+
+		handler = &dynamicHandler{
+			ProjectionMessageHandler: ProjectionMessageHandler{
+				ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+					c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+					c.ConsumesEventType(MessageA{})
+				},
+			},
+		}
+
+		proj = &Projector{
+			Stream:  stream,
+			Handler: handler,
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("re-opens the stream with the new filter when the consumed types change", func() {
+		var (
+			m        sync.Mutex
+			version  []byte
+			messages []dogma.Message
+		)
+
+		handler.ResourceVersionFunc = func(context.Context, []byte) ([]byte, error) {
+			m.Lock()
+			defer m.Unlock()
+			return version, nil
+		}
+
+		handler.HandleEventFunc = func(
+			_ context.Context,
+			_, c, n []byte,
+			_ dogma.ProjectionEventScope,
+			msg dogma.Message,
+		) (bool, error) {
+			m.Lock()
+			defer m.Unlock()
+
+			if !bytes.Equal(c, version) {
+				return false, nil
+			}
+
+			version = n
+			messages = append(messages, msg)
+
+			switch len(messages) {
+			case 2:
+				go func() {
+					handler.Change(func(c dogma.ProjectionConfigurer) {
+						c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+						c.ConsumesEventType(MessageA{})
+						c.ConsumesEventType(MessageB{})
+					})
+
+					Eventually(proj.ConsumedTypes).Should(HaveLen(2))
+					stream.Append(time.Now(), MessageB2)
+				}()
+			case 3:
+				cancel()
+			}
+
+			return true, nil
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+		Expect(messages).To(Equal(
+			[]dogma.Message{MessageA1, MessageA2, MessageB2},
+		))
+	})
+
+	It("returns an error if the handler's identity changes", func() {
+		handler.HandleEventFunc = func(
+			context.Context,
+			[]byte, []byte, []byte,
+			dogma.ProjectionEventScope,
+			dogma.Message,
+		) (bool, error) {
+			go handler.Change(func(c dogma.ProjectionConfigurer) {
+				c.Identity("<other>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageA{})
+			})
+			return true, nil
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(MatchError(
+			"unable to consume from '<id>' for the '<proj>' projection: unable to reconfigure the '<proj>' projection: the handler's identity has changed (<other>/45804515-8b41-4d23-97b1-0cda5a0d782c)",
+		))
+	})
+})
@@ -0,0 +1,167 @@
+package ordered
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dogmatiq/configkit/message"
+)
+
+// ErrHandleTimeout is the cause of the cancellation of the context passed to
+// the handler when the handler's timeout elapses.
+//
+// It allows a handler to distinguish its own timeout from the projector being
+// stopped by calling context.Cause().
+var ErrHandleTimeout = errors.New("handle timeout exceeded")
+
+// OpenError is an error that occurred while opening a cursor on the stream.
+//
+// It wraps errors that occur when reading the current resource version from
+// the handler, unmarshaling the offset from that version, or opening the
+// stream itself. Such errors are typically caused by infrastructure problems
+// and are often transient.
+type OpenError struct {
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *OpenError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *OpenError) Unwrap() error {
+	return e.Err
+}
+
+// HandleError is an error returned by the handler while handling an event.
+//
+// Such errors are typically caused by a problem with the handler or the event
+// itself, and are often not resolved by retrying.
+type HandleError struct {
+	// Offset is the offset of the (first) event that was being handled.
+	Offset uint64
+
+	// Err is the error returned by the handler.
+	Err error
+}
+
+func (e *HandleError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *HandleError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is an error that represents a panic that was recovered from one
+// of the handler's methods.
+type PanicError struct {
+	// Value is the value that was passed to panic().
+	Value interface{}
+
+	// Stack is the stack trace of the goroutine at the time of the panic.
+	Stack []byte
+
+	// Method is the name of the handler method that panicked, one of
+	// "HandleEvent", "HandleEventBatch", "TimeoutHint" or "Compact".
+	Method string
+
+	// Offset is the offset of the event that was being handled. It is zero if
+	// Method is "Compact". If Method is "HandleEventBatch" it is the offset of
+	// the first event in the batch.
+	Offset uint64
+
+	// MessageType is the type of the event that was being handled. It is the
+	// zero-value if Method is "Compact" or "HandleEventBatch".
+	MessageType message.Type
+}
+
+func (e *PanicError) Error() string {
+	switch e.Method {
+	case "Compact":
+		return fmt.Sprintf(
+			"handler panicked while compacting the projection: %v",
+			e.Value,
+		)
+	case "HandleEventBatch":
+		return fmt.Sprintf(
+			"handler panicked while handling a batch of events starting at offset %d: %v",
+			e.Offset,
+			e.Value,
+		)
+	case "TimeoutHint":
+		return fmt.Sprintf(
+			"handler panicked while computing the timeout for %s event at offset %d: %v",
+			e.MessageType,
+			e.Offset,
+			e.Value,
+		)
+	default:
+		return fmt.Sprintf(
+			"handler panicked while handling %s event at offset %d: %v",
+			e.MessageType,
+			e.Offset,
+			e.Value,
+		)
+	}
+}
+
+// OrderingError is returned when a stream's cursor returns an event with an
+// offset that is not greater than that of the previous event, violating the
+// ordering contract of Cursor.Next().
+//
+// It is only detected when Projector.StrictOrdering is true.
+type OrderingError struct {
+	// Offset is the offset of the event that was returned by the cursor.
+	Offset uint64
+
+	// Expected is the lowest offset that the cursor may have returned.
+	Expected uint64
+}
+
+func (e *OrderingError) Error() string {
+	return fmt.Sprintf(
+		"stream returned out-of-order offset %d, expected %d or greater",
+		e.Offset,
+		e.Expected,
+	)
+}
+
+// ErrorAction is an action that the projector takes when the handler fails
+// to handle an event.
+type ErrorAction int
+
+const (
+	// ReturnError causes the projector to stop, returning the error from
+	// Run().
+	ReturnError ErrorAction = iota
+
+	// SkipEvent causes the projector to continue with the next event as though the
+	// failed event had not been on the stream.
+	//
+	// A skipped event is not recorded in the projection's version, so if the
+	// consumer restarts before a subsequent event is applied, the skipped event
+	// is delivered again.
+	SkipEvent
+)
+
+// ConflictError is returned when optimistic concurrency conflicts prevent the
+// projection from advancing for longer than Projector.ConflictTimeout.
+type ConflictError struct {
+	// Offset is the offset of the (first) event that most recently caused a
+	// conflict.
+	Offset uint64
+
+	// Elapsed is the amount of time since the first of the consecutive
+	// conflicts occurred.
+	Elapsed time.Duration
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf(
+		"optimistic concurrency conflicts have prevented the projection from advancing past offset %d for %s",
+		e.Offset,
+		e.Elapsed,
+	)
+}
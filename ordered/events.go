@@ -0,0 +1,52 @@
+//go:build go1.23
+
+package ordered
+
+import (
+	"context"
+	"errors"
+	"iter"
+
+	"github.com/dogmatiq/dogma"
+)
+
+// Events returns an iterator over the events in s, beginning at offset.
+//
+// The offset and filter parameters have the same semantics as for
+// Stream.Open(). The cursor is opened when iteration begins and closed when it
+// ends, including when the caller breaks out of the loop early.
+//
+// Iteration ends without an error when the stream is sealed. Any other error,
+// including the cancelation of ctx, is yielded along with a zero-value
+// envelope, after which iteration ends.
+func Events(
+	ctx context.Context,
+	s Stream,
+	offset uint64,
+	filter []dogma.Message,
+) iter.Seq2[Envelope, error] {
+	return func(yield func(Envelope, error) bool) {
+		cur, err := s.Open(ctx, offset, filter)
+		if err != nil {
+			if !errors.Is(err, ErrStreamSealed) {
+				yield(Envelope{}, err)
+			}
+			return
+		}
+		defer cur.Close()
+
+		for {
+			env, err := cur.Next(ctx)
+			if err != nil {
+				if !errors.Is(err, ErrStreamSealed) {
+					yield(Envelope{}, err)
+				}
+				return
+			}
+
+			if !yield(env, nil) {
+				return
+			}
+		}
+	}
+}
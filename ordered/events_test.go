@@ -0,0 +1,107 @@
+//go:build go1.23
+
+package ordered_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("func Events()", func() {
+	var (
+		now    time.Time
+		ctx    context.Context
+		cancel func()
+		stream *MemoryStream
+	)
+
+	BeforeEach(func() {
+		now = time.Now()
+
+		ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+		stream = &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		stream.Append(
+			now,
+			MessageA1,
+			MessageB1,
+			MessageA2,
+			MessageB2,
+		)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("yields the filtered events until the stream is sealed", func() {
+		stream.Seal()
+
+		var envs []Envelope
+		for env, err := range Events(ctx, stream, 1, []dogma.Message{MessageA{}}) {
+			Expect(err).ShouldNot(HaveOccurred())
+			envs = append(envs, env)
+		}
+
+		Expect(envs).To(Equal(
+			[]Envelope{
+				{Offset: 2, RecordedAt: now, Message: MessageA2, MessageID: "<id>@2"},
+			},
+		))
+	})
+
+	It("stops when the caller breaks out of the loop", func() {
+		var envs []Envelope
+		for env, err := range Events(ctx, stream, 0, nil) {
+			Expect(err).ShouldNot(HaveOccurred())
+			envs = append(envs, env)
+
+			if len(envs) == 2 {
+				break
+			}
+		}
+
+		Expect(envs).To(HaveLen(2))
+	})
+
+	It("yields an error if the context is canceled", func() {
+		var errs []error
+		for _, err := range Events(ctx, stream, 0, nil) {
+			if err != nil {
+				errs = append(errs, err)
+			} else {
+				cancel()
+			}
+		}
+
+		Expect(errs).To(Equal([]error{context.Canceled}))
+	})
+
+	It("yields an error if the stream can not be read", func() {
+		stream.Truncate(2)
+
+		var errs []error
+		for _, err := range Events(ctx, stream, 0, nil) {
+			errs = append(errs, err)
+		}
+
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0]).To(MatchError("can not read truncated event at offset 0, the first available offset is 2"))
+	})
+
+	It("does not yield anything if the offset is beyond the end of a sealed stream", func() {
+		stream.Seal()
+
+		for range Events(ctx, stream, 10, nil) {
+			Fail("unexpected iteration")
+		}
+	})
+})
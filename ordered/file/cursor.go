@@ -0,0 +1,166 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+)
+
+var errCursorClosed = errors.New("cursor is closed")
+
+var (
+	_ ordered.PeekCursor   = (*cursor)(nil)
+	_ ordered.FilterCursor = (*cursor)(nil)
+)
+
+// cursor is an implementation of ordered.Cursor that reads events from a log
+// file.
+type cursor struct {
+	stream    *Stream
+	offset    uint64
+	filter    map[string]struct{}
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// Next returns the next relevant event in the stream.
+//
+// If the end of the stream is reached it blocks until a relevant event is
+// appended to the stream, ctx is canceled or the stream is sealed. If the
+// stream is sealed, ordered.ErrStreamSealed is returned.
+func (c *cursor) Next(ctx context.Context) (ordered.Envelope, error) {
+	env, err := c.Peek(ctx)
+	if err != nil {
+		return ordered.Envelope{}, err
+	}
+
+	c.offset = env.Offset + 1
+
+	return env, nil
+}
+
+// Peek returns the next relevant event in the stream without advancing the
+// cursor past it.
+//
+// It blocks in the same way as Next().
+func (c *cursor) Peek(ctx context.Context) (ordered.Envelope, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return ordered.Envelope{}, ctx.Err()
+		case <-c.closed:
+			return ordered.Envelope{}, errCursorClosed
+		default:
+		}
+
+		e, ready, err := c.get()
+		if err != nil {
+			return ordered.Envelope{}, err
+		}
+
+		if ready == nil {
+			return c.read(e)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ordered.Envelope{}, ctx.Err()
+		case <-c.closed:
+			return ordered.Envelope{}, errCursorClosed
+		case <-ready:
+		}
+	}
+}
+
+// SetFilter replaces the cursor's message-type filter.
+//
+// The new filter applies to events read from the cursor's current position
+// onwards. Events that have already been returned are never re-read.
+func (c *cursor) SetFilter(filter []dogma.Message) {
+	ids := c.stream.typeIDs(filter)
+
+	c.stream.m.Lock()
+	defer c.stream.m.Unlock()
+
+	c.filter = ids
+}
+
+// Close stops the cursor.
+//
+// Any current or future calls to Next() return a non-nil error.
+func (c *cursor) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+
+	return nil
+}
+
+// get returns the index entry of the next relevant event.
+//
+// If there are no more relevant events it returns a channel that is closed
+// when the stream changes.
+func (c *cursor) get() (entry, <-chan struct{}, error) {
+	s := c.stream
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.closed {
+		return entry{}, nil, errStreamClosed
+	}
+
+	for c.offset < uint64(len(s.index)) {
+		e := s.index[c.offset]
+
+		if c.filter == nil {
+			return e, nil, nil
+		}
+
+		if _, ok := c.filter[e.typeID]; ok {
+			return e, nil, nil
+		}
+
+		c.offset++
+	}
+
+	if s.sealed {
+		return entry{}, nil, ordered.ErrStreamSealed
+	}
+
+	if s.ready == nil {
+		s.ready = make(chan struct{})
+	}
+
+	return entry{}, s.ready, nil
+}
+
+// read reads the event described by e from the file.
+func (c *cursor) read(e entry) (ordered.Envelope, error) {
+	s := c.stream
+
+	s.m.Lock()
+	size := s.size
+	s.m.Unlock()
+
+	rec, _, err := readRecord(s.file, e.pos, size)
+	if err != nil {
+		return ordered.Envelope{}, fmt.Errorf("unable to read event at offset %d: %w", c.offset, err)
+	}
+
+	m, err := s.Marshaler.Unmarshal(rec.TypeID, rec.Data)
+	if err != nil {
+		return ordered.Envelope{}, fmt.Errorf("unable to unmarshal event at offset %d: %w", c.offset, err)
+	}
+
+	return ordered.Envelope{
+		Offset:     rec.Offset,
+		RecordedAt: rec.RecordedAt,
+		Message:    m,
+		MessageID:  ordered.MessageID(s.ID(), rec.Offset),
+	}, nil
+}
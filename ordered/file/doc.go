@@ -0,0 +1,3 @@
+// Package file provides an implementation of ordered.Stream that stores events
+// in an append-only log file.
+package file
@@ -0,0 +1,149 @@
+package file
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+)
+
+// Each record is stored as a header followed by a payload.
+//
+// The header contains the length of the payload and its CRC-32 (Castagnoli)
+// checksum, each encoded as a 4-byte big-endian integer.
+//
+// The first byte of the payload is the record kind. The payload of an event
+// record continues with the event's offset (8 bytes), the time at which it was
+// recorded as nanoseconds since the Unix epoch (8 bytes), the length of the
+// type ID (2 bytes), the type ID itself and finally the marshaled message data.
+// A seal record has no further content.
+const (
+	headerSize      = 8
+	eventHeaderSize = 1 + 8 + 8 + 2
+)
+
+// recordKind identifies the type of a record.
+type recordKind byte
+
+const (
+	eventRecord recordKind = iota + 1
+	sealRecord
+)
+
+var table = crc32.MakeTable(crc32.Castagnoli)
+
+// errTornRecord indicates that a record extends beyond the end of the file,
+// which occurs if the process crashes while the record is being written.
+var errTornRecord = errors.New("record is incomplete")
+
+// record is a single entry within the log file.
+type record struct {
+	Kind       recordKind
+	Offset     uint64
+	RecordedAt time.Time
+	TypeID     string
+	Data       []byte
+}
+
+// appendRecord appends the binary representation of r to buf.
+func appendRecord(buf []byte, r record) ([]byte, error) {
+	if len(r.TypeID) > 0xffff {
+		return nil, fmt.Errorf("type ID is too long (%d bytes)", len(r.TypeID))
+	}
+
+	start := len(buf)
+	buf = append(buf, make([]byte, headerSize)...)
+	buf = append(buf, byte(r.Kind))
+
+	if r.Kind == eventRecord {
+		buf = binary.BigEndian.AppendUint64(buf, r.Offset)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(r.RecordedAt.UnixNano()))
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(r.TypeID)))
+		buf = append(buf, r.TypeID...)
+		buf = append(buf, r.Data...)
+	}
+
+	payload := buf[start+headerSize:]
+	binary.BigEndian.PutUint32(buf[start:], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[start+4:], crc32.Checksum(payload, table))
+
+	return buf, nil
+}
+
+// readRecord reads the record at the given position within r.
+//
+// size is the size of the file, which bounds the records that can be read. It
+// returns the record and the position of the next record. The position of the
+// next record is returned even if the record is corrupt, provided that its
+// header could be read.
+func readRecord(r io.ReaderAt, pos, size int64) (record, int64, error) {
+	if size-pos < headerSize {
+		return record{}, 0, errTornRecord
+	}
+
+	var header [headerSize]byte
+	if _, err := r.ReadAt(header[:], pos); err != nil {
+		return record{}, 0, err
+	}
+
+	n := int64(binary.BigEndian.Uint32(header[:]))
+	sum := binary.BigEndian.Uint32(header[4:])
+	next := pos + headerSize + n
+
+	if next > size {
+		return record{}, 0, errTornRecord
+	}
+
+	payload := make([]byte, n)
+	if _, err := r.ReadAt(payload, pos+headerSize); err != nil {
+		return record{}, 0, err
+	}
+
+	if crc32.Checksum(payload, table) != sum {
+		return record{}, next, fmt.Errorf("checksum mismatch in record at position %d", pos)
+	}
+
+	rec, err := decodePayload(payload)
+	if err != nil {
+		return record{}, next, fmt.Errorf("malformed record at position %d: %w", pos, err)
+	}
+
+	return rec, next, nil
+}
+
+// decodePayload decodes the payload of a record.
+func decodePayload(p []byte) (record, error) {
+	if len(p) == 0 {
+		return record{}, errors.New("payload is empty")
+	}
+
+	rec := record{Kind: recordKind(p[0])}
+
+	switch rec.Kind {
+	case sealRecord:
+		return rec, nil
+	case eventRecord:
+	default:
+		return record{}, fmt.Errorf("unrecognized record kind (%d)", rec.Kind)
+	}
+
+	if len(p) < eventHeaderSize {
+		return record{}, errors.New("payload is too short")
+	}
+
+	rec.Offset = binary.BigEndian.Uint64(p[1:])
+	rec.RecordedAt = time.Unix(0, int64(binary.BigEndian.Uint64(p[9:])))
+	n := int(binary.BigEndian.Uint16(p[17:]))
+	p = p[eventHeaderSize:]
+
+	if len(p) < n {
+		return record{}, errors.New("type ID is truncated")
+	}
+
+	rec.TypeID = string(p[:n])
+	rec.Data = p[n:]
+
+	return rec, nil
+}
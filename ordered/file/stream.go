@@ -0,0 +1,408 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dogmatiq/aperture/marshaling"
+	"github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+)
+
+// SyncPolicy determines when data written to the log file is flushed to stable
+// storage.
+type SyncPolicy int
+
+const (
+	// SyncAlways flushes the log file to stable storage before Append() or
+	// Seal() returns, so that acknowledged events survive a crash of the host.
+	// It is the default.
+	SyncAlways SyncPolicy = iota
+
+	// SyncNever leaves flushing the log file to the operating system.
+	// Acknowledged events survive a crash of the process, but may be lost if
+	// the host crashes.
+	SyncNever
+)
+
+var errStreamClosed = errors.New("stream is closed")
+
+// Stream is an implementation of ordered.Stream that stores events in an
+// append-only log file.
+//
+// Each record in the file is protected by a checksum. When the file is first
+// accessed it is scanned to build an in-memory index of the position of each
+// event. If the process crashed while a record was being written, the
+// incomplete record is discarded.
+//
+// Only a single Stream may access a given file at any one time, and cursors
+// are only notified of events appended via that Stream.
+type Stream struct {
+	// StreamID is a unique identifier for the stream, it must not be empty.
+	// The tuple of stream ID and event offset must uniquely identify a message.
+	StreamID string
+
+	// Path is the path to the log file. It is created if it does not exist.
+	Path string
+
+	// Marshaler is used to marshal and unmarshal event messages. It must
+	// support every type of message appended to the stream.
+	Marshaler marshaling.Marshaler
+
+	// Sync determines when data written to the log file is flushed to stable
+	// storage.
+	Sync SyncPolicy
+
+	m      sync.Mutex
+	file   *os.File
+	size   int64
+	index  []entry
+	sealed bool
+	closed bool
+	ready  chan struct{}
+}
+
+// entry is the index entry for a single event.
+type entry struct {
+	pos    int64
+	typeID string
+}
+
+var (
+	_ ordered.HeadStream   = (*Stream)(nil)
+	_ ordered.BoundsStream = (*Stream)(nil)
+)
+
+// ID returns a unique identifier for the stream.
+//
+// The tuple of stream ID and event offset must uniquely identify a message.
+func (s *Stream) ID() string {
+	if s.StreamID == "" {
+		panic("stream ID must not be empty")
+	}
+
+	return s.StreamID
+}
+
+// Open returns a cursor used to read events from this stream.
+//
+// offset is the position of the first event to read. The first event on a
+// stream is always at offset 0. If the given offset is beyond the end of a
+// sealed stream, a *ordered.SealedError is returned.
+//
+// filter is a set of zero-value event messages, the types of which indicate
+// which event types are returned by Cursor.Next(). If filter is empty, all
+// events types are returned. The type ID of each filter type is obtained
+// by marshaling the zero-value message. Types that are not supported by the
+// marshaler can never appear on the stream, and hence are ignored.
+func (s *Stream) Open(
+	ctx context.Context,
+	offset uint64,
+	filter []dogma.Message,
+) (ordered.Cursor, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	next := uint64(len(s.index))
+	if s.sealed && offset >= next {
+		err := &ordered.SealedError{RequestedOffset: offset}
+		if next > 0 {
+			err.LastOffset = next - 1
+		}
+		return nil, err
+	}
+
+	return &cursor{
+		stream: s,
+		offset: offset,
+		filter: s.typeIDs(filter),
+		closed: make(chan struct{}),
+	}, nil
+}
+
+// typeIDs returns the set of type IDs of the messages in filter, or nil if
+// filter is empty.
+func (s *Stream) typeIDs(filter []dogma.Message) map[string]struct{} {
+	if len(filter) == 0 {
+		return nil
+	}
+
+	ids := map[string]struct{}{}
+
+	for _, m := range filter {
+		if _, id, err := s.Marshaler.Marshal(m); err == nil {
+			ids[id] = struct{}{}
+		}
+	}
+
+	return ids
+}
+
+// Head returns the offset of the stream's head, that is, the offset at which
+// the next event will be appended.
+//
+// final is true if the stream is sealed, in which case the head never changes.
+func (s *Stream) Head(ctx context.Context) (offset uint64, final bool, err error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if err := s.load(); err != nil {
+		return 0, false, err
+	}
+
+	return uint64(len(s.index)), s.sealed, nil
+}
+
+// Bounds returns the range of offsets of the events that are available on the
+// stream.
+//
+// first is always zero, as events are never removed from the file. next is
+// the offset at which the next event will be appended.
+func (s *Stream) Bounds(ctx context.Context) (first, next uint64, err error) {
+	next, _, err = s.Head(ctx)
+	return 0, next, err
+}
+
+// Append appends messages to the end of the stream.
+//
+// Every message is recorded at the same time, t. The messages are written to
+// the file in a single write. If s.Sync is SyncAlways the file is flushed to
+// stable storage before Append() returns.
+//
+// It returns an error if the stream is sealed.
+func (s *Stream) Append(t time.Time, messages ...dogma.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	records := make([]record, len(messages))
+	for i, m := range messages {
+		data, id, err := s.Marshaler.Marshal(m)
+		if err != nil {
+			return fmt.Errorf("unable to marshal %T message: %w", m, err)
+		}
+
+		records[i] = record{
+			Kind:       eventRecord,
+			RecordedAt: t,
+			TypeID:     id,
+			Data:       data,
+		}
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	if s.sealed {
+		return errors.New("can not append to a sealed stream")
+	}
+
+	var (
+		buf     []byte
+		entries = make([]entry, len(records))
+		next    = uint64(len(s.index))
+	)
+
+	for i, rec := range records {
+		entries[i] = entry{
+			pos:    s.size + int64(len(buf)),
+			typeID: rec.TypeID,
+		}
+
+		rec.Offset = next + uint64(i)
+
+		var err error
+		buf, err = appendRecord(buf, rec)
+		if err != nil {
+			return fmt.Errorf("unable to append %T message: %w", messages[i], err)
+		}
+	}
+
+	if err := s.write(buf); err != nil {
+		return err
+	}
+
+	s.index = append(s.index, entries...)
+	s.notify()
+
+	return nil
+}
+
+// Seal marks the stream as sealed, preventing new events from being appended.
+//
+// A seal record is written to the file, so the stream remains sealed when the
+// file is opened again.
+func (s *Stream) Seal() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	if s.sealed {
+		return nil
+	}
+
+	buf, err := appendRecord(nil, record{Kind: sealRecord})
+	if err != nil {
+		return err
+	}
+
+	if err := s.write(buf); err != nil {
+		return err
+	}
+
+	s.sealed = true
+	s.notify()
+
+	return nil
+}
+
+// Close closes the log file.
+//
+// Any current or future calls to Next() on the stream's cursors return a
+// non-nil error, as do calls to the stream's other methods.
+func (s *Stream) Close() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.closed {
+		return nil
+	}
+
+	s.closed = true
+	s.notify()
+
+	if s.file == nil {
+		return nil
+	}
+
+	return s.file.Close()
+}
+
+// load opens the log file and builds the index, if it has not already been
+// done. s.m must be locked.
+func (s *Stream) load() error {
+	if s.closed {
+		return errStreamClosed
+	}
+
+	if s.file != nil {
+		return nil
+	}
+
+	f, err := os.OpenFile(s.Path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	if err := s.scan(f); err != nil {
+		f.Close()
+		s.index = nil
+		s.sealed = false
+		return fmt.Errorf("unable to load '%s': %w", s.Path, err)
+	}
+
+	s.file = f
+
+	return nil
+}
+
+// scan reads every record in f to build the index.
+//
+// If the last record in the file is incomplete or corrupt it is assumed to be
+// the result of a crash during a write, and the file is truncated to remove
+// it.
+func (s *Stream) scan(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	var pos int64
+	size := info.Size()
+
+	for pos < size {
+		rec, next, err := readRecord(f, pos, size)
+		if err != nil {
+			if errors.Is(err, errTornRecord) || next == size {
+				if err := f.Truncate(pos); err != nil {
+					return err
+				}
+				break
+			}
+
+			return err
+		}
+
+		if s.sealed {
+			return fmt.Errorf("unexpected record at position %d, the stream is sealed", pos)
+		}
+
+		switch rec.Kind {
+		case sealRecord:
+			s.sealed = true
+		case eventRecord:
+			if rec.Offset != uint64(len(s.index)) {
+				return fmt.Errorf(
+					"unexpected offset in record at position %d, expected %d, got %d",
+					pos,
+					len(s.index),
+					rec.Offset,
+				)
+			}
+
+			s.index = append(s.index, entry{pos, rec.TypeID})
+		}
+
+		pos = next
+	}
+
+	s.size = pos
+
+	return nil
+}
+
+// write appends buf to the log file, flushing it to stable storage according
+// to the sync policy. s.m must be locked.
+//
+// If an error occurs the file is truncated to remove any partially written
+// data.
+func (s *Stream) write(buf []byte) error {
+	_, err := s.file.Write(buf)
+
+	if err == nil && s.Sync == SyncAlways {
+		err = s.file.Sync()
+	}
+
+	if err != nil {
+		s.file.Truncate(s.size)
+		return err
+	}
+
+	s.size += int64(len(buf))
+
+	return nil
+}
+
+// notify wakes any cursors that are waiting for the stream to change. s.m must
+// be locked.
+func (s *Stream) notify() {
+	if s.ready != nil {
+		close(s.ready)
+		s.ready = nil
+	}
+}
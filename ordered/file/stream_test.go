@@ -0,0 +1,453 @@
+package file_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dogmatiq/aperture/marshaling"
+	"github.com/dogmatiq/aperture/ordered"
+	. "github.com/dogmatiq/aperture/ordered/file"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type Stream", func() {
+	var (
+		ctx       context.Context
+		cancel    func()
+		path      string
+		marshaler marshaling.Marshaler
+		stream    *Stream
+		now       time.Time
+	)
+
+	// reopen returns a new stream that uses the same file as stream.
+	reopen := func() *Stream {
+		stream.Close()
+
+		return &Stream{
+			StreamID:  "<id>",
+			Path:      path,
+			Marshaler: marshaler,
+		}
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+
+		var err error
+		marshaler, err = marshaling.NewMarshaler(
+			MessageA{},
+			MessageB{},
+			MessageC{},
+		)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		path = filepath.Join(GinkgoT().TempDir(), "stream.log")
+
+		stream = &Stream{
+			StreamID:  "<id>",
+			Path:      path,
+			Marshaler: marshaler,
+		}
+
+		now = time.Now()
+
+		err = stream.Append(
+			now,
+			MessageA1,
+			MessageB1,
+			MessageA2,
+			MessageB2,
+		)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		stream.Close()
+		cancel()
+	})
+
+	Describe("func ID()", func() {
+		It("returns the stream ID", func() {
+			Expect(stream.ID()).To(Equal("<id>"))
+		})
+
+		It("panics if the stream ID is empty", func() {
+			stream.StreamID = ""
+
+			Expect(func() {
+				stream.ID()
+			}).To(Panic())
+		})
+	})
+
+	Describe("func Open()", func() {
+		It("honours the initial offset", func() {
+			cur, err := stream.Open(ctx, 2, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeNumerically("==", 2))
+			Expect(env.RecordedAt).To(BeTemporally("==", now))
+			Expect(env.Message).To(Equal(MessageA2))
+			Expect(env.MessageID).To(Equal("<id>@2"))
+		})
+
+		It("applies the message type filter", func() {
+			cur, err := stream.Open(ctx, 0, []dogma.Message{MessageB{}})
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeNumerically("==", 1))
+			Expect(env.Message).To(Equal(MessageB1))
+
+			env, err = cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeNumerically("==", 3))
+			Expect(env.Message).To(Equal(MessageB2))
+		})
+
+		It("does not return any events when the filter is FilterNone", func() {
+			cur, err := stream.Open(ctx, 0, ordered.FilterNone)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+			defer cancel()
+
+			_, err = cur.Next(ctx)
+			Expect(err).To(Equal(context.DeadlineExceeded))
+		})
+
+		It("reads events written by a previous stream", func() {
+			stream = reopen()
+
+			cur, err := stream.Open(ctx, 3, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeNumerically("==", 3))
+			Expect(env.Message).To(Equal(MessageB2))
+		})
+
+		It("discards an incomplete record at the end of the file", func() {
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+			Expect(err).ShouldNot(HaveOccurred())
+			_, err = f.Write([]byte{0, 0, 1, 0, 1, 2, 3})
+			Expect(err).ShouldNot(HaveOccurred())
+			f.Close()
+
+			stream = reopen()
+
+			err = stream.Append(now, MessageC1)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			cur, err := stream.Open(ctx, 3, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Message).To(Equal(MessageB2))
+
+			env, err = cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeNumerically("==", 4))
+			Expect(env.Message).To(Equal(MessageC1))
+		})
+
+		It("returns an error if a record before the end of the file is corrupt", func() {
+			stream.Close()
+
+			data, err := os.ReadFile(path)
+			Expect(err).ShouldNot(HaveOccurred())
+			data[30] ^= 0xff // within the payload of the first record
+			err = os.WriteFile(path, data, 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			stream = reopen()
+
+			_, err = stream.Open(ctx, 0, nil)
+			Expect(err).To(MatchError(ContainSubstring("checksum mismatch")))
+		})
+
+		It("returns an error if the stream is closed", func() {
+			stream.Close()
+
+			_, err := stream.Open(ctx, 0, nil)
+			Expect(err).To(MatchError("stream is closed"))
+		})
+
+		Context("when the stream is sealed", func() {
+			BeforeEach(func() {
+				err := stream.Seal()
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+
+			It("returns a cursor if the offset is already on the stream", func() {
+				cur, err := stream.Open(ctx, 3, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				_, err = cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				_, err = cur.Next(ctx)
+				Expect(err).To(Equal(ordered.ErrStreamSealed))
+			})
+
+			It("returns a *SealedError if offset is beyond the end of the stream", func() {
+				_, err := stream.Open(ctx, 4, nil)
+				Expect(err).To(Equal(
+					&ordered.SealedError{
+						RequestedOffset: 4,
+						LastOffset:      3,
+					},
+				))
+			})
+
+			It("remains sealed when the file is opened again", func() {
+				stream = reopen()
+
+				_, final, err := stream.Head(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(final).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("func Head()", func() {
+		It("returns the offset of the next event", func() {
+			offset, final, err := stream.Head(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(offset).To(BeNumerically("==", 4))
+			Expect(final).To(BeFalse())
+		})
+
+		It("returns zero for a new file", func() {
+			stream = &Stream{
+				StreamID:  "<id>",
+				Path:      filepath.Join(GinkgoT().TempDir(), "empty.log"),
+				Marshaler: marshaler,
+			}
+
+			offset, _, err := stream.Head(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(offset).To(BeZero())
+		})
+	})
+
+	Describe("func SetFilter()", func() {
+		It("applies the new filter from the cursor's current position", func() {
+			cur, err := stream.Open(ctx, 0, []dogma.Message{MessageB{}})
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Message).To(Equal(MessageB1))
+
+			cur.(ordered.FilterCursor).SetFilter([]dogma.Message{MessageA{}})
+
+			env, err = cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Message).To(Equal(MessageA2))
+		})
+	})
+
+	Describe("func Peek()", func() {
+		It("returns the next relevant event without advancing the cursor", func() {
+			cur, err := stream.Open(ctx, 0, []dogma.Message{MessageB{}})
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			peeked, err := cur.(ordered.PeekCursor).Peek(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(peeked.Offset).To(BeNumerically("==", 1))
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(Equal(peeked.Offset))
+			Expect(env.Message).To(Equal(MessageB1))
+
+			env, err = cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Message).To(Equal(MessageB2))
+		})
+	})
+
+	Describe("func Bounds()", func() {
+		It("returns the offsets of the first and next events", func() {
+			first, next, err := stream.Bounds(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(first).To(BeNumerically("==", 0))
+			Expect(next).To(BeNumerically("==", 4))
+		})
+	})
+
+	Describe("func Append()", func() {
+		It("wakes waiting consumers", func() {
+			cur, err := stream.Open(ctx, 4, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(20 * time.Millisecond)
+				err := stream.Append(now, MessageC1)
+				Expect(err).ShouldNot(HaveOccurred())
+			}()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeNumerically("==", 4))
+			Expect(env.Message).To(Equal(MessageC1))
+		})
+
+		It("does not append any events if a message can not be marshaled", func() {
+			err := stream.Append(now, MessageC1, MessageD1)
+			Expect(err).Should(HaveOccurred())
+
+			offset, _, err := stream.Head(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(offset).To(BeNumerically("==", 4))
+		})
+
+		It("returns an error if the stream is sealed", func() {
+			err := stream.Seal()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = stream.Append(now, MessageC1)
+			Expect(err).To(MatchError("can not append to a sealed stream"))
+		})
+
+		It("does not flush the file if Sync is SyncNever", func() {
+			stream.Sync = SyncNever
+
+			err := stream.Append(now, MessageC1)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			stream = reopen()
+
+			offset, _, err := stream.Head(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(offset).To(BeNumerically("==", 5))
+		})
+	})
+
+	Describe("func Seal()", func() {
+		It("wakes waiting consumers", func() {
+			cur, err := stream.Open(ctx, 4, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(20 * time.Millisecond)
+				err := stream.Seal()
+				Expect(err).ShouldNot(HaveOccurred())
+			}()
+
+			_, err = cur.Next(ctx)
+			Expect(err).To(Equal(ordered.ErrStreamSealed))
+		})
+
+		It("does not return an error if called on an already-sealed stream", func() {
+			err := stream.Seal()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = stream.Seal()
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+	})
+
+	Describe("type cursor", func() {
+		Describe("func Next()", func() {
+			It("returns an error if the cursor is closed", func() {
+				cur, err := stream.Open(ctx, 0, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				cur.Close()
+
+				_, err = cur.Next(ctx)
+				Expect(err).To(MatchError("cursor is closed"))
+			})
+
+			It("returns an error if the stream is closed while waiting", func() {
+				cur, err := stream.Open(ctx, 4, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				go func() {
+					time.Sleep(20 * time.Millisecond)
+					stream.Close()
+				}()
+
+				_, err = cur.Next(ctx)
+				Expect(err).To(MatchError("stream is closed"))
+			})
+
+			It("does not advance the cursor if the context is canceled", func() {
+				cur, err := stream.Open(ctx, 4, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				wctx, wcancel := context.WithTimeout(ctx, 20*time.Millisecond)
+				defer wcancel()
+
+				_, err = cur.Next(wctx)
+				Expect(err).To(Equal(context.DeadlineExceeded))
+
+				err = stream.Append(now, MessageC1)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				env, err := cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Offset).To(BeNumerically("==", 4))
+			})
+		})
+	})
+
+	It("can be used by a projector", func() {
+		handler := &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageB{})
+			},
+		}
+
+		var messages []dogma.Message
+		handler.HandleEventFunc = func(
+			_ context.Context,
+			_, _, _ []byte,
+			_ dogma.ProjectionEventScope,
+			m dogma.Message,
+		) (bool, error) {
+			messages = append(messages, m)
+			if len(messages) == 2 {
+				cancel()
+			}
+			return true, nil
+		}
+
+		proj := &ordered.Projector{
+			Stream:  stream,
+			Handler: handler,
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+		Expect(messages).To(Equal([]dogma.Message{MessageB1, MessageB2}))
+	})
+})
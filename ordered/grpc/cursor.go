@@ -0,0 +1,111 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/aperture/ordered/grpc/internal/streampb"
+)
+
+// cursor is an implementation of ordered.Cursor that reads events from a
+// streaming RPC.
+type cursor struct {
+	stream *Stream
+	rpc    streampb.StreamAPI_OpenClient
+	err    error
+
+	// done is canceled when the cursor is closed, which cancels the RPC.
+	done   context.Context
+	cancel context.CancelFunc
+
+	// results is used by recv() to pass each event received from the server
+	// to Next(). It is unbuffered, so an event is only received once the
+	// previous one has been consumed.
+	results chan result
+}
+
+// result is the outcome of receiving a single event from the server.
+type result struct {
+	event *streampb.Event
+	err   error
+}
+
+// Next returns the next relevant event in the stream.
+//
+// If the end of the stream is reached it blocks until a relevant event is
+// appended to the stream, ctx is canceled or the stream is sealed. If the
+// stream is sealed, ordered.ErrStreamSealed is returned.
+func (c *cursor) Next(ctx context.Context) (ordered.Envelope, error) {
+	if c.done.Err() != nil {
+		return ordered.Envelope{}, errCursorClosed
+	}
+
+	if c.err != nil {
+		return ordered.Envelope{}, c.err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ordered.Envelope{}, ctx.Err()
+	case <-c.done.Done():
+		return ordered.Envelope{}, errCursorClosed
+	case r := <-c.results:
+		if r.err != nil {
+			c.err = unmarshalError(r.err)
+			return ordered.Envelope{}, c.err
+		}
+
+		return c.unmarshal(r.event)
+	}
+}
+
+// Close stops the cursor.
+//
+// Any current or future calls to Next() return a non-nil error. Closing the
+// cursor cancels the RPC, which closes the cursor on the server.
+func (c *cursor) Close() error {
+	c.cancel()
+	return nil
+}
+
+// recv receives events from the server until the RPC ends.
+func (c *cursor) recv() {
+	for {
+		ev, err := c.rpc.Recv()
+
+		select {
+		case c.results <- result{ev, err}:
+		case <-c.done.Done():
+			return
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// unmarshal returns the envelope represented by ev.
+func (c *cursor) unmarshal(ev *streampb.Event) (ordered.Envelope, error) {
+	m, err := c.stream.Marshaler.Unmarshal(
+		ev.GetMessage().GetTypeId(),
+		ev.GetMessage().GetData(),
+	)
+	if err != nil {
+		return ordered.Envelope{}, fmt.Errorf("unable to unmarshal event at offset %d: %w", ev.GetOffset(), err)
+	}
+
+	env := ordered.Envelope{
+		Offset:     ev.GetOffset(),
+		RecordedAt: ev.GetRecordedAt().AsTime(),
+		Message:    m,
+		MessageID:  ev.GetMessageId(),
+	}
+
+	if env.MessageID == "" {
+		env.MessageID = ordered.MessageID(c.stream.ID(), env.Offset)
+	}
+
+	return env, nil
+}
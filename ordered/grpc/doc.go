@@ -0,0 +1,4 @@
+// Package grpc provides a gRPC server that exposes ordered streams to remote
+// consumers, and an implementation of ordered.Stream that consumes events from
+// such a server.
+package grpc
@@ -0,0 +1,94 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/aperture/ordered/grpc/internal/streampb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+var errCursorClosed = errors.New("cursor is closed")
+
+// marshalError returns the gRPC status error that represents err on the wire.
+//
+// Sealed and truncated stream errors are represented by an OUT_OF_RANGE status
+// with details that allow the client to reconstruct the original error.
+func marshalError(err error) error {
+	var (
+		sealed    *ordered.SealedError
+		truncated *ordered.TruncatedError
+	)
+
+	switch {
+	case errors.As(err, &sealed):
+		return withDetails(
+			codes.OutOfRange,
+			err,
+			&streampb.SealedError{
+				RequestedOffset: sealed.RequestedOffset,
+				LastOffset:      sealed.LastOffset,
+				AtOpen:          true,
+			},
+		)
+	case errors.Is(err, ordered.ErrStreamSealed):
+		return withDetails(codes.OutOfRange, err, &streampb.SealedError{})
+	case errors.As(err, &truncated):
+		return withDetails(
+			codes.OutOfRange,
+			err,
+			&streampb.TruncatedError{
+				RequestedOffset: truncated.RequestedOffset,
+				FirstOffset:     truncated.FirstOffset,
+			},
+		)
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return status.Error(codes.Unknown, err.Error())
+	}
+}
+
+// withDetails returns a status error with the given code and details.
+func withDetails(c codes.Code, err error, d protoadapt.MessageV1) error {
+	st, e := status.New(c, err.Error()).WithDetails(d)
+	if e != nil {
+		return status.Error(c, err.Error())
+	}
+
+	return st.Err()
+}
+
+// unmarshalError returns the error represented by a gRPC status error
+// returned by the server.
+func unmarshalError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *streampb.SealedError:
+			if d.GetAtOpen() {
+				return &ordered.SealedError{
+					RequestedOffset: d.GetRequestedOffset(),
+					LastOffset:      d.GetLastOffset(),
+				}
+			}
+			return ordered.ErrStreamSealed
+		case *streampb.TruncatedError:
+			return &ordered.TruncatedError{
+				RequestedOffset: d.GetRequestedOffset(),
+				FirstOffset:     d.GetFirstOffset(),
+			}
+		}
+	}
+
+	return err
+}
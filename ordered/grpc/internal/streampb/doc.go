@@ -0,0 +1,5 @@
+// Package streampb contains the Protocol Buffers messages and gRPC service
+// definitions used by the gRPC stream client and server.
+package streampb
+
+//go:generate protoc --go_out=paths=source_relative:. --go-grpc_out=paths=source_relative:. stream.proto
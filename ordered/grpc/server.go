@@ -0,0 +1,142 @@
+package grpc
+
+import (
+	"fmt"
+
+	"github.com/dogmatiq/aperture/marshaling"
+	"github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/aperture/ordered/grpc/internal/streampb"
+	"github.com/dogmatiq/dogma"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server exposes ordered streams to remote consumers via gRPC.
+//
+// Use Register() to add the server's service to a gRPC server. Remote
+// consumers read the streams using a Stream.
+type Server struct {
+	// Streams is the set of streams that are available to consumers. Each
+	// stream is identified by its ID.
+	Streams []ordered.Stream
+
+	// Marshaler is used to marshal and unmarshal event messages. It must
+	// support every type of message on the streams.
+	Marshaler marshaling.Marshaler
+}
+
+// Register registers the server's service with r.
+func (s *Server) Register(r grpc.ServiceRegistrar) {
+	streampb.RegisterStreamAPIServer(r, &api{server: s})
+}
+
+// api is the implementation of streampb.StreamAPIServer.
+type api struct {
+	streampb.UnimplementedStreamAPIServer
+
+	server *Server
+}
+
+// Open reads events from a stream and sends them to the client.
+//
+// The response header is sent once the cursor has been opened, allowing the
+// client to report errors that occur while opening the stream from
+// Stream.Open(). Events are sent as they become available, the gRPC stream's
+// flow control prevents the server from reading events faster than the client
+// consumes them.
+func (a *api) Open(req *streampb.OpenRequest, rpc streampb.StreamAPI_OpenServer) error {
+	ctx := rpc.Context()
+
+	stream, ok := a.stream(req.GetStreamId())
+	if !ok {
+		return status.Errorf(codes.NotFound, "stream '%s' does not exist", req.GetStreamId())
+	}
+
+	cur, err := stream.Open(ctx, req.GetOffset(), a.filter(req))
+	if err != nil {
+		return marshalError(err)
+	}
+	defer cur.Close()
+
+	if err := rpc.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		env, err := cur.Next(ctx)
+		if err != nil {
+			return marshalError(err)
+		}
+
+		if env.Synthetic {
+			// Synthetic events do not exist on the stream itself. Consumers
+			// that require them can wrap the client's stream instead.
+			continue
+		}
+
+		ev, err := a.marshal(env)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+
+		if err := rpc.Send(ev); err != nil {
+			return err
+		}
+	}
+}
+
+// stream returns the stream with the given ID.
+func (a *api) stream(id string) (ordered.Stream, bool) {
+	for _, s := range a.server.Streams {
+		if s.ID() == id {
+			return s, true
+		}
+	}
+
+	return nil, false
+}
+
+// filter returns the message-type filter described by req.
+//
+// Filter types that are not supported by the server's marshaler can never
+// appear on the stream, and hence are ignored.
+func (a *api) filter(req *streampb.OpenRequest) []dogma.Message {
+	if !req.GetFiltered() {
+		return nil
+	}
+
+	var filter []dogma.Message
+
+	for _, m := range req.GetFilter() {
+		if f, err := a.server.Marshaler.Unmarshal(m.GetTypeId(), m.GetData()); err == nil {
+			filter = append(filter, f)
+		}
+	}
+
+	if len(filter) == 0 {
+		return ordered.FilterNone
+	}
+
+	return filter
+}
+
+// marshal returns the wire representation of env.
+func (a *api) marshal(env ordered.Envelope) (*streampb.Event, error) {
+	data, id, err := a.server.Marshaler.Marshal(env.Message)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal event at offset %d: %w", env.Offset, err)
+	}
+
+	return &streampb.Event{
+		Offset:     env.Offset,
+		RecordedAt: timestamppb.New(env.RecordedAt),
+		Message: &streampb.Message{
+			TypeId: id,
+			Data:   data,
+		},
+		MessageId: env.MessageID,
+	}, nil
+}
@@ -0,0 +1,128 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/dogmatiq/aperture/marshaling"
+	"github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/aperture/ordered/grpc/internal/streampb"
+	"github.com/dogmatiq/dogma"
+	"google.golang.org/grpc"
+)
+
+// Stream is an implementation of ordered.Stream that consumes events from a
+// remote Server via gRPC.
+//
+// Each open cursor holds one streaming RPC to the server until it is closed.
+// ordered.ErrStreamSealed, *ordered.SealedError and *ordered.TruncatedError
+// errors that occur on the server are reported by the client as the same
+// types.
+type Stream struct {
+	// StreamID is the ID of the stream on the server, it must not be empty.
+	StreamID string
+
+	// Conn is the connection to the server, such as a *grpc.ClientConn.
+	Conn grpc.ClientConnInterface
+
+	// Marshaler is used to marshal and unmarshal event messages. It must
+	// support every type of message on the stream.
+	Marshaler marshaling.Marshaler
+}
+
+// ID returns a unique identifier for the stream.
+//
+// The tuple of stream ID and event offset must uniquely identify a message.
+func (s *Stream) ID() string {
+	if s.StreamID == "" {
+		panic("stream ID must not be empty")
+	}
+
+	return s.StreamID
+}
+
+// Open returns a cursor used to read events from this stream.
+//
+// offset is the position of the first event to read. The first event on a
+// stream is always at offset 0. If the given offset is beyond the end of a
+// sealed stream, a *ordered.SealedError is returned.
+//
+// filter is a set of zero-value event messages, the types of which indicate
+// which event types are returned by Cursor.Next(). If filter is empty, all
+// events types are returned. The filter is applied by the server. Types that
+// are not supported by the marshaler can never appear on the stream, and hence
+// are ignored.
+//
+// ctx applies only to opening the cursor. The RPC remains open until the
+// cursor is closed.
+func (s *Stream) Open(
+	ctx context.Context,
+	offset uint64,
+	filter []dogma.Message,
+) (ordered.Cursor, error) {
+	req := &streampb.OpenRequest{
+		StreamId: s.ID(),
+		Offset:   offset,
+		Filtered: len(filter) > 0,
+	}
+
+	for _, m := range filter {
+		if data, id, err := s.Marshaler.Marshal(m); err == nil {
+			req.Filter = append(req.Filter, &streampb.Message{
+				TypeId: id,
+				Data:   data,
+			})
+		}
+	}
+
+	// The RPC outlives ctx, but retains its values, such as those used for
+	// tracing.
+	rpcCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	rpc, err := streampb.NewStreamAPIClient(s.Conn).Open(rpcCtx, req)
+	if err != nil {
+		cancel()
+		return nil, s.openError(ctx, err)
+	}
+
+	// The server sends the response header once it has opened its cursor. If
+	// opening failed the RPC ends without a header, and the error is obtained
+	// from Recv().
+	md, err := rpc.Header()
+	if err == nil && md == nil {
+		_, err = rpc.Recv()
+	}
+	if err != nil {
+		cancel()
+		return nil, s.openError(ctx, err)
+	}
+
+	if !stop() {
+		// ctx was canceled after the cursor was opened.
+		cancel()
+		return nil, ctx.Err()
+	}
+
+	c := &cursor{
+		stream:  s,
+		rpc:     rpc,
+		done:    rpcCtx,
+		cancel:  cancel,
+		results: make(chan result),
+	}
+
+	go c.recv()
+
+	return c, nil
+}
+
+// openError returns the error to report from Open() given an error that
+// occurred while starting the RPC.
+func (s *Stream) openError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return unmarshalError(err)
+}
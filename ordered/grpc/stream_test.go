@@ -0,0 +1,308 @@
+package grpc_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/dogmatiq/aperture/marshaling"
+	"github.com/dogmatiq/aperture/ordered"
+	. "github.com/dogmatiq/aperture/ordered/grpc"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var _ ordered.Stream = (*Stream)(nil)
+
+var _ = Describe("type Stream", func() {
+	var (
+		ctx    context.Context
+		cancel func()
+		now    time.Time
+		source *ordered.MemoryStream
+		closed chan struct{}
+		server *grpc.Server
+		conn   *grpc.ClientConn
+		stream *Stream
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+
+		marshaler, err := marshaling.NewMarshaler(
+			MessageA{},
+			MessageB{},
+			MessageC{},
+		)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		now = time.Now()
+
+		source = &ordered.MemoryStream{
+			StreamID: "<id>",
+		}
+
+		source.Append(
+			now,
+			MessageA1,
+			MessageB1,
+			MessageA2,
+			MessageB2,
+		)
+
+		closed = make(chan struct{}, 10)
+
+		lis := bufconn.Listen(1024 * 1024)
+
+		server = grpc.NewServer()
+		(&Server{
+			Streams:   []ordered.Stream{&closeNotifyingStream{source, closed}},
+			Marshaler: marshaler,
+		}).Register(server)
+
+		go server.Serve(lis)
+
+		conn, err = grpc.NewClient(
+			"passthrough:///bufconn",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return lis.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		stream = &Stream{
+			StreamID:  "<id>",
+			Conn:      conn,
+			Marshaler: marshaler,
+		}
+	})
+
+	AfterEach(func() {
+		conn.Close()
+		server.Stop()
+		cancel()
+	})
+
+	Describe("func ID()", func() {
+		It("returns the stream ID", func() {
+			Expect(stream.ID()).To(Equal("<id>"))
+		})
+
+		It("panics if the stream ID is empty", func() {
+			stream.StreamID = ""
+			Expect(func() {
+				stream.ID()
+			}).To(PanicWith("stream ID must not be empty"))
+		})
+	})
+
+	Describe("func Open()", func() {
+		It("honours the initial offset", func() {
+			cur, err := stream.Open(ctx, 2, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeNumerically("==", 2))
+			Expect(env.RecordedAt).To(BeTemporally("==", now))
+			Expect(env.Message).To(Equal(MessageA2))
+			Expect(env.MessageID).To(Equal("<id>@2"))
+
+			env, err = cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeNumerically("==", 3))
+			Expect(env.Message).To(Equal(MessageB2))
+		})
+
+		It("applies the message type filter", func() {
+			cur, err := stream.Open(ctx, 0, []dogma.Message{MessageB{}})
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Message).To(Equal(MessageB1))
+
+			env, err = cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Message).To(Equal(MessageB2))
+		})
+
+		It("returns a *SealedError if the offset is beyond the end of a sealed stream", func() {
+			source.Seal()
+
+			_, err := stream.Open(ctx, 10, nil)
+
+			var sealedErr *ordered.SealedError
+			Expect(errors.As(err, &sealedErr)).To(BeTrue())
+			Expect(sealedErr.RequestedOffset).To(BeNumerically("==", 10))
+			Expect(sealedErr.LastOffset).To(BeNumerically("==", 3))
+			Expect(err).To(MatchError(ordered.ErrStreamSealed))
+		})
+
+		It("returns an error if the stream does not exist on the server", func() {
+			stream.StreamID = "<unknown>"
+
+			_, err := stream.Open(ctx, 0, nil)
+			Expect(err).To(MatchError(ContainSubstring("stream '<unknown>' does not exist")))
+		})
+	})
+
+	Describe("type cursor", func() {
+		Describe("func Next()", func() {
+			It("blocks until an event is appended", func() {
+				cur, err := stream.Open(ctx, 4, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				go func() {
+					time.Sleep(20 * time.Millisecond)
+					source.Append(now, MessageC1)
+				}()
+
+				env, err := cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Offset).To(BeNumerically("==", 4))
+				Expect(env.Message).To(Equal(MessageC1))
+			})
+
+			It("does not advance the cursor if ctx is canceled", func() {
+				cur, err := stream.Open(ctx, 4, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				nctx, ncancel := context.WithTimeout(ctx, 20*time.Millisecond)
+				defer ncancel()
+
+				_, err = cur.Next(nctx)
+				Expect(err).To(Equal(context.DeadlineExceeded))
+
+				source.Append(now, MessageC1)
+
+				env, err := cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Offset).To(BeNumerically("==", 4))
+			})
+
+			It("returns ErrStreamSealed at the end of a sealed stream", func() {
+				source.Seal()
+
+				cur, err := stream.Open(ctx, 3, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				_, err = cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				_, err = cur.Next(ctx)
+				Expect(err).To(Equal(ordered.ErrStreamSealed))
+			})
+
+			It("returns a *TruncatedError if the next event has been truncated", func() {
+				source.Truncate(2)
+
+				cur, err := stream.Open(ctx, 1, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				_, err = cur.Next(ctx)
+
+				var truncErr *ordered.TruncatedError
+				Expect(errors.As(err, &truncErr)).To(BeTrue())
+				Expect(truncErr.RequestedOffset).To(BeNumerically("==", 1))
+				Expect(truncErr.FirstOffset).To(BeNumerically("==", 2))
+			})
+
+			It("returns an error if the cursor is closed", func() {
+				cur, err := stream.Open(ctx, 0, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				cur.Close()
+
+				_, err = cur.Next(ctx)
+				Expect(err).To(MatchError("cursor is closed"))
+			})
+		})
+
+		Describe("func Close()", func() {
+			It("closes the cursor on the server", func() {
+				cur, err := stream.Open(ctx, 4, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				cur.Close()
+
+				Eventually(closed).Should(Receive())
+			})
+		})
+	})
+
+	It("can be consumed by a projector", func() {
+		handler := &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageB{})
+			},
+		}
+
+		var messages []dogma.Message
+		handler.HandleEventFunc = func(
+			_ context.Context,
+			_, _, _ []byte,
+			_ dogma.ProjectionEventScope,
+			m dogma.Message,
+		) (bool, error) {
+			messages = append(messages, m)
+			if len(messages) == 2 {
+				cancel()
+			}
+			return true, nil
+		}
+
+		proj := &ordered.Projector{
+			Stream:  stream,
+			Handler: handler,
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+		Expect(messages).To(Equal([]dogma.Message{MessageB1, MessageB2}))
+	})
+})
+
+// closeNotifyingStream is an ordered.Stream that signals when a cursor opened
+// on it is closed.
+type closeNotifyingStream struct {
+	ordered.Stream
+	closed chan<- struct{}
+}
+
+func (s *closeNotifyingStream) Open(
+	ctx context.Context,
+	offset uint64,
+	filter []dogma.Message,
+) (ordered.Cursor, error) {
+	cur, err := s.Stream.Open(ctx, offset, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &closeNotifyingCursor{cur, s.closed}, nil
+}
+
+type closeNotifyingCursor struct {
+	ordered.Cursor
+	closed chan<- struct{}
+}
+
+func (c *closeNotifyingCursor) Close() error {
+	c.closed <- struct{}{}
+	return c.Cursor.Close()
+}
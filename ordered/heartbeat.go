@@ -0,0 +1,437 @@
+package ordered
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dogmatiq/configkit/message"
+	"github.com/dogmatiq/dogma"
+	"github.com/dogmatiq/linger"
+)
+
+// DefaultHeartbeatInterval is the default interval at which a HeartbeatStream
+// injects heartbeat events.
+const DefaultHeartbeatInterval = 1 * time.Minute
+
+// HeartbeatStream is a Stream that wraps another stream and injects synthetic
+// "heartbeat" events when no real events have arrived for some time.
+//
+// It allows projections to perform time-based logic even when the underlying
+// stream is idle. Each time a cursor waits for longer than Interval for the
+// next event, it returns a synthetic envelope containing the Heartbeat message.
+//
+// Heartbeats are only returned to cursors that are opened with a filter that
+// includes the type of the Heartbeat message, or with an empty filter.
+//
+// Heartbeat events are not stored on the underlying stream and do not occupy
+// an offset of their own, instead their envelopes have the Synthetic field set
+// to true. A projector does not advance the projection's version when it
+// applies a synthetic event, so a restarted projector resumes from the last
+// real event it applied. Any heartbeats that were delivered before the restart
+// are never delivered again.
+//
+// HeartbeatStream implements HeadStream, BoundsStream, SealedStream and
+// TailStream by forwarding to the underlying stream. If the underlying stream
+// does not support one of these capabilities the corresponding method returns
+// an error that wraps errors.ErrUnsupported, which a Projector treats as
+// though the capability were not implemented at all.
+//
+// Its cursors implement PeekCursor and PredicateCursor. They also implement
+// NonBlockingCursor and FilterCursor if the underlying stream's cursors do.
+// TryNext() never returns a heartbeat, as it never waits for an event.
+type HeartbeatStream struct {
+	// Stream is the underlying stream.
+	Stream Stream
+
+	// Heartbeat is the message contained in each heartbeat event.
+	Heartbeat dogma.Message
+
+	// Interval is the maximum amount of time a cursor waits for a real event
+	// before returning a heartbeat. If it is non-positive,
+	// DefaultHeartbeatInterval is used.
+	Interval time.Duration
+}
+
+// ID returns a unique identifier for the stream.
+//
+// It is the same as the underlying stream's ID.
+func (s *HeartbeatStream) ID() string {
+	return s.Stream.ID()
+}
+
+// Open returns a cursor used to read events from this stream.
+//
+// offset is the position of the first real event to read. filter has the same
+// semantics as for the underlying stream, except that it may include the type
+// of the Heartbeat message.
+func (s *HeartbeatStream) Open(
+	ctx context.Context,
+	offset uint64,
+	filter []dogma.Message,
+) (Cursor, error) {
+	filter, ok := s.filter(filter)
+
+	c, err := s.Stream.Open(ctx, offset, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return newHeartbeatCursor(s, c, offset, ok), nil
+}
+
+// OpenTail returns a cursor used to read events that are appended to the
+// stream after the cursor is opened.
+//
+// It returns an error that wraps errors.ErrUnsupported if the underlying
+// stream is not a TailStream.
+func (s *HeartbeatStream) OpenTail(
+	ctx context.Context,
+	filter []dogma.Message,
+) (Cursor, uint64, error) {
+	ts, ok := s.Stream.(TailStream)
+	if !ok {
+		return nil, 0, s.unsupported("TailStream")
+	}
+
+	filter, ok = s.filter(filter)
+
+	c, offset, err := ts.OpenTail(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return newHeartbeatCursor(s, c, offset, ok), offset, nil
+}
+
+// Head returns the offset of the stream's head.
+//
+// If the underlying stream is not a HeadStream, the head is obtained from
+// Bounds() instead, in which case final is only true if the underlying
+// stream is also a SealedStream that reports that it is sealed. It returns
+// an error that wraps errors.ErrUnsupported if the underlying stream is
+// neither a HeadStream nor a BoundsStream.
+func (s *HeartbeatStream) Head(ctx context.Context) (offset uint64, final bool, err error) {
+	if hs, ok := s.Stream.(HeadStream); ok {
+		return hs.Head(ctx)
+	}
+
+	bs, ok := s.Stream.(BoundsStream)
+	if !ok {
+		return 0, false, s.unsupported("HeadStream or BoundsStream")
+	}
+
+	_, offset, err = bs.Bounds(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if ss, ok := s.Stream.(SealedStream); ok {
+		final, err = ss.IsSealed(ctx)
+	}
+
+	return offset, final, err
+}
+
+// Bounds returns the range of offsets of the events that are available on the
+// stream.
+//
+// It returns an error that wraps errors.ErrUnsupported if the underlying
+// stream is not a BoundsStream.
+func (s *HeartbeatStream) Bounds(ctx context.Context) (first, next uint64, err error) {
+	bs, ok := s.Stream.(BoundsStream)
+	if !ok {
+		return 0, 0, s.unsupported("BoundsStream")
+	}
+
+	return bs.Bounds(ctx)
+}
+
+// IsSealed returns true if the stream is sealed.
+//
+// If the underlying stream is not a SealedStream, the final flag reported by
+// Head() is used instead. It returns an error that wraps
+// errors.ErrUnsupported if the underlying stream is neither a SealedStream nor
+// a HeadStream.
+func (s *HeartbeatStream) IsSealed(ctx context.Context) (bool, error) {
+	if ss, ok := s.Stream.(SealedStream); ok {
+		return ss.IsSealed(ctx)
+	}
+
+	hs, ok := s.Stream.(HeadStream)
+	if !ok {
+		return false, s.unsupported("SealedStream or HeadStream")
+	}
+
+	_, final, err := hs.Head(ctx)
+	return final, err
+}
+
+// unsupported returns an error indicating that the underlying stream does not
+// implement the named interfaces.
+func (s *HeartbeatStream) unsupported(names string) error {
+	return fmt.Errorf(
+		"%T does not implement %s: %w",
+		s.Stream,
+		names,
+		errors.ErrUnsupported,
+	)
+}
+
+// filter returns the filter to use when opening the underlying stream, and a
+// boolean indicating whether heartbeats are included in the filter f.
+func (s *HeartbeatStream) filter(f []dogma.Message) ([]dogma.Message, bool) {
+	if len(f) == 0 {
+		return f, true
+	}
+
+	t := message.TypeOf(s.Heartbeat)
+	var filter []dogma.Message
+
+	for _, m := range f {
+		if message.TypeOf(m) != t {
+			filter = append(filter, m)
+		}
+	}
+
+	if len(filter) == len(f) {
+		return f, false
+	}
+
+	if len(filter) == 0 {
+		// The cursor only wants heartbeats, but an empty filter would match
+		// every real event.
+		return FilterNone, true
+	}
+
+	return filter, true
+}
+
+// heartbeatCursor is a Cursor that returns a heartbeat event whenever it waits
+// longer than the heartbeat interval for a real event.
+//
+// It implements PeekCursor and PredicateCursor regardless of the capabilities
+// of the underlying cursor. Use newHeartbeatCursor() to obtain a cursor that
+// also implements NonBlockingCursor and FilterCursor if the underlying cursor
+// does.
+type heartbeatCursor struct {
+	stream    *HeartbeatStream
+	cursor    Cursor
+	interval  time.Duration
+	next      uint64
+	enabled   bool
+	predicate func(Envelope) bool
+	local     bool
+	pending   *Envelope
+}
+
+// newHeartbeatCursor returns a cursor that injects heartbeats from s into the
+// events read from c, which was opened at the given offset.
+//
+// enabled is false if the cursor's filter excludes heartbeats.
+func newHeartbeatCursor(
+	s *HeartbeatStream,
+	c Cursor,
+	offset uint64,
+	enabled bool,
+) Cursor {
+	hc := &heartbeatCursor{
+		stream:   s,
+		cursor:   c,
+		interval: linger.MustCoalesce(s.Interval, DefaultHeartbeatInterval),
+		next:     offset,
+		enabled:  enabled,
+	}
+
+	nb, isNonBlocking := c.(NonBlockingCursor)
+	fc, isFilter := c.(FilterCursor)
+
+	switch {
+	case isNonBlocking && isFilter:
+		return &nonBlockingFilterHeartbeatCursor{hc, nb, fc}
+	case isNonBlocking:
+		return &nonBlockingHeartbeatCursor{hc, nb}
+	case isFilter:
+		return &filterHeartbeatCursor{hc, fc}
+	default:
+		return hc
+	}
+}
+
+// Next returns the next relevant event in the stream, or a heartbeat event if
+// no real event becomes available within the heartbeat interval.
+func (c *heartbeatCursor) Next(ctx context.Context) (Envelope, error) {
+	if env := c.pending; env != nil {
+		c.pending = nil
+		return *env, nil
+	}
+
+	for {
+		env, err := c.read(ctx)
+		if err != nil || c.matches(env) {
+			return env, err
+		}
+	}
+}
+
+// Peek returns the next relevant event in the stream without advancing the
+// cursor's position.
+//
+// If no real event becomes available within the heartbeat interval it returns
+// a heartbeat event, which is then also returned by the next call to Next().
+func (c *heartbeatCursor) Peek(ctx context.Context) (Envelope, error) {
+	if c.pending == nil {
+		env, err := c.Next(ctx)
+		if err != nil {
+			return Envelope{}, err
+		}
+		c.pending = &env
+	}
+
+	return *c.pending, nil
+}
+
+// SetPredicate sets a function that is called with each event that matches
+// the cursor's message-type filter, including heartbeat events.
+//
+// If the underlying cursor is a PredicateCursor the predicate is also set on
+// the underlying cursor, otherwise it is evaluated by this cursor.
+func (c *heartbeatCursor) SetPredicate(fn func(Envelope) bool) {
+	c.predicate = fn
+	c.local = true
+
+	if pc, ok := c.cursor.(PredicateCursor); ok {
+		pc.SetPredicate(fn)
+		c.local = false
+	}
+}
+
+// Close stops the cursor.
+func (c *heartbeatCursor) Close() error {
+	return c.cursor.Close()
+}
+
+// read returns the next event from the underlying cursor, or a heartbeat event
+// if no real event becomes available within the heartbeat interval.
+func (c *heartbeatCursor) read(ctx context.Context) (Envelope, error) {
+	if !c.enabled {
+		env, err := c.cursor.Next(ctx)
+		if err == nil {
+			c.next = env.Offset + 1
+		}
+		return env, err
+	}
+
+	hctx, cancel := context.WithTimeout(ctx, c.interval)
+	defer cancel()
+
+	env, err := c.cursor.Next(hctx)
+	if err == nil {
+		c.next = env.Offset + 1
+		return env, nil
+	}
+
+	// Only return a heartbeat if the inner cursor gave up because the
+	// heartbeat interval elapsed, so that genuine errors are not masked.
+	if ctx.Err() == nil && hctx.Err() != nil && errors.Is(err, context.DeadlineExceeded) {
+		return Envelope{
+			Offset:     c.next,
+			RecordedAt: time.Now(),
+			Message:    c.stream.Heartbeat,
+			Synthetic:  true,
+		}, nil
+	}
+
+	return Envelope{}, err
+}
+
+// tryNext is the implementation of TryNext() for cursors that wrap a
+// NonBlockingCursor.
+//
+// It never returns a heartbeat, as it never waits for an event.
+func (c *heartbeatCursor) tryNext(ctx context.Context, nb NonBlockingCursor) (Envelope, bool, error) {
+	if env := c.pending; env != nil {
+		c.pending = nil
+		return *env, true, nil
+	}
+
+	for {
+		env, ok, err := nb.TryNext(ctx)
+		if err != nil || !ok {
+			return Envelope{}, ok, err
+		}
+
+		c.next = env.Offset + 1
+
+		if c.matches(env) {
+			return env, true, nil
+		}
+	}
+}
+
+// setFilter is the implementation of SetFilter() for cursors that wrap a
+// FilterCursor.
+func (c *heartbeatCursor) setFilter(filter []dogma.Message, fc FilterCursor) {
+	filter, c.enabled = c.stream.filter(filter)
+	fc.SetFilter(filter)
+}
+
+// matches returns true if env satisfies the cursor's predicate, if it has to
+// be evaluated by this cursor.
+func (c *heartbeatCursor) matches(env Envelope) bool {
+	if c.predicate == nil {
+		return true
+	}
+
+	if c.local || env.Synthetic {
+		return c.predicate(env)
+	}
+
+	return true
+}
+
+// nonBlockingHeartbeatCursor is a heartbeatCursor that wraps a
+// NonBlockingCursor.
+type nonBlockingHeartbeatCursor struct {
+	*heartbeatCursor
+	nb NonBlockingCursor
+}
+
+// TryNext returns the next relevant event in the stream, if one is immediately
+// available.
+func (c *nonBlockingHeartbeatCursor) TryNext(ctx context.Context) (Envelope, bool, error) {
+	return c.tryNext(ctx, c.nb)
+}
+
+// filterHeartbeatCursor is a heartbeatCursor that wraps a FilterCursor.
+type filterHeartbeatCursor struct {
+	*heartbeatCursor
+	fc FilterCursor
+}
+
+// SetFilter replaces the cursor's message-type filter, which may include the
+// type of the Heartbeat message.
+func (c *filterHeartbeatCursor) SetFilter(filter []dogma.Message) {
+	c.setFilter(filter, c.fc)
+}
+
+// nonBlockingFilterHeartbeatCursor is a heartbeatCursor that wraps a cursor
+// that is both a NonBlockingCursor and a FilterCursor.
+type nonBlockingFilterHeartbeatCursor struct {
+	*heartbeatCursor
+	nb NonBlockingCursor
+	fc FilterCursor
+}
+
+// TryNext returns the next relevant event in the stream, if one is immediately
+// available.
+func (c *nonBlockingFilterHeartbeatCursor) TryNext(ctx context.Context) (Envelope, bool, error) {
+	return c.tryNext(ctx, c.nb)
+}
+
+// SetFilter replaces the cursor's message-type filter, which may include the
+// type of the Heartbeat message.
+func (c *nonBlockingFilterHeartbeatCursor) SetFilter(filter []dogma.Message) {
+	c.setFilter(filter, c.fc)
+}
@@ -0,0 +1,601 @@
+package ordered_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/dogmatiq/aperture/aperturetest"
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var (
+	_ HeadStream   = (*HeartbeatStream)(nil)
+	_ BoundsStream = (*HeartbeatStream)(nil)
+	_ SealedStream = (*HeartbeatStream)(nil)
+	_ TailStream   = (*HeartbeatStream)(nil)
+)
+
+var _ = Describe("type HeartbeatStream", func() {
+	var (
+		ctx    context.Context
+		cancel func()
+		mem    *MemoryStream
+		stream *HeartbeatStream
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+
+		mem = &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		mem.Append(
+			time.Now(),
+			MessageA1,
+			MessageB1,
+		)
+
+		stream = &HeartbeatStream{
+			Stream:    mem,
+			Heartbeat: MessageX1,
+			Interval:  10 * time.Millisecond,
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Describe("func ID()", func() {
+		It("returns the ID of the underlying stream", func() {
+			Expect(stream.ID()).To(Equal("<id>"))
+		})
+	})
+
+	Describe("func Open()", func() {
+		It("returns a heartbeat when no event arrives within the interval", func() {
+			cur, err := stream.Open(ctx, 0, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Message).To(Equal(MessageA1))
+			Expect(env.Synthetic).To(BeFalse())
+
+			env, err = cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Message).To(Equal(MessageB1))
+
+			env, err = cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Message).To(Equal(MessageX1))
+			Expect(env.Offset).To(BeEquivalentTo(2))
+			Expect(env.Synthetic).To(BeTrue())
+		})
+
+		It("returns real events that arrive after a heartbeat", func() {
+			cur, err := stream.Open(ctx, 2, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Synthetic).To(BeTrue())
+
+			mem.Append(time.Now(), MessageA2)
+
+			env, err = cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Message).To(Equal(MessageA2))
+			Expect(env.Offset).To(BeEquivalentTo(2))
+			Expect(env.Synthetic).To(BeFalse())
+		})
+
+		It("does not return heartbeats if the filter excludes them", func() {
+			cur, err := stream.Open(ctx, 2, []dogma.Message{MessageA{}})
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			defer cancel()
+
+			_, err = cur.Next(ctx)
+			Expect(err).To(Equal(context.DeadlineExceeded))
+		})
+
+		It("does not return real events if the filter only includes heartbeats", func() {
+			cur, err := stream.Open(ctx, 0, []dogma.Message{MessageX{}})
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Message).To(Equal(MessageX1))
+			Expect(env.Offset).To(BeZero())
+		})
+
+		It("applies the filter to real events", func() {
+			cur, err := stream.Open(ctx, 0, []dogma.Message{MessageB{}, MessageX{}})
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Message).To(Equal(MessageB1))
+
+			env, err = cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Message).To(Equal(MessageX1))
+			Expect(env.Offset).To(BeEquivalentTo(2))
+		})
+
+		It("returns errors from the underlying cursor that occur when the interval elapses", func() {
+			stream.Stream = &openFuncStream{
+				Stream: mem,
+				OpenFunc: func(context.Context, uint64, []dogma.Message) (Cursor, error) {
+					return &nextFuncCursor{
+						NextFunc: func(ctx context.Context) (Envelope, error) {
+							<-ctx.Done()
+							return Envelope{}, errors.New("<error>")
+						},
+					}, nil
+				},
+			}
+
+			cur, err := stream.Open(ctx, 0, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			_, err = cur.Next(ctx)
+			Expect(err).To(MatchError("<error>"))
+		})
+
+		It("returns ErrStreamSealed if the underlying stream is sealed", func() {
+			cur, err := stream.Open(ctx, 2, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			mem.Seal()
+
+			_, err = cur.Next(ctx)
+			Expect(err).To(MatchError(ErrStreamSealed))
+		})
+	})
+
+	Describe("type cursor", func() {
+		It("forwards the capabilities of the underlying cursor", func() {
+			cur, err := stream.Open(ctx, 0, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			_, ok := cur.(NonBlockingCursor)
+			Expect(ok).To(BeTrue())
+
+			_, ok = cur.(FilterCursor)
+			Expect(ok).To(BeTrue())
+
+			_, ok = cur.(PeekCursor)
+			Expect(ok).To(BeTrue())
+
+			_, ok = cur.(PredicateCursor)
+			Expect(ok).To(BeTrue())
+		})
+
+		It("does not implement NonBlockingCursor or FilterCursor if the underlying cursor does not", func() {
+			stream.Stream = &openFuncStream{
+				Stream: mem,
+				OpenFunc: func(context.Context, uint64, []dogma.Message) (Cursor, error) {
+					return &nextFuncCursor{}, nil
+				},
+			}
+
+			cur, err := stream.Open(ctx, 0, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			_, ok := cur.(NonBlockingCursor)
+			Expect(ok).To(BeFalse())
+
+			_, ok = cur.(FilterCursor)
+			Expect(ok).To(BeFalse())
+		})
+
+		Describe("func TryNext()", func() {
+			It("returns events without returning heartbeats", func() {
+				cur, err := stream.Open(ctx, 0, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				nb := cur.(NonBlockingCursor)
+
+				env, ok, err := nb.TryNext(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(ok).To(BeTrue())
+				Expect(env.Message).To(Equal(MessageA1))
+
+				env, ok, err = nb.TryNext(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(ok).To(BeTrue())
+				Expect(env.Message).To(Equal(MessageB1))
+
+				time.Sleep(2 * stream.Interval)
+
+				_, ok, err = nb.TryNext(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(ok).To(BeFalse())
+			})
+		})
+
+		Describe("func SetFilter()", func() {
+			It("enables heartbeats if the filter includes them", func() {
+				cur, err := stream.Open(ctx, 2, []dogma.Message{MessageA{}})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				cur.(FilterCursor).SetFilter([]dogma.Message{MessageA{}, MessageX{}})
+
+				env, err := cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Message).To(Equal(MessageX1))
+			})
+
+			It("disables heartbeats if the filter excludes them", func() {
+				cur, err := stream.Open(ctx, 0, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				cur.(FilterCursor).SetFilter([]dogma.Message{MessageB{}})
+
+				env, err := cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Message).To(Equal(MessageB1))
+
+				go func() {
+					time.Sleep(5 * stream.Interval)
+					mem.Append(time.Now(), MessageB2)
+				}()
+
+				env, err = cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Message).To(Equal(MessageB2))
+			})
+		})
+
+		Describe("func Peek()", func() {
+			It("returns the same heartbeat from the next call to Next()", func() {
+				cur, err := stream.Open(ctx, 2, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				peeked, err := cur.(PeekCursor).Peek(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(peeked.Message).To(Equal(MessageX1))
+
+				env, err := cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env).To(Equal(peeked))
+			})
+		})
+
+		Describe("func SetPredicate()", func() {
+			It("applies the predicate to real events and heartbeats", func() {
+				cur, err := stream.Open(ctx, 0, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				cur.(PredicateCursor).SetPredicate(func(env Envelope) bool {
+					return env.Message != MessageA1 && !env.Synthetic
+				})
+
+				env, err := cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Message).To(Equal(MessageB1))
+
+				go func() {
+					time.Sleep(5 * stream.Interval)
+					mem.Append(time.Now(), MessageA2)
+				}()
+
+				env, err = cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Message).To(Equal(MessageA2))
+			})
+
+			It("applies the predicate if the underlying cursor is not a PredicateCursor", func() {
+				stream.Stream = &ThrottledStream{Stream: mem}
+
+				cur, err := stream.Open(ctx, 0, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				cur.(PredicateCursor).SetPredicate(func(env Envelope) bool {
+					return env.Message != MessageA1
+				})
+
+				env, err := cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Message).To(Equal(MessageB1))
+			})
+		})
+	})
+
+	It("allows a projector to run until it has caught up with the underlying stream", func() {
+		var messages []dogma.Message
+
+		handler := &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageA{})
+				c.ConsumesEventType(MessageB{})
+				c.ConsumesEventType(MessageX{})
+			},
+			HandleEventFunc: func(
+				_ context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				m dogma.Message,
+			) (bool, error) {
+				messages = append(messages, m)
+				return true, nil
+			},
+		}
+
+		proj := &Projector{
+			Stream:  stream,
+			Handler: handler,
+		}
+
+		err := proj.RunUntilCaughtUp(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(messages).To(Equal([]dogma.Message{MessageA1, MessageB1}))
+	})
+
+	Describe("func OpenTail()", func() {
+		It("returns a heartbeat cursor at the head of the underlying stream", func() {
+			cur, offset, err := stream.OpenTail(ctx, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+			Expect(offset).To(BeEquivalentTo(2))
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Message).To(Equal(MessageX1))
+			Expect(env.Offset).To(BeEquivalentTo(2))
+			Expect(env.Synthetic).To(BeTrue())
+		})
+
+		It("returns an error if the underlying stream is not a TailStream", func() {
+			stream.Stream = &struct{ Stream }{mem}
+
+			_, _, err := stream.OpenTail(ctx, nil)
+			Expect(err).To(MatchError(errors.ErrUnsupported))
+		})
+	})
+
+	Describe("func Head()", func() {
+		It("returns the head of the underlying stream", func() {
+			mem.Seal()
+
+			offset, final, err := stream.Head(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(offset).To(BeEquivalentTo(2))
+			Expect(final).To(BeTrue())
+		})
+
+		It("uses the bounds of the underlying stream if it is not a HeadStream", func() {
+			stream.Stream = &struct{ BoundsStream }{mem}
+
+			offset, final, err := stream.Head(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(offset).To(BeEquivalentTo(2))
+			Expect(final).To(BeFalse())
+		})
+
+		It("returns an error if the underlying stream is neither a HeadStream nor a BoundsStream", func() {
+			stream.Stream = &struct{ Stream }{mem}
+
+			_, _, err := stream.Head(ctx)
+			Expect(err).To(MatchError(errors.ErrUnsupported))
+		})
+	})
+
+	Describe("func Bounds()", func() {
+		It("returns the bounds of the underlying stream", func() {
+			mem.Truncate(1)
+
+			first, next, err := stream.Bounds(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(first).To(BeEquivalentTo(1))
+			Expect(next).To(BeEquivalentTo(2))
+		})
+
+		It("returns an error if the underlying stream is not a BoundsStream", func() {
+			stream.Stream = &struct{ Stream }{mem}
+
+			_, _, err := stream.Bounds(ctx)
+			Expect(err).To(MatchError(errors.ErrUnsupported))
+		})
+	})
+
+	Describe("func IsSealed()", func() {
+		It("returns true if the underlying stream is sealed", func() {
+			mem.Seal()
+
+			sealed, err := stream.IsSealed(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(sealed).To(BeTrue())
+		})
+
+		It("uses the head of the underlying stream if it is not a SealedStream", func() {
+			stream.Stream = &struct{ HeadStream }{mem}
+			mem.Seal()
+
+			sealed, err := stream.IsSealed(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(sealed).To(BeTrue())
+		})
+
+		It("returns an error if the underlying stream is neither a SealedStream nor a HeadStream", func() {
+			stream.Stream = &struct{ Stream }{mem}
+
+			_, err := stream.IsSealed(ctx)
+			Expect(err).To(MatchError(errors.ErrUnsupported))
+		})
+	})
+
+	It("allows a projector to start at the tail of the underlying stream", func() {
+		handler := &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageA{})
+			},
+			HandleEventFunc: func(
+				_ context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				m dogma.Message,
+			) (bool, error) {
+				Expect(m).To(Equal(MessageA2))
+				cancel()
+				return true, nil
+			},
+		}
+
+		proj := &Projector{
+			Stream:      stream,
+			Handler:     handler,
+			StartAtTail: true,
+		}
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			mem.Append(time.Now(), MessageA2)
+		}()
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+	})
+
+	It("reports that the projector has caught up with the underlying stream", func() {
+		handler := &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageA{})
+				c.ConsumesEventType(MessageB{})
+			},
+		}
+
+		proj := &Projector{
+			Stream:  stream,
+			Handler: handler,
+			OnCaughtUp: func(offset uint64) {
+				Expect(offset).To(BeEquivalentTo(2))
+				cancel()
+			},
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+	})
+
+	It("does not check the head of an underlying stream that can not report it", func() {
+		stream.Stream = &struct{ Stream }{mem}
+
+		handler := &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageA{})
+			},
+			HandleEventFunc: func(
+				_ context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				m dogma.Message,
+			) (bool, error) {
+				Expect(m).To(Equal(MessageA1))
+				cancel()
+				return true, nil
+			},
+		}
+
+		proj := &Projector{
+			Stream:         stream,
+			Handler:        handler,
+			FailBeyondHead: true,
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+	})
+
+	It("does not advance the projection's version when a heartbeat is applied", func() {
+		var versions [][]byte
+
+		handler := &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageA{})
+				c.ConsumesEventType(MessageX{})
+			},
+			HandleEventFunc: func(
+				_ context.Context,
+				_, c, n []byte,
+				_ dogma.ProjectionEventScope,
+				m dogma.Message,
+			) (bool, error) {
+				if m == MessageX1 {
+					Expect(n).To(Equal(c))
+					cancel()
+				}
+
+				versions = append(versions, c)
+				return true, nil
+			},
+		}
+
+		proj := &Projector{
+			Stream:  stream,
+			Handler: handler,
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+		Expect(versions).To(HaveLen(2))
+		Expect(versions[1]).To(BeVersion(1))
+		Expect(proj.HandledCount()).To(BeEquivalentTo(1))
+	})
+})
+
+// openFuncStream is a Stream that opens cursors using a function.
+type openFuncStream struct {
+	Stream
+	OpenFunc func(context.Context, uint64, []dogma.Message) (Cursor, error)
+}
+
+func (s *openFuncStream) Open(
+	ctx context.Context,
+	offset uint64,
+	filter []dogma.Message,
+) (Cursor, error) {
+	return s.OpenFunc(ctx, offset, filter)
+}
+
+// nextFuncCursor is a Cursor that reads events using a function.
+type nextFuncCursor struct {
+	NextFunc func(context.Context) (Envelope, error)
+}
+
+func (c *nextFuncCursor) Next(ctx context.Context) (Envelope, error) {
+	return c.NextFunc(ctx)
+}
+
+func (c *nextFuncCursor) Close() error {
+	return nil
+}
@@ -0,0 +1,48 @@
+package ordered
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrLimitReached is returned by the Next() method of a cursor returned by
+// LimitCursor() once it has returned its limit of events.
+var ErrLimitReached = errors.New("cursor limit reached")
+
+// LimitCursor returns a cursor that returns at most n events from c.
+//
+// Once n events have been returned, Next() returns ErrLimitReached without
+// reading from c. Closing the returned cursor closes c.
+func LimitCursor(c Cursor, n uint64) Cursor {
+	return &limitCursor{
+		cursor:    c,
+		remaining: n,
+	}
+}
+
+type limitCursor struct {
+	cursor    Cursor
+	remaining uint64
+}
+
+// Next returns the next relevant event in the stream, or ErrLimitReached if
+// the limit has been reached.
+func (c *limitCursor) Next(ctx context.Context) (Envelope, error) {
+	if c.remaining == 0 {
+		return Envelope{}, ErrLimitReached
+	}
+
+	env, err := c.cursor.Next(ctx)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	c.remaining--
+
+	return env, nil
+}
+
+// Close stops the cursor.
+func (c *limitCursor) Close() error {
+	return c.cursor.Close()
+}
@@ -0,0 +1,97 @@
+package ordered_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("func LimitCursor()", func() {
+	var (
+		ctx    context.Context
+		cancel func()
+		stream *MemoryStream
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+
+		stream = &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		stream.Append(
+			time.Now(),
+			MessageA1,
+			MessageB1,
+			MessageA2,
+			MessageB2,
+		)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("returns ErrLimitReached after the given number of events", func() {
+		cur, err := stream.Open(ctx, 0, []dogma.Message{MessageA{}})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		cur = LimitCursor(cur, 1)
+		defer cur.Close()
+
+		env, err := cur.Next(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(env.Message).To(Equal(MessageA1))
+
+		_, err = cur.Next(ctx)
+		Expect(err).To(Equal(ErrLimitReached))
+	})
+
+	It("returns ErrLimitReached immediately if the limit is zero", func() {
+		cur, err := stream.Open(ctx, 0, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		cur = LimitCursor(cur, 0)
+		defer cur.Close()
+
+		_, err = cur.Next(ctx)
+		Expect(err).To(Equal(ErrLimitReached))
+	})
+
+	It("does not count errors towards the limit", func() {
+		cur, err := stream.Open(ctx, 4, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		cur = LimitCursor(cur, 1)
+		defer cur.Close()
+
+		expired, cancelExpired := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancelExpired()
+
+		_, err = cur.Next(expired)
+		Expect(err).To(Equal(context.DeadlineExceeded))
+
+		stream.Append(time.Now(), MessageA3)
+
+		env, err := cur.Next(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(env.Message).To(Equal(MessageA3))
+	})
+
+	It("closes the underlying cursor", func() {
+		cur, err := stream.Open(ctx, 0, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		err = LimitCursor(cur, 1).Close()
+		Expect(err).ShouldNot(HaveOccurred())
+
+		_, err = cur.Next(ctx)
+		Expect(err).To(MatchError("cursor is closed"))
+	})
+})
@@ -0,0 +1,44 @@
+package ordered
+
+import "fmt"
+
+// LogContext describes the context in which the projector, or the handler via
+// its scope, produces a log message.
+type LogContext struct {
+	// Handler is the name of the projection handler.
+	Handler string
+
+	// Resource is the resource that the handler is consuming.
+	Resource []byte
+
+	// Offset is the offset of the event being handled. It is zero if Compact
+	// is true.
+	Offset uint64
+
+	// Compact is true if the message is logged during compaction of the
+	// projection, rather than while handling an event.
+	Compact bool
+}
+
+// DefaultLogFormat is the default function used to produce the prefix of log
+// messages.
+//
+// It produces "[<handler> <resource>@<offset>]" while handling events, and
+// "[<handler> compact]" while compacting.
+func DefaultLogFormat(c LogContext) string {
+	if c.Compact {
+		return fmt.Sprintf("[%s compact]", c.Handler)
+	}
+
+	return fmt.Sprintf("[%s %s@%d]", c.Handler, c.Resource, c.Offset)
+}
+
+// logPrefix returns the prefix to use for a log message produced within the
+// context c, using format if it is non-nil.
+func logPrefix(format func(LogContext) string, c LogContext) string {
+	if format == nil {
+		format = DefaultLogFormat
+	}
+
+	return format(c)
+}
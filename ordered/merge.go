@@ -0,0 +1,398 @@
+package ordered
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dogmatiq/configkit/message"
+	"github.com/dogmatiq/dogma"
+)
+
+// MergedStream is an implementation of Stream that merges the events of
+// several underlying streams into a single stream.
+//
+// Events are ordered by the time at which they were recorded. Events recorded
+// at the same time are ordered by the position of their stream within
+// Streams, then by their offset within that stream.
+//
+// Each event is assigned a new offset that is its position within the merged
+// stream. Its MessageID is that of the event on its underlying stream. Opening
+// a cursor at a non-zero offset reads each underlying stream from the start,
+// discarding events until the offset is reached.
+//
+// The merged order is deterministic, and hence so are the offsets, provided
+// that events are appended to each underlying stream in the order in which
+// they are recorded, and that any stream that has no pending event when an
+// event is returned does not later receive an event that was recorded earlier.
+// Before each event is returned, a cursor checks every underlying stream for a
+// pending event. This check is definitive only for streams whose cursors
+// implement NonBlockingCursor.
+//
+// A merged stream is sealed once all of its underlying streams are sealed.
+type MergedStream struct {
+	// StreamID is a unique identifier for the stream, it must not be empty.
+	StreamID string
+
+	// Streams is the set of streams to merge. It must not be empty.
+	Streams []Stream
+}
+
+// ID returns a unique identifier for the stream.
+//
+// The tuple of stream ID and event offset must uniquely identify a message.
+func (s *MergedStream) ID() string {
+	if s.StreamID == "" {
+		panic("stream ID must not be empty")
+	}
+
+	return s.StreamID
+}
+
+// Open returns a cursor used to read events from this stream.
+//
+// offset is the position of the first event to read. The first event on a
+// stream is always at offset 0.
+//
+// If the offset is beyond the end of a sealed stream, the cursor's Next()
+// method returns ErrStreamSealed.
+//
+// filter is a set of zero-value event messages, the types of which indicate
+// which event types are returned by Cursor.Next(). If filter is empty, all
+// events types are returned. The underlying streams are always opened without
+// a filter, as every event must be read in order to number them.
+func (s *MergedStream) Open(
+	ctx context.Context,
+	offset uint64,
+	filter []dogma.Message,
+) (Cursor, error) {
+	if len(s.Streams) == 0 {
+		return nil, errors.New("merged stream has no underlying streams")
+	}
+
+	c := &mergedCursor{
+		skip:    offset,
+		sources: make([]*mergeSource, len(s.Streams)),
+	}
+
+	if len(filter) > 0 {
+		c.filter = message.TypesOf(filter...)
+	}
+
+	for i, us := range s.Streams {
+		cur, err := us.Open(ctx, 0, nil)
+		if errors.Is(err, ErrStreamSealed) {
+			// The underlying stream is sealed and empty.
+			c.sources[i] = &mergeSource{sealed: true}
+			continue
+		}
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("unable to open the '%s' stream: %w", us.ID(), err)
+		}
+
+		nb, ok := cur.(NonBlockingCursor)
+		if !ok {
+			nb = pumpCursor(cur)
+		}
+
+		c.sources[i] = &mergeSource{cursor: nb}
+	}
+
+	return c, nil
+}
+
+// mergedCursor is an implementation of Cursor that reads events from a
+// MergedStream.
+type mergedCursor struct {
+	m       sync.Mutex
+	sources []*mergeSource
+	filter  message.TypeCollection
+	skip    uint64
+	offset  uint64
+	closed  atomic.Bool
+}
+
+// mergeSource is the state of a single underlying stream within a
+// mergedCursor.
+type mergeSource struct {
+	cursor NonBlockingCursor
+	head   *Envelope
+	sealed bool
+}
+
+// Next returns the next relevant event in the stream.
+//
+// If the end of the stream is reached it blocks until a relevant event is
+// appended to one of the underlying streams, ctx is canceled or every
+// underlying stream is sealed. If the stream is sealed, ErrStreamSealed is
+// returned.
+func (c *mergedCursor) Next(ctx context.Context) (Envelope, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.closed.Load() {
+		return Envelope{}, errors.New("cursor is closed")
+	}
+
+	for {
+		if err := c.poll(ctx); err != nil {
+			return Envelope{}, err
+		}
+
+		src := c.earliest()
+
+		if src == nil {
+			if c.sealed() {
+				return Envelope{}, ErrStreamSealed
+			}
+
+			if err := c.wait(ctx); err != nil {
+				return Envelope{}, err
+			}
+
+			continue
+		}
+
+		env := *src.head
+		src.head = nil
+
+		env.Offset = c.offset
+		c.offset++
+
+		if env.Offset < c.skip {
+			continue
+		}
+
+		if c.filter != nil && !c.filter.HasM(env.Message) {
+			continue
+		}
+
+		return env, nil
+	}
+}
+
+// Close stops the cursor.
+//
+// Any current or future calls to Next() return a non-nil error.
+func (c *mergedCursor) Close() error {
+	c.closed.Store(true)
+
+	var err error
+	for _, src := range c.sources {
+		if src != nil && src.cursor != nil {
+			err = errors.Join(err, src.cursor.Close())
+		}
+	}
+
+	return err
+}
+
+// poll reads the next event from each underlying stream that does not already
+// have a pending event, without blocking.
+func (c *mergedCursor) poll(ctx context.Context) error {
+	for _, src := range c.sources {
+		if src.head != nil || src.sealed {
+			continue
+		}
+
+		env, ok, err := src.cursor.TryNext(ctx)
+		if err := src.update(env, ok, err); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// wait blocks until at least one of the underlying streams has a pending
+// event or is sealed.
+func (c *mergedCursor) wait(ctx context.Context) error {
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		src *mergeSource
+		env Envelope
+		err error
+	}
+
+	var pending []*mergeSource
+	for _, src := range c.sources {
+		if !src.sealed {
+			pending = append(pending, src)
+		}
+	}
+
+	results := make(chan result, len(pending))
+
+	for _, src := range pending {
+		go func(src *mergeSource) {
+			env, err := src.cursor.Next(wctx)
+			results <- result{src, env, err}
+		}(src)
+	}
+
+	var err error
+
+	for range pending {
+		r := <-results
+
+		// Once any stream has made progress the remaining reads are
+		// abandoned. A cursor that is interrupted by the cancelation does not
+		// advance, so no events are lost.
+		cancel()
+
+		if errors.Is(r.err, context.Canceled) || errors.Is(r.err, context.DeadlineExceeded) {
+			continue
+		}
+
+		if e := r.src.update(r.env, r.err == nil, r.err); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Any events that were read before ctx was canceled are retained as
+	// pending events for the next call to Next().
+	return ctx.Err()
+}
+
+// earliest returns the source with the earliest pending event, or nil if no
+// source has a pending event.
+func (c *mergedCursor) earliest() *mergeSource {
+	var earliest *mergeSource
+
+	for _, src := range c.sources {
+		if src.head == nil {
+			continue
+		}
+
+		// Sources are visited in order, so only a strictly earlier event
+		// displaces the current choice. This breaks ties by the position of
+		// the stream within MergedStream.Streams.
+		if earliest == nil || src.head.RecordedAt.Before(earliest.head.RecordedAt) {
+			earliest = src
+		}
+	}
+
+	return earliest
+}
+
+// sealed returns true if every underlying stream is sealed.
+func (c *mergedCursor) sealed() bool {
+	for _, src := range c.sources {
+		if !src.sealed {
+			return false
+		}
+	}
+
+	return true
+}
+
+// update records the result of reading from the source's cursor.
+func (s *mergeSource) update(env Envelope, ok bool, err error) error {
+	if errors.Is(err, ErrStreamSealed) {
+		s.sealed = true
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		s.head = &env
+	}
+
+	return nil
+}
+
+// pumpCursor returns a NonBlockingCursor that reads events from cur in a
+// separate goroutine.
+//
+// It is used for underlying cursors that do not natively support
+// non-blocking reads.
+func pumpCursor(cur Cursor) NonBlockingCursor {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := &pump{
+		cursor:  cur,
+		cancel:  cancel,
+		results: make(chan pumpResult, 1),
+	}
+
+	go c.run(ctx)
+
+	return c
+}
+
+// pump is an implementation of NonBlockingCursor that reads events from an
+// underlying cursor in a separate goroutine.
+type pump struct {
+	cursor  Cursor
+	cancel  context.CancelFunc
+	results chan pumpResult
+	err     error
+}
+
+type pumpResult struct {
+	env Envelope
+	err error
+}
+
+func (c *pump) run(ctx context.Context) {
+	for {
+		env, err := c.cursor.Next(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case c.results <- pumpResult{env, err}:
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (c *pump) Next(ctx context.Context) (Envelope, error) {
+	if c.err != nil {
+		return Envelope{}, c.err
+	}
+
+	select {
+	case <-ctx.Done():
+		return Envelope{}, ctx.Err()
+	case r := <-c.results:
+		c.err = r.err
+		return r.env, r.err
+	}
+}
+
+func (c *pump) TryNext(ctx context.Context) (Envelope, bool, error) {
+	if c.err != nil {
+		return Envelope{}, false, c.err
+	}
+
+	select {
+	case <-ctx.Done():
+		return Envelope{}, false, ctx.Err()
+	case r := <-c.results:
+		c.err = r.err
+		return r.env, r.err == nil, r.err
+	default:
+		return Envelope{}, false, nil
+	}
+}
+
+func (c *pump) Close() error {
+	c.cancel()
+	return c.cursor.Close()
+}
@@ -0,0 +1,223 @@
+package ordered_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type MergedStream", func() {
+	var (
+		ctx          context.Context
+		cancel       func()
+		t0           time.Time
+		streamA      *MemoryStream
+		streamB      *MemoryStream
+		stream       *MergedStream
+		readMessages func(Cursor, int) []dogma.Message
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+
+		t0 = time.Now()
+
+		streamA = &MemoryStream{StreamID: "<stream-a>"}
+		streamA.Append(t0, MessageA1)
+		streamA.Append(t0.Add(2*time.Second), MessageA2)
+
+		streamB = &MemoryStream{StreamID: "<stream-b>"}
+		streamB.Append(t0.Add(1*time.Second), MessageB1)
+		streamB.Append(t0.Add(2*time.Second), MessageB2)
+
+		stream = &MergedStream{
+			StreamID: "<merged>",
+			Streams:  []Stream{streamA, streamB},
+		}
+
+		readMessages = func(cur Cursor, n int) []dogma.Message {
+			var messages []dogma.Message
+			for len(messages) < n {
+				env, err := cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				messages = append(messages, env.Message)
+			}
+			return messages
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Describe("func ID()", func() {
+		It("returns the stream ID", func() {
+			Expect(stream.ID()).To(Equal("<merged>"))
+		})
+	})
+
+	Describe("func Open()", func() {
+		It("returns an error if there are no underlying streams", func() {
+			stream.Streams = nil
+
+			_, err := stream.Open(ctx, 0, nil)
+			Expect(err).To(MatchError("merged stream has no underlying streams"))
+		})
+	})
+
+	Describe("type mergedCursor", func() {
+		It("returns events in the order they were recorded, breaking ties by stream position", func() {
+			cur, err := stream.Open(ctx, 0, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env).To(Equal(Envelope{
+				Offset:     0,
+				RecordedAt: t0,
+				Message:    MessageA1,
+				MessageID:  "<stream-a>@0",
+			}))
+
+			Expect(readMessages(cur, 3)).To(Equal(
+				[]dogma.Message{MessageB1, MessageA2, MessageB2},
+			))
+		})
+
+		It("assigns offsets within the merged stream", func() {
+			cur, err := stream.Open(ctx, 2, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeNumerically("==", 2))
+			Expect(env.Message).To(Equal(MessageA2))
+			Expect(env.MessageID).To(Equal("<stream-a>@1"))
+		})
+
+		It("applies the filter without affecting offsets", func() {
+			cur, err := stream.Open(ctx, 0, []dogma.Message{MessageB{}})
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeNumerically("==", 1))
+			Expect(env.Message).To(Equal(MessageB1))
+
+			env, err = cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeNumerically("==", 3))
+			Expect(env.Message).To(Equal(MessageB2))
+		})
+
+		It("blocks until an event is appended to any underlying stream", func() {
+			cur, err := stream.Open(ctx, 4, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			go func() {
+				time.Sleep(20 * time.Millisecond)
+				streamB.Append(t0.Add(3*time.Second), MessageB3)
+			}()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeNumerically("==", 4))
+			Expect(env.Message).To(Equal(MessageB3))
+		})
+
+		It("does not lose events when ctx is canceled while waiting", func() {
+			cur, err := stream.Open(ctx, 4, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			wctx, wcancel := context.WithTimeout(ctx, 20*time.Millisecond)
+			defer wcancel()
+
+			_, err = cur.Next(wctx)
+			Expect(err).To(Equal(context.DeadlineExceeded))
+
+			streamA.Append(t0.Add(3*time.Second), MessageA3)
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeNumerically("==", 4))
+			Expect(env.Message).To(Equal(MessageA3))
+		})
+
+		It("returns ErrStreamSealed once every underlying stream is sealed", func() {
+			streamA.Seal()
+
+			cur, err := stream.Open(ctx, 4, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			go func() {
+				time.Sleep(20 * time.Millisecond)
+				streamB.Seal()
+			}()
+
+			_, err = cur.Next(ctx)
+			Expect(err).To(Equal(ErrStreamSealed))
+		})
+
+		It("supports underlying cursors that do not implement NonBlockingCursor", func() {
+			stream.Streams[1] = &blockingStream{streamB}
+
+			cur, err := stream.Open(ctx, 0, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			streamA.Seal()
+			streamB.Seal()
+
+			// Only the stream with a non-blocking cursor is checked
+			// definitively, so the order is not guaranteed.
+			Expect(readMessages(cur, 4)).To(ConsistOf(
+				MessageA1, MessageB1, MessageA2, MessageB2,
+			))
+
+			_, err = cur.Next(ctx)
+			Expect(err).To(Equal(ErrStreamSealed))
+		})
+
+		It("returns an error if the cursor is closed", func() {
+			cur, err := stream.Open(ctx, 0, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			cur.Close()
+
+			_, err = cur.Next(ctx)
+			Expect(err).To(MatchError("cursor is closed"))
+		})
+	})
+})
+
+// blockingStream is a Stream whose cursors do not implement
+// NonBlockingCursor.
+type blockingStream struct {
+	*MemoryStream
+}
+
+func (s *blockingStream) Open(ctx context.Context, offset uint64, filter []dogma.Message) (Cursor, error) {
+	cur, err := s.MemoryStream.Open(ctx, offset, filter)
+	if err != nil {
+		return nil, err
+	}
+	return blockingCursor{cur}, nil
+}
+
+type blockingCursor struct {
+	cur Cursor
+}
+
+func (c blockingCursor) Next(ctx context.Context) (Envelope, error) { return c.cur.Next(ctx) }
+func (c blockingCursor) Close() error                               { return c.cur.Close() }
@@ -0,0 +1,313 @@
+package ordered
+
+import (
+	"context"
+	"time"
+
+	"github.com/dogmatiq/aperture/internal/tracing"
+	"github.com/dogmatiq/linger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ProjectorMetrics is a set of OpenTelemetry instruments used to record
+// metrics about the operation of a projector.
+//
+// Any of the instruments may be nil, in which case the associated measurement
+// is not recorded.
+type ProjectorMetrics struct {
+	// Attributes is a set of attributes added to every measurement.
+	Attributes attribute.Set
+
+	// CursorOpenCount is incremented each time the projector opens a cursor on
+	// the stream.
+	CursorOpenCount metric.Int64Counter
+
+	// CursorCloseCount is incremented each time the projector closes a cursor.
+	CursorCloseCount metric.Int64Counter
+
+	// ResumeOffset is set to the offset at which the projector resumes
+	// consuming each time it opens a cursor.
+	ResumeOffset metric.Int64Gauge
+
+	// EventCount is incremented for each event delivered to the handler. The
+	// outcome is recorded using the "status" attribute, which is one of
+	// "handled", "conflict", "error" or "skipped".
+	EventCount metric.Int64Counter
+
+	// ErrorCount is incremented each time an error occurs while consuming
+	// events, including errors returned by the handler and by the stream's
+	// cursor. Errors caused by the cancelation of a context, and reaching the
+	// end of a sealed stream, are not counted.
+	//
+	// Events that are handled successfully are counted by EventCount.
+	ErrorCount metric.Int64Counter
+
+	// Lag is set to the difference between the current time and the time at
+	// which each event was recorded, in seconds, once the handler has applied
+	// the event. For a batch it is recorded for the last event in the batch.
+	//
+	// While the projector is catching up, such as when a new projection is
+	// built from the start of the stream, the lag reflects the age of the
+	// historical events being applied and may be very large. Alerts based on
+	// the lag should take this into account, for example by ignoring it
+	// until CaughtUpOffset() reports that the projector has caught up.
+	Lag metric.Float64Gauge
+
+	// CompactionCount is incremented each time the handler's Compact() method
+	// returns, regardless of whether the compaction succeeded.
+	CompactionCount metric.Int64Counter
+
+	// CompactionTimeoutCount is incremented each time the compaction timeout
+	// elapses before the handler's Compact() method returns.
+	//
+	// A projection that consistently times out while compacting may never be
+	// fully compacted.
+	CompactionTimeoutCount metric.Int64Counter
+
+	// CompactionTime records the time taken by each call to the handler's
+	// Compact() method, in seconds.
+	CompactionTime metric.Float64Histogram
+
+	// Info is set to 1 when the projector starts running. It describes the
+	// projector's configuration using attributes for the handler's name and
+	// key, the stream ID, and the effective timeouts.
+	//
+	// It is intended to be exported as an "info" metric, such that dashboards
+	// can join other metrics against the projector's configuration.
+	Info metric.Int64Gauge
+}
+
+const (
+	// statusKey is the attribute key for the outcome of handling an event.
+	statusKey = attribute.Key("status")
+
+	statusHandled  = "handled"
+	statusConflict = "conflict"
+	statusError    = "error"
+	statusSkipped  = "skipped"
+
+	// Attribute keys for the configuration recorded by the info metric.
+	defaultTimeoutKey     = attribute.Key("aperture.projector.default_timeout")
+	compactionIntervalKey = attribute.Key("aperture.projector.compaction_interval")
+	compactionTimeoutKey  = attribute.Key("aperture.projector.compaction_timeout")
+)
+
+// NewProjectorMetrics returns a set of instruments created using meter.
+//
+// Every instrument is created, using names prefixed with "aperture.projector".
+// attrs are added to every measurement.
+func NewProjectorMetrics(
+	meter metric.Meter,
+	attrs ...attribute.KeyValue,
+) (*ProjectorMetrics, error) {
+	m := &ProjectorMetrics{
+		Attributes: attribute.NewSet(attrs...),
+	}
+
+	var err error
+
+	m.CursorOpenCount, err = meter.Int64Counter(
+		"aperture.projector.cursor.opens",
+		metric.WithDescription("The number of times the projector has opened a cursor on the stream."),
+		metric.WithUnit("{cursor}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.CursorCloseCount, err = meter.Int64Counter(
+		"aperture.projector.cursor.closes",
+		metric.WithDescription("The number of times the projector has closed a cursor."),
+		metric.WithUnit("{cursor}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.ResumeOffset, err = meter.Int64Gauge(
+		"aperture.projector.resume_offset",
+		metric.WithDescription("The offset at which the projector most recently resumed consuming from the stream."),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.EventCount, err = meter.Int64Counter(
+		"aperture.projector.events",
+		metric.WithDescription("The number of events delivered to the handler, by outcome."),
+		metric.WithUnit("{event}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.ErrorCount, err = meter.Int64Counter(
+		"aperture.projector.errors",
+		metric.WithDescription("The number of errors that have occurred while consuming events."),
+		metric.WithUnit("{error}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Lag, err = meter.Float64Gauge(
+		"aperture.projector.lag",
+		metric.WithDescription("The time between an event being recorded and it being applied to the projection."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.CompactionCount, err = meter.Int64Counter(
+		"aperture.projector.compactions",
+		metric.WithDescription("The number of times the projection has been compacted."),
+		metric.WithUnit("{compaction}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.CompactionTimeoutCount, err = meter.Int64Counter(
+		"aperture.projector.compaction.timeouts",
+		metric.WithDescription("The number of times compaction of the projection has timed out."),
+		metric.WithUnit("{compaction}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.CompactionTime, err = meter.Float64Histogram(
+		"aperture.projector.compaction.duration",
+		metric.WithDescription("The time taken to compact the projection."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Info, err = meter.Int64Gauge(
+		"aperture.projector.info",
+		metric.WithDescription("Information about the projector's configuration."),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// cursorOpened records that the projector has opened a cursor.
+func (m *ProjectorMetrics) cursorOpened(ctx context.Context) {
+	if m != nil {
+		m.add(ctx, m.CursorOpenCount, 1)
+	}
+}
+
+// cursorClosed records that the projector has closed a cursor.
+func (m *ProjectorMetrics) cursorClosed(ctx context.Context) {
+	if m != nil {
+		m.add(ctx, m.CursorCloseCount, 1)
+	}
+}
+
+// resumed records that the projector is resuming consumption at the given
+// offset.
+func (m *ProjectorMetrics) resumed(ctx context.Context, offset uint64) {
+	if m != nil && m.ResumeOffset != nil {
+		m.ResumeOffset.Record(ctx, int64(offset), metric.WithAttributeSet(m.Attributes))
+	}
+}
+
+// handled records the outcome of delivering n events to the handler.
+func (m *ProjectorMetrics) handled(ctx context.Context, status string, n int) {
+	if m != nil && m.EventCount != nil {
+		m.EventCount.Add(
+			ctx,
+			int64(n),
+			metric.WithAttributeSet(m.Attributes),
+			metric.WithAttributes(statusKey.String(status)),
+		)
+	}
+}
+
+// lagged records the lag between the current time and the time at which a
+// handled event was recorded.
+func (m *ProjectorMetrics) lagged(ctx context.Context, recordedAt time.Time) {
+	if m != nil && m.Lag != nil {
+		m.Lag.Record(
+			ctx,
+			time.Since(recordedAt).Seconds(),
+			metric.WithAttributeSet(m.Attributes),
+		)
+	}
+}
+
+// failed records that an error occurred while consuming events.
+func (m *ProjectorMetrics) failed(ctx context.Context) {
+	if m != nil {
+		m.add(ctx, m.ErrorCount, 1)
+	}
+}
+
+// compacted records that the handler's Compact() method returned after d,
+// and whether the compaction timed out.
+func (m *ProjectorMetrics) compacted(ctx context.Context, d time.Duration, timedOut bool) {
+	if m == nil {
+		return
+	}
+
+	m.add(ctx, m.CompactionCount, 1)
+
+	if timedOut {
+		m.add(ctx, m.CompactionTimeoutCount, 1)
+	}
+
+	if m.CompactionTime != nil {
+		m.CompactionTime.Record(ctx, d.Seconds(), metric.WithAttributeSet(m.Attributes))
+	}
+}
+
+// started records the projector's info metric with the given attributes.
+func (m *ProjectorMetrics) started(ctx context.Context, attrs ...attribute.KeyValue) {
+	if m != nil && m.Info != nil {
+		m.Info.Record(
+			ctx,
+			1,
+			metric.WithAttributeSet(m.Attributes),
+			metric.WithAttributes(attrs...),
+		)
+	}
+}
+
+// add adds n to the counter c, if it is non-nil.
+func (m *ProjectorMetrics) add(ctx context.Context, c metric.Int64Counter, n int64) {
+	if c != nil {
+		c.Add(ctx, n, metric.WithAttributeSet(m.Attributes))
+	}
+}
+
+// info returns the attributes that describe the projector's configuration.
+func (p *Projector) info() []attribute.KeyValue {
+	compactionInterval := "none"
+	if d := p.compactionInterval(); d >= 0 {
+		compactionInterval = linger.MustCoalesce(d, DefaultCompactionInterval).String()
+	}
+
+	compactionTimeout := "none"
+	if t := p.compactionTimeout(); t >= 0 {
+		compactionTimeout = linger.MustCoalesce(t, DefaultCompactionTimeout).String()
+	}
+
+	return []attribute.KeyValue{
+		tracing.HandlerName.String(p.name),
+		tracing.HandlerKey.String(p.key),
+		tracing.StreamID.String(p.Stream.ID()),
+		defaultTimeoutKey.String(
+			linger.MustCoalesce(p.defaultTimeout(), DefaultTimeout).String(),
+		),
+		compactionIntervalKey.String(compactionInterval),
+		compactionTimeoutKey.String(compactionTimeout),
+	}
+}
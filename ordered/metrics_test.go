@@ -0,0 +1,554 @@
+package ordered_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// collectSum returns the value of the sum metric with the given name, and the
+// attributes of its only data point.
+func collectSum(reader sdkmetric.Reader, name string) (int64, attribute.Set) {
+	var rm metricdata.ResourceMetrics
+	err := reader.Collect(context.Background(), &rm)
+	Expect(err).ShouldNot(HaveOccurred())
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+
+			sum := m.Data.(metricdata.Sum[int64])
+			Expect(sum.DataPoints).To(HaveLen(1))
+			return sum.DataPoints[0].Value, sum.DataPoints[0].Attributes
+		}
+	}
+
+	return 0, attribute.Set{}
+}
+
+// collectStatuses returns the values of the sum metric with the given name,
+// keyed by the value of each data point's "status" attribute.
+func collectStatuses(reader sdkmetric.Reader, name string) map[string]int64 {
+	var rm metricdata.ResourceMetrics
+	err := reader.Collect(context.Background(), &rm)
+	Expect(err).ShouldNot(HaveOccurred())
+
+	values := map[string]int64{}
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				status, _ := dp.Attributes.Value("status")
+				values[status.AsString()] = dp.Value
+			}
+		}
+	}
+
+	return values
+}
+
+// collectGauge returns the value of the gauge metric with the given name.
+func collectGauge(reader sdkmetric.Reader, name string) int64 {
+	v, _ := collectGaugeWithAttributes(reader, name)
+	return v
+}
+
+// collectGaugeWithAttributes returns the value of the gauge metric with the
+// given name, and the attributes of its only data point.
+func collectGaugeWithAttributes(reader sdkmetric.Reader, name string) (int64, attribute.Set) {
+	var rm metricdata.ResourceMetrics
+	err := reader.Collect(context.Background(), &rm)
+	Expect(err).ShouldNot(HaveOccurred())
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+
+			gauge := m.Data.(metricdata.Gauge[int64])
+			Expect(gauge.DataPoints).To(HaveLen(1))
+			return gauge.DataPoints[0].Value, gauge.DataPoints[0].Attributes
+		}
+	}
+
+	return 0, attribute.Set{}
+}
+
+// collectFloatGauge returns the value of the float gauge metric with the given
+// name.
+func collectFloatGauge(reader sdkmetric.Reader, name string) float64 {
+	var rm metricdata.ResourceMetrics
+	err := reader.Collect(context.Background(), &rm)
+	Expect(err).ShouldNot(HaveOccurred())
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+
+			gauge := m.Data.(metricdata.Gauge[float64])
+			Expect(gauge.DataPoints).To(HaveLen(1))
+			return gauge.DataPoints[0].Value
+		}
+	}
+
+	return 0
+}
+
+// collectHistogramCount returns the number of measurements recorded by the
+// histogram metric with the given name.
+func collectHistogramCount(reader sdkmetric.Reader, name string) uint64 {
+	var rm metricdata.ResourceMetrics
+	err := reader.Collect(context.Background(), &rm)
+	Expect(err).ShouldNot(HaveOccurred())
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+
+			hist := m.Data.(metricdata.Histogram[float64])
+			Expect(hist.DataPoints).To(HaveLen(1))
+			return hist.DataPoints[0].Count
+		}
+	}
+
+	return 0
+}
+
+var _ = Describe("type ProjectorMetrics", func() {
+	var (
+		ctx     context.Context
+		cancel  func()
+		reader  *sdkmetric.ManualReader
+		stream  *MemoryStream
+		handler *ProjectionMessageHandler
+		proj    *Projector
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), DefaultTimeout*2)
+
+		reader = sdkmetric.NewManualReader()
+		meter := sdkmetric.NewMeterProvider(
+			sdkmetric.WithReader(reader),
+		).Meter("<meter>")
+
+		opened, err := meter.Int64Counter("cursor.open")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		closed, err := meter.Int64Counter("cursor.close")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		resumed, err := meter.Int64Gauge("resume.offset")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		events, err := meter.Int64Counter("events")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		errs, err := meter.Int64Counter("errors")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		lag, err := meter.Float64Gauge("lag")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		info, err := meter.Int64Gauge("projector.info")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		compactions, err := meter.Int64Counter("compactions")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		compactionTimeouts, err := meter.Int64Counter("compaction.timeouts")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		compactionTime, err := meter.Float64Histogram("compaction.duration")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		stream = &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		stream.Append(
+			time.Now(),
+			MessageA1,
+			MessageA2,
+		)
+
+		handler = &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageA{})
+			},
+		}
+
+		proj = &Projector{
+			Stream:  stream,
+			Handler: handler,
+			Metrics: &ProjectorMetrics{
+				Attributes:             attribute.NewSet(attribute.String("projection", "<proj>")),
+				CursorOpenCount:        opened,
+				CursorCloseCount:       closed,
+				ResumeOffset:           resumed,
+				EventCount:             events,
+				ErrorCount:             errs,
+				Lag:                    lag,
+				CompactionCount:        compactions,
+				CompactionTimeoutCount: compactionTimeouts,
+				CompactionTime:         compactionTime,
+				Info:                   info,
+			},
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("counts the cursors that are opened and closed", func() {
+		calls := 0
+		handler.HandleEventFunc = func(
+			context.Context,
+			[]byte, []byte, []byte,
+			dogma.ProjectionEventScope,
+			dogma.Message,
+		) (bool, error) {
+			calls++
+			if calls == 2 {
+				cancel()
+			}
+
+			// Fail the first event due to an OCC conflict, causing the cursor
+			// to be re-opened.
+			return calls > 1, nil
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+
+		opened, attrs := collectSum(reader, "cursor.open")
+		Expect(opened).To(BeNumerically("==", 2))
+		Expect(attrs.Equals(&proj.Metrics.Attributes)).To(BeTrue())
+
+		closed, _ := collectSum(reader, "cursor.close")
+		Expect(closed).To(BeNumerically("==", 2))
+	})
+
+	It("records the offset at which the projector resumes consuming", func() {
+		handler.ResourceVersionFunc = func(context.Context, []byte) ([]byte, error) {
+			return []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, nil
+		}
+
+		handler.HandleEventFunc = func(
+			context.Context,
+			[]byte, []byte, []byte,
+			dogma.ProjectionEventScope,
+			dogma.Message,
+		) (bool, error) {
+			cancel()
+			return true, nil
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+		Expect(collectGauge(reader, "resume.offset")).To(BeNumerically("==", 1))
+	})
+
+	It("counts the events by outcome", func() {
+		stream.Append(
+			time.Now(),
+			MessageA3,
+		)
+
+		calls := 0
+		handler.HandleEventFunc = func(
+			context.Context,
+			[]byte, []byte, []byte,
+			dogma.ProjectionEventScope,
+			dogma.Message,
+		) (bool, error) {
+			calls++
+			switch calls {
+			case 1:
+				return false, nil
+			case 3:
+				return false, errors.New("<error>")
+			case 4:
+				cancel()
+			}
+			return true, nil
+		}
+
+		proj.OnHandlerError = func(Envelope, error) ErrorAction {
+			return SkipEvent
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+		Expect(collectStatuses(reader, "events")).To(Equal(
+			map[string]int64{
+				"conflict": 1,
+				"skipped":  1,
+				"handled":  2,
+			},
+		))
+	})
+
+	It("counts the events that fail", func() {
+		handler.HandleEventFunc = func(
+			context.Context,
+			[]byte, []byte, []byte,
+			dogma.ProjectionEventScope,
+			dogma.Message,
+		) (bool, error) {
+			return false, errors.New("<error>")
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).Should(HaveOccurred())
+		Expect(collectStatuses(reader, "events")).To(Equal(
+			map[string]int64{
+				"error": 1,
+			},
+		))
+	})
+
+	It("counts the errors that occur while consuming", func() {
+		handler.HandleEventFunc = func(
+			context.Context,
+			[]byte, []byte, []byte,
+			dogma.ProjectionEventScope,
+			dogma.Message,
+		) (bool, error) {
+			return false, errors.New("<error>")
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).Should(HaveOccurred())
+
+		count, attrs := collectSum(reader, "errors")
+		Expect(count).To(BeNumerically("==", 1))
+		Expect(attrs).To(Equal(
+			attribute.NewSet(attribute.String("projection", "<proj>")),
+		))
+	})
+
+	It("does not count errors caused by context cancelation", func() {
+		handler.HandleEventFunc = func(
+			context.Context,
+			[]byte, []byte, []byte,
+			dogma.ProjectionEventScope,
+			dogma.Message,
+		) (bool, error) {
+			cancel()
+			return true, nil
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+
+		count, _ := collectSum(reader, "errors")
+		Expect(count).To(BeZero())
+	})
+
+	It("does not count the projector catching up as an error", func() {
+		err := proj.RunUntilCaughtUp(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		count, _ := collectSum(reader, "errors")
+		Expect(count).To(BeZero())
+	})
+
+	It("records the lag between recording and handling each event", func() {
+		stream.AppendEnvelopes(
+			Envelope{
+				RecordedAt: time.Now().Add(-10 * time.Second),
+				Message:    MessageA3,
+			},
+		)
+
+		handler.HandleEventFunc = func(
+			_ context.Context,
+			_, _, _ []byte,
+			_ dogma.ProjectionEventScope,
+			m dogma.Message,
+		) (bool, error) {
+			if m == MessageA3 {
+				cancel()
+			}
+			return true, nil
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+
+		lag := collectFloatGauge(reader, "lag")
+		Expect(lag).To(BeNumerically("~", 10, 1))
+	})
+
+	It("counts compactions and records their duration", func() {
+		handler.CompactFunc = func(context.Context, dogma.ProjectionCompactScope) error {
+			cancel()
+			return nil
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+
+		compactions, _ := collectSum(reader, "compactions")
+		Expect(compactions).To(BeNumerically("==", 1))
+
+		timeouts, _ := collectSum(reader, "compaction.timeouts")
+		Expect(timeouts).To(BeZero())
+
+		Expect(collectHistogramCount(reader, "compaction.duration")).To(BeNumerically("==", 1))
+	})
+
+	It("counts compactions that time out", func() {
+		proj.CompactionTimeout = 10 * time.Millisecond
+
+		handler.CompactFunc = func(ctx context.Context, _ dogma.ProjectionCompactScope) error {
+			<-ctx.Done()
+			cancel()
+			return ctx.Err()
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+
+		compactions, _ := collectSum(reader, "compactions")
+		Expect(compactions).To(BeNumerically("==", 1))
+
+		timeouts, _ := collectSum(reader, "compaction.timeouts")
+		Expect(timeouts).To(BeNumerically("==", 1))
+	})
+
+	It("records an info metric describing the projector's configuration", func() {
+		proj.DefaultTimeout = 10 * time.Second
+		proj.CompactionTimeout = -1
+
+		handler.HandleEventFunc = func(
+			context.Context,
+			[]byte, []byte, []byte,
+			dogma.ProjectionEventScope,
+			dogma.Message,
+		) (bool, error) {
+			cancel()
+			return true, nil
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+
+		v, attrs := collectGaugeWithAttributes(reader, "projector.info")
+		Expect(v).To(BeNumerically("==", 1))
+		Expect(attrs.ToSlice()).To(ConsistOf(
+			attribute.String("projection", "<proj>"),
+			attribute.String("aperture.handler.name", "<proj>"),
+			attribute.String("aperture.handler.key", "45804515-8b41-4d23-97b1-0cda5a0d782c"),
+			attribute.String("aperture.stream.id", "<id>"),
+			attribute.String("aperture.projector.default_timeout", "10s"),
+			attribute.String("aperture.projector.compaction_interval", "24h0m0s"),
+			attribute.String("aperture.projector.compaction_timeout", "none"),
+		))
+	})
+
+	It("does not count a cursor that fails to open", func() {
+		handler.ResourceVersionFunc = func(context.Context, []byte) ([]byte, error) {
+			return []byte{0x01}, nil
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).Should(HaveOccurred())
+
+		opened, _ := collectSum(reader, "cursor.open")
+		Expect(opened).To(BeZero())
+
+		closed, _ := collectSum(reader, "cursor.close")
+		Expect(closed).To(BeZero())
+	})
+
+	It("does not record anything if the instruments are nil", func() {
+		proj.Metrics = &ProjectorMetrics{}
+
+		handler.HandleEventFunc = func(
+			context.Context,
+			[]byte, []byte, []byte,
+			dogma.ProjectionEventScope,
+			dogma.Message,
+		) (bool, error) {
+			cancel()
+			return true, nil
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+	})
+
+	Describe("func NewProjectorMetrics()", func() {
+		It("creates every instrument", func() {
+			reader = sdkmetric.NewManualReader()
+			meter := sdkmetric.NewMeterProvider(
+				sdkmetric.WithReader(reader),
+			).Meter("<meter>")
+
+			metrics, err := NewProjectorMetrics(
+				meter,
+				attribute.String("projection", "<proj>"),
+			)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(metrics.CursorOpenCount).NotTo(BeNil())
+			Expect(metrics.CursorCloseCount).NotTo(BeNil())
+			Expect(metrics.ResumeOffset).NotTo(BeNil())
+			Expect(metrics.EventCount).NotTo(BeNil())
+			Expect(metrics.ErrorCount).NotTo(BeNil())
+			Expect(metrics.Lag).NotTo(BeNil())
+			Expect(metrics.CompactionCount).NotTo(BeNil())
+			Expect(metrics.CompactionTimeoutCount).NotTo(BeNil())
+			Expect(metrics.CompactionTime).NotTo(BeNil())
+			Expect(metrics.Info).NotTo(BeNil())
+
+			proj.Metrics = metrics
+
+			handler.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				cancel()
+				return true, nil
+			}
+
+			err = proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+
+			opened, attrs := collectSum(reader, "aperture.projector.cursor.opens")
+			Expect(opened).To(BeNumerically("==", 1))
+			Expect(attrs).To(Equal(
+				attribute.NewSet(attribute.String("projection", "<proj>")),
+			))
+
+			Expect(collectStatuses(reader, "aperture.projector.events")).To(Equal(
+				map[string]int64{"handled": 1},
+			))
+		})
+	})
+})
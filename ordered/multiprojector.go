@@ -0,0 +1,189 @@
+package ordered
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dogmatiq/configkit"
+	"github.com/dogmatiq/configkit/message"
+	"github.com/dogmatiq/dodeca/logging"
+	"github.com/dogmatiq/dogma"
+	"golang.org/x/sync/errgroup"
+)
+
+// MultiProjector reads events from a single stream and applies them to
+// several projections.
+//
+// It is equivalent to running a separate Projector for each handler, except
+// that the stream is only read once. This is useful when reading from the
+// stream is expensive.
+//
+// Each handler tracks its own offset within the stream using OCC, exactly as
+// it would when used with a Projector. The stream is opened at the lowest
+// offset required by any of the handlers, and each event is only passed to
+// those handlers that consume its type and have not already applied it.
+//
+// If any handler fails due to an OCC conflict the cursor is closed and the
+// consumer restarts, re-reading the resource version of every handler. Events
+// already applied to other handlers remain applied and are not passed to them
+// again. If any handler returns an error, Run() returns that error; events
+// already applied to other handlers remain applied.
+type MultiProjector struct {
+	// Stream is the stream used to obtain event messages.
+	Stream Stream
+
+	// Handlers is the set of Dogma projection handlers that the messages are
+	// applied to.
+	Handlers []dogma.ProjectionMessageHandler
+
+	// Logger is the target for log messages from the projector and the
+	// handlers. If it is nil, logging.DefaultLogger is used.
+	Logger logging.Logger
+
+	// StructuredLogger, if non-nil, is the target for log messages produced
+	// via the scopes passed to the handlers, as per
+	// Projector.StructuredLogger.
+	StructuredLogger *slog.Logger
+
+	// DefaultTimeout is the timeout duration to use when hanlding an event if
+	// the handler does not provide a timeout hint. If it is zero the global
+	// DefaultTimeout constant is used.
+	DefaultTimeout time.Duration
+
+	// CompactionInterval is the interval at which the projector compacts each
+	// projection. If it is zero the global DefaultCompactionInterval constant
+	// is used.
+	// If it is negative, compaction is disabled, as per
+	// Projector.CompactionInterval.
+	CompactionInterval time.Duration
+
+	// CompactionTimeout is the default timeout to use when compacting each
+	// projection. If it is zero the global DefaultCompactionTimeout is used.
+	// If it is negative, no timeout is applied, as per
+	// Projector.CompactionTimeout.
+	CompactionTimeout time.Duration
+}
+
+// Run runs the projections until ctx is canceled or an error occurs.
+//
+// Run() can safely be called again after exiting with an error.
+func (m *MultiProjector) Run(ctx context.Context) (err error) {
+	defer configkit.Recover(&err)
+
+	projectors := make([]*Projector, len(m.Handlers))
+	for i, h := range m.Handlers {
+		p := &Projector{
+			Stream:             m.Stream,
+			Handler:            h,
+			Logger:             m.Logger,
+			StructuredLogger:   m.StructuredLogger,
+			DefaultTimeout:     m.DefaultTimeout,
+			CompactionInterval: m.CompactionInterval,
+			CompactionTimeout:  m.CompactionTimeout,
+		}
+		p.prepare()
+		projectors[i] = p
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+
+	for _, p := range projectors {
+		func(p *Projector) {
+			g.Go(func() error {
+				return p.compactLoop(gctx)
+			})
+		}(p)
+	}
+
+	g.Go(func() error {
+		for {
+			if err := m.consume(gctx, projectors); err != nil {
+				return fmt.Errorf(
+					"unable to consume from '%s': %w",
+					m.Stream.ID(),
+					err,
+				)
+			}
+		}
+	})
+
+	err = g.Wait()
+
+	if ctx.Err() != nil && !isGenuine(err) {
+		// Don't wrap the error at all if we have been asked to bail.
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// consume opens the stream, consumes messages and applies them to the
+// projections.
+//
+// It consumes until ctx is canceled, an error occurs, or a message is not
+// applied to one of the projections due to an OCC conflict, in which case it
+// returns nil.
+func (m *MultiProjector) consume(ctx context.Context, projectors []*Projector) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	offsets := make([]uint64, len(projectors))
+	var (
+		types  []message.TypeCollection
+		offset uint64
+	)
+
+	for i, p := range projectors {
+		o, err := p.resume(ctx)
+		if err != nil {
+			return &OpenError{
+				fmt.Errorf("'%s' projection: %w", p.name, err),
+			}
+		}
+
+		if i == 0 || o < offset {
+			offset = o
+		}
+
+		offsets[i] = o
+		types = append(types, p.state.Load().types)
+	}
+
+	cur, err := m.Stream.Open(
+		ctx,
+		offset,
+		filterOf(message.UnionT(types...)),
+	)
+	if err != nil {
+		return &OpenError{err}
+	}
+	defer cur.Close()
+
+	for {
+		env, err := cur.Next(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i, p := range projectors {
+			st := p.state.Load()
+			if env.Offset < offsets[i] || !st.consumes(env) {
+				continue
+			}
+
+			ok, err := p.handle(ctx, st.handler, env)
+			if err != nil {
+				return fmt.Errorf("'%s' projection: %w", p.name, err)
+			}
+
+			if !ok {
+				return nil
+			}
+
+			offsets[i] = env.Offset + 1
+		}
+	}
+}
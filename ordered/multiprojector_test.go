@@ -0,0 +1,285 @@
+package ordered_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type MultiProjector", func() {
+	var (
+		ctx      context.Context
+		cancel   func()
+		stream   *MemoryStream
+		handler1 *ProjectionMessageHandler
+		handler2 *ProjectionMessageHandler
+		proj     *MultiProjector
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), DefaultTimeout*2)
+
+		stream = &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		stream.Append(
+			time.Now(),
+			MessageA1,
+			MessageB1,
+			MessageA2,
+			MessageB2,
+		)
+
+		handler1 = &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj-1>", "d61a5ab5-6d92-4d25-9a1b-0c7bb2c3f2f5")
+				c.ConsumesEventType(MessageA{})
+			},
+		}
+
+		handler2 = &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj-2>", "0a2e1a2e-7d3b-4f7e-8f1e-5d5b1ab0dc8e")
+				c.ConsumesEventType(MessageA{})
+				c.ConsumesEventType(MessageB{})
+			},
+		}
+
+		proj = &MultiProjector{
+			Stream:   stream,
+			Handlers: []dogma.ProjectionMessageHandler{handler1, handler2},
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Describe("func Run()", func() {
+		It("passes each event to the handlers that consume it", func() {
+			var (
+				m         sync.Mutex
+				messages1 []dogma.Message
+				messages2 []dogma.Message
+			)
+
+			handler1.HandleEventFunc = func(
+				_ context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				msg dogma.Message,
+			) (bool, error) {
+				m.Lock()
+				defer m.Unlock()
+				messages1 = append(messages1, msg)
+				return true, nil
+			}
+
+			handler2.HandleEventFunc = func(
+				_ context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				msg dogma.Message,
+			) (bool, error) {
+				m.Lock()
+				defer m.Unlock()
+				messages2 = append(messages2, msg)
+
+				if len(messages2) == 4 {
+					cancel()
+				}
+
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+			Expect(messages1).To(Equal(
+				[]dogma.Message{MessageA1, MessageA2},
+			))
+			Expect(messages2).To(Equal(
+				[]dogma.Message{MessageA1, MessageB1, MessageA2, MessageB2},
+			))
+		})
+
+		It("does not pass events that a handler has already applied", func() {
+			handler2.ResourceVersionFunc = func(
+				context.Context,
+				[]byte,
+			) ([]byte, error) {
+				return []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02}, nil
+			}
+
+			var messages1, messages2 []dogma.Message
+
+			handler1.HandleEventFunc = func(
+				_ context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				msg dogma.Message,
+			) (bool, error) {
+				messages1 = append(messages1, msg)
+				return true, nil
+			}
+
+			handler2.HandleEventFunc = func(
+				_ context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				msg dogma.Message,
+			) (bool, error) {
+				messages2 = append(messages2, msg)
+				cancel()
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+			Expect(messages1).To(Equal(
+				[]dogma.Message{MessageA1, MessageA2},
+			))
+			Expect(messages2).To(Equal(
+				[]dogma.Message{MessageB2},
+			))
+		})
+
+		It("does not pass events to other handlers again when a conflict occurs", func() {
+			var messages1 []dogma.Message
+			handler1.HandleEventFunc = func(
+				_ context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				msg dogma.Message,
+			) (bool, error) {
+				messages1 = append(messages1, msg)
+				handler1.ResourceVersionFunc = func(
+					context.Context,
+					[]byte,
+				) ([]byte, error) {
+					return []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, nil
+				}
+				return true, nil
+			}
+
+			conflicted := false
+			handler2.HandleEventFunc = func(
+				_ context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				msg dogma.Message,
+			) (bool, error) {
+				if !conflicted {
+					conflicted = true
+					return false, nil
+				}
+
+				Expect(msg).To(Equal(MessageA1))
+				cancel()
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+			Expect(messages1).To(Equal(
+				[]dogma.Message{MessageA1},
+			))
+		})
+
+		It("returns an error if a handler returns an error", func() {
+			handler2.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				return false, errors.New("<error>")
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(MatchError(
+				"unable to consume from '<id>': '<proj-2>' projection: <error>",
+			))
+
+			var handleErr *HandleError
+			Expect(errors.As(err, &handleErr)).To(BeTrue())
+		})
+
+		It("returns the handler's error if the context is canceled at the same time", func() {
+			handler2.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				cancel()
+				return false, errors.New("<error>")
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(MatchError(
+				"unable to consume from '<id>': '<proj-2>' projection: <error>",
+			))
+		})
+
+		It("returns an error if a handler's version can not be read", func() {
+			handler1.ResourceVersionFunc = func(
+				context.Context,
+				[]byte,
+			) ([]byte, error) {
+				return nil, errors.New("<error>")
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(MatchError(
+				"unable to consume from '<id>': '<proj-1>' projection: <error>",
+			))
+
+			var openErr *OpenError
+			Expect(errors.As(err, &openErr)).To(BeTrue())
+		})
+
+		It("compacts each projection", func() {
+			var (
+				m         sync.Mutex
+				compacted []string
+			)
+
+			compact := func(name string) func(context.Context, dogma.ProjectionCompactScope) error {
+				return func(context.Context, dogma.ProjectionCompactScope) error {
+					m.Lock()
+					defer m.Unlock()
+					compacted = append(compacted, name)
+
+					if len(compacted) == 2 {
+						cancel()
+					}
+
+					return nil
+				}
+			}
+
+			handler1.CompactFunc = compact("<proj-1>")
+			handler2.CompactFunc = compact("<proj-2>")
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+			Expect(compacted).To(ConsistOf("<proj-1>", "<proj-2>"))
+		})
+
+		It("returns an error if a handler configuration is invalid", func() {
+			handler2.ConfigureFunc = nil
+			err := proj.Run(ctx)
+			Expect(err).To(MatchError(
+				"*fixtures.ProjectionMessageHandler is configured without an identity, Identity() must be called exactly once within Configure()",
+			))
+		})
+	})
+})
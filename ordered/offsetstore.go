@@ -0,0 +1,111 @@
+package ordered
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/dogmatiq/aperture/ordered/resource"
+	"github.com/dogmatiq/dodeca/logging"
+)
+
+// OffsetStore is an interface for persisting the offset of the next event to
+// be consumed from a stream, independently of the projection itself.
+type OffsetStore interface {
+	// Load returns the offset of the next event to be consumed by the consumer
+	// identified by key.
+	//
+	// If no offset has been saved for the given key, it returns 0.
+	Load(ctx context.Context, key string) (uint64, error)
+
+	// Save sets the offset of the next event to be consumed by the consumer
+	// identified by key.
+	Save(ctx context.Context, key string, offset uint64) error
+}
+
+// MemoryOffsetStore is an implementation of OffsetStore that stores offsets in
+// memory.
+//
+// It is intended for testing and for simple deployments where all consumers
+// run within a single process. It is safe for concurrent use.
+type MemoryOffsetStore struct {
+	m       sync.RWMutex
+	offsets map[string]uint64
+}
+
+// Load returns the offset of the next event to be consumed by the consumer
+// identified by key.
+//
+// If no offset has been saved for the given key, it returns 0.
+func (s *MemoryOffsetStore) Load(ctx context.Context, key string) (uint64, error) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	return s.offsets[key], nil
+}
+
+// Save sets the offset of the next event to be consumed by the consumer
+// identified by key.
+func (s *MemoryOffsetStore) Save(ctx context.Context, key string, offset uint64) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.offsets == nil {
+		s.offsets = map[string]uint64{}
+	}
+
+	s.offsets[key] = offset
+
+	return nil
+}
+
+// consumerID returns the ID used to identify the projector to the stream and
+// to the offset store.
+func (p *Projector) consumerID() string {
+	if p.ConsumerID != "" {
+		return p.ConsumerID
+	}
+
+	return p.key
+}
+
+// load returns the offset of the next event to consume from p.OffsetStore.
+func (p *Projector) load(ctx context.Context) (uint64, error) {
+	offset, err := p.OffsetStore.Load(ctx, p.consumerID())
+	if err != nil {
+		return 0, fmt.Errorf("unable to load the offset: %w", err)
+	}
+
+	// The handler is still passed versions that encode the offset, so that
+	// they remain consistent with those used without an offset store.
+	p.current = nil
+	if offset > 0 {
+		p.current = resource.MarshalOffset(offset)
+	}
+
+	logging.Log(
+		p.Logger,
+		"[%s %s@%d] started consuming",
+		p.name,
+		p.resource,
+		offset,
+	)
+
+	return offset, nil
+}
+
+// save persists the offset of the next event to consume to p.OffsetStore, if
+// it is non-nil.
+//
+// It is called only after the handler has applied the preceding events.
+func (p *Projector) save(ctx context.Context, offset uint64) error {
+	if p.OffsetStore == nil {
+		return nil
+	}
+
+	if err := p.OffsetStore.Save(ctx, p.consumerID(), offset); err != nil {
+		return fmt.Errorf("unable to save the offset: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,255 @@
+package ordered_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/dogmatiq/aperture/aperturetest"
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// failingOffsetStore is an OffsetStore that fails to save offsets while err is
+// non-nil.
+type failingOffsetStore struct {
+	MemoryOffsetStore
+	err error
+}
+
+func (s *failingOffsetStore) Save(ctx context.Context, key string, offset uint64) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	return s.MemoryOffsetStore.Save(ctx, key, offset)
+}
+
+var _ OffsetStore = (*MemoryOffsetStore)(nil)
+
+var _ = Describe("type MemoryOffsetStore", func() {
+	var (
+		ctx   context.Context
+		store *MemoryOffsetStore
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		store = &MemoryOffsetStore{}
+	})
+
+	Describe("func Load()", func() {
+		It("returns zero if no offset has been saved", func() {
+			offset, err := store.Load(ctx, "<key>")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(offset).To(BeZero())
+		})
+
+		It("returns the saved offset", func() {
+			err := store.Save(ctx, "<key>", 123)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			offset, err := store.Load(ctx, "<key>")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(offset).To(BeEquivalentTo(123))
+		})
+	})
+
+	Describe("func Save()", func() {
+		It("replaces the previously saved offset", func() {
+			err := store.Save(ctx, "<key>", 123)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = store.Save(ctx, "<key>", 456)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			offset, err := store.Load(ctx, "<key>")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(offset).To(BeEquivalentTo(456))
+		})
+
+		It("stores the offsets for each key independently", func() {
+			var g sync.WaitGroup
+
+			for i := 0; i < 10; i++ {
+				i := i // capture loop variable
+				g.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer g.Done()
+					key := fmt.Sprintf("<key-%d>", i)
+					err := store.Save(ctx, key, uint64(i))
+					Expect(err).ShouldNot(HaveOccurred())
+				}()
+			}
+
+			g.Wait()
+
+			for i := 0; i < 10; i++ {
+				key := fmt.Sprintf("<key-%d>", i)
+				offset, err := store.Load(ctx, key)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(offset).To(BeEquivalentTo(i))
+			}
+		})
+	})
+})
+
+var _ = Describe("type Projector (with an offset store)", func() {
+	var (
+		ctx     context.Context
+		cancel  func()
+		stream  *MemoryStream
+		store   *failingOffsetStore
+		handler *ProjectionMessageHandler
+		proj    *Projector
+		handled []dogma.Message
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), DefaultTimeout*2)
+
+		stream = &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		stream.Append(
+			time.Now(),
+			MessageA1,
+			MessageA2,
+			MessageA3,
+		)
+
+		store = &failingOffsetStore{}
+		handled = nil
+
+		handler = &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageA{})
+			},
+			ResourceVersionFunc: func(context.Context, []byte) ([]byte, error) {
+				Fail("unexpected call to ResourceVersion()")
+				return nil, nil
+			},
+			HandleEventFunc: func(
+				_ context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				m dogma.Message,
+			) (bool, error) {
+				handled = append(handled, m)
+				if len(handled) == 3 {
+					cancel()
+				}
+				return true, nil
+			},
+		}
+
+		proj = &Projector{
+			Stream:      stream,
+			Handler:     handler,
+			OffsetStore: store,
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("resumes from the offset in the store", func() {
+		err := store.Save(ctx, "45804515-8b41-4d23-97b1-0cda5a0d782c", 2)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		handler.HandleEventFunc = func(
+			_ context.Context,
+			_, c, n []byte,
+			_ dogma.ProjectionEventScope,
+			m dogma.Message,
+		) (bool, error) {
+			defer cancel()
+			Expect(c).To(BeVersion(2))
+			Expect(n).To(BeVersion(3))
+			Expect(m).To(Equal(MessageA3))
+			return true, nil
+		}
+
+		err = proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+	})
+
+	It("saves the offset after each event is handled", func() {
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+
+		offset, err := store.Load(ctx, "45804515-8b41-4d23-97b1-0cda5a0d782c")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(offset).To(BeEquivalentTo(3))
+	})
+
+	It("saves the offset using the consumer ID, if set", func() {
+		proj.ConsumerID = "<consumer>"
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+
+		offset, err := store.Load(ctx, "<consumer>")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(offset).To(BeEquivalentTo(3))
+	})
+
+	It("does not save the offset if the handler fails", func() {
+		handler.HandleEventFunc = func(
+			context.Context,
+			[]byte, []byte, []byte,
+			dogma.ProjectionEventScope,
+			dogma.Message,
+		) (bool, error) {
+			return false, errors.New("<error>")
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(MatchError(ContainSubstring("<error>")))
+
+		offset, err := store.Load(ctx, "45804515-8b41-4d23-97b1-0cda5a0d782c")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(offset).To(BeZero())
+	})
+
+	It("redelivers the event if the offset can not be saved", func() {
+		store.err = errors.New("<error>")
+
+		err := proj.Run(ctx)
+		Expect(err).To(MatchError(ContainSubstring("unable to save the offset: <error>")))
+		Expect(handled).To(Equal([]dogma.Message{MessageA1}))
+
+		store.err = nil
+
+		err = proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+		Expect(handled).To(Equal(
+			[]dogma.Message{MessageA1, MessageA1, MessageA2},
+		))
+	})
+
+	It("returns an error if the offset can not be loaded", func() {
+		proj.OffsetStore = &loadFailingOffsetStore{}
+
+		err := proj.Run(ctx)
+		Expect(err).To(MatchError(ContainSubstring("unable to load the offset: <error>")))
+	})
+})
+
+// loadFailingOffsetStore is an OffsetStore that always fails to load offsets.
+type loadFailingOffsetStore struct {
+	MemoryOffsetStore
+}
+
+func (s *loadFailingOffsetStore) Load(context.Context, string) (uint64, error) {
+	return 0, errors.New("<error>")
+}
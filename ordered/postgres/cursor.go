@@ -0,0 +1,301 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var errCursorClosed = errors.New("cursor is closed")
+
+var (
+	_ ordered.NonBlockingCursor = (*cursor)(nil)
+	_ ordered.PeekCursor        = (*cursor)(nil)
+	_ ordered.FilterCursor      = (*cursor)(nil)
+)
+
+// cursor is an implementation of ordered.Cursor that reads events from a
+// PostgreSQL stream.
+type cursor struct {
+	stream   *Stream
+	offset   uint64
+	filtered bool
+	typeIDs  []string
+
+	// done is canceled when the cursor is closed.
+	done  context.Context
+	close context.CancelFunc
+
+	// m is held by Next() and Peek() while they are using the buffer and
+	// listener, so that Close() does not release the listener while it is in
+	// use.
+	m        sync.Mutex
+	buffer   []ordered.Envelope
+	listener *pgxpool.Conn
+}
+
+// Next returns the next relevant event in the stream.
+//
+// If the end of the stream is reached it blocks until a relevant event is
+// appended to the stream, or ctx is canceled.
+func (c *cursor) Next(ctx context.Context) (ordered.Envelope, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if err := c.await(ctx); err != nil {
+		return ordered.Envelope{}, err
+	}
+
+	return c.pop(), nil
+}
+
+// Peek returns the next relevant event in the stream without advancing the
+// cursor past it.
+//
+// It blocks in the same way as Next().
+func (c *cursor) Peek(ctx context.Context) (ordered.Envelope, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if err := c.await(ctx); err != nil {
+		return ordered.Envelope{}, err
+	}
+
+	return c.buffer[0], nil
+}
+
+// await blocks until the buffer contains at least one event. c.m must be held.
+func (c *cursor) await(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stop := context.AfterFunc(c.done, cancel)
+	defer stop()
+
+	for {
+		if c.done.Err() != nil {
+			return errCursorClosed
+		}
+
+		if len(c.buffer) > 0 {
+			return nil
+		}
+
+		if err := c.listen(ctx); err != nil {
+			return c.err(ctx, err)
+		}
+
+		if err := c.fetch(ctx); err != nil {
+			return c.err(ctx, err)
+		}
+
+		if len(c.buffer) == 0 {
+			if err := c.wait(ctx); err != nil {
+				return c.err(ctx, err)
+			}
+		}
+	}
+}
+
+// TryNext returns the next relevant event in the stream, if one is immediately
+// available.
+//
+// ok is false if the end of the stream has been reached.
+func (c *cursor) TryNext(ctx context.Context) (_ ordered.Envelope, ok bool, _ error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stop := context.AfterFunc(c.done, cancel)
+	defer stop()
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.done.Err() != nil {
+		return ordered.Envelope{}, false, errCursorClosed
+	}
+
+	if len(c.buffer) == 0 {
+		if err := c.fetch(ctx); err != nil {
+			return ordered.Envelope{}, false, c.err(ctx, err)
+		}
+	}
+
+	if len(c.buffer) == 0 {
+		return ordered.Envelope{}, false, nil
+	}
+
+	return c.pop(), true, nil
+}
+
+// SetFilter replaces the cursor's message-type filter.
+//
+// The new filter applies to events read from the cursor's current position
+// onwards. Events that have already been returned are never re-read. Any
+// events that were buffered using the previous filter are discarded.
+func (c *cursor) SetFilter(filter []dogma.Message) {
+	ids := c.stream.typeIDs(filter)
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.filtered = len(filter) > 0
+	c.typeIDs = ids
+	c.buffer = nil
+}
+
+// Close stops the cursor.
+//
+// Any current or future calls to Next() return a non-nil error.
+func (c *cursor) Close() error {
+	c.close()
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.buffer = nil
+	c.unlisten()
+
+	return nil
+}
+
+// pop removes the first event from the buffer and advances the cursor past it.
+func (c *cursor) pop() ordered.Envelope {
+	env := c.buffer[0]
+	c.buffer = c.buffer[1:]
+	c.offset = env.Offset + 1
+	return env
+}
+
+// err returns the error to report from Next() given an error that occurred
+// while reading from the database.
+func (c *cursor) err(ctx context.Context, err error) error {
+	if c.done.Err() != nil {
+		return errCursorClosed
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// listen acquires a dedicated connection and starts listening for
+// notifications about new events, if it is not already doing so.
+//
+// It must be called before fetch() so that no notifications are missed
+// between querying the events and waiting for new ones.
+func (c *cursor) listen(ctx context.Context) error {
+	if c.listener != nil {
+		return nil
+	}
+
+	conn, err := c.stream.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := conn.Exec(ctx, `LISTEN `+channel); err != nil {
+		conn.Release()
+		return err
+	}
+
+	c.listener = conn
+
+	return nil
+}
+
+// unlisten closes the listener's connection, if any.
+//
+// The connection is closed rather than returned to the pool so that it does
+// not continue to receive notifications.
+func (c *cursor) unlisten() {
+	if c.listener == nil {
+		return
+	}
+
+	conn := c.listener.Hijack()
+	c.listener = nil
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn.Close(ctx)
+}
+
+// wait blocks until a notification is received for this cursor's stream.
+func (c *cursor) wait(ctx context.Context) error {
+	for {
+		n, err := c.listener.Conn().WaitForNotification(ctx)
+		if err != nil {
+			// The connection can not be used after WaitForNotification()
+			// fails, including when ctx is canceled.
+			c.unlisten()
+			return err
+		}
+
+		if n.Payload == c.stream.ID() {
+			return nil
+		}
+	}
+}
+
+// fetch reads the next page of events into the buffer.
+func (c *cursor) fetch(ctx context.Context) error {
+	q := `SELECT stream_offset, recorded_at, type_id, data
+		FROM aperture.event
+		WHERE stream_id = $1
+		AND stream_offset >= $2
+		ORDER BY stream_offset
+		LIMIT $3`
+	args := []any{c.stream.ID(), int64(c.offset), c.stream.pageSize()}
+
+	if c.filtered {
+		q = `SELECT stream_offset, recorded_at, type_id, data
+			FROM aperture.event
+			WHERE stream_id = $1
+			AND stream_offset >= $2
+			AND type_id = ANY($4)
+			ORDER BY stream_offset
+			LIMIT $3`
+		args = append(args, c.typeIDs)
+	}
+
+	rows, err := c.stream.Pool.Query(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			offset int64
+			env    ordered.Envelope
+			typeID string
+			data   []byte
+		)
+
+		if err := rows.Scan(&offset, &env.RecordedAt, &typeID, &data); err != nil {
+			return err
+		}
+
+		m, err := c.stream.Marshaler.Unmarshal(typeID, data)
+		if err != nil {
+			return fmt.Errorf("unable to unmarshal event at offset %d: %w", offset, err)
+		}
+
+		env.Offset = uint64(offset)
+		env.Message = m
+		env.MessageID = ordered.MessageID(c.stream.ID(), env.Offset)
+		c.buffer = append(c.buffer, env)
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,3 @@
+// Package postgres provides an implementation of ordered.Stream that stores
+// events in a PostgreSQL database.
+package postgres
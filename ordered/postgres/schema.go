@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// channel is the name of the PostgreSQL notification channel used to notify
+// cursors that events have been appended to a stream. The payload of each
+// notification is the ID of the stream.
+const channel = "aperture_event"
+
+// schema is the sequence of statements that create the tables used by Stream.
+var schema = []string{
+	`CREATE SCHEMA IF NOT EXISTS aperture`,
+	`CREATE TABLE IF NOT EXISTS aperture.stream (
+		id          TEXT NOT NULL PRIMARY KEY,
+		next_offset BIGINT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS aperture.event (
+		stream_id     TEXT NOT NULL,
+		stream_offset BIGINT NOT NULL,
+		recorded_at   TIMESTAMPTZ NOT NULL,
+		type_id       TEXT NOT NULL,
+		data          BYTEA NOT NULL,
+
+		PRIMARY KEY (stream_id, stream_offset)
+	)`,
+	`CREATE INDEX IF NOT EXISTS event_by_type ON aperture.event (
+		stream_id,
+		type_id,
+		stream_offset
+	)`,
+}
+
+// CreateSchema creates the PostgreSQL schema and tables used by Stream, if
+// they do not already exist.
+func CreateSchema(ctx context.Context, pool *pgxpool.Pool) error {
+	for _, q := range schema {
+		if _, err := pool.Exec(ctx, q); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DropSchema removes the PostgreSQL schema and tables used by Stream, and
+// hence all of the events that they contain.
+func DropSchema(ctx context.Context, pool *pgxpool.Pool) error {
+	_, err := pool.Exec(ctx, `DROP SCHEMA IF EXISTS aperture CASCADE`)
+	return err
+}
@@ -0,0 +1,184 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dogmatiq/aperture/marshaling"
+	"github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultPageSize is the default maximum number of events that a cursor reads
+// from the database in a single query.
+const DefaultPageSize = 100
+
+// Stream is an implementation of ordered.Stream that stores events in a
+// PostgreSQL database.
+//
+// The database schema must be created using CreateSchema() before the stream
+// is used. Streams with different IDs may share the same database.
+//
+// Cursors wait for new events using PostgreSQL's LISTEN/NOTIFY mechanism, so
+// each open cursor holds one connection from the pool while it is waiting.
+// Sealing a stream is not supported.
+type Stream struct {
+	// StreamID is a unique identifier for the stream, it must not be empty.
+	// The tuple of stream ID and event offset must uniquely identify a message.
+	StreamID string
+
+	// Pool is the pool of connections to the database.
+	Pool *pgxpool.Pool
+
+	// Marshaler is used to marshal and unmarshal event messages. It must
+	// support every type of message appended to the stream.
+	Marshaler marshaling.Marshaler
+
+	// PageSize is the maximum number of events that a cursor reads from the
+	// database in a single query. If it is non-positive, DefaultPageSize is
+	// used.
+	PageSize int
+}
+
+var _ ordered.HeadStream = (*Stream)(nil)
+
+// ID returns a unique identifier for the stream.
+//
+// The tuple of stream ID and event offset must uniquely identify a message.
+func (s *Stream) ID() string {
+	if s.StreamID == "" {
+		panic("stream ID must not be empty")
+	}
+
+	return s.StreamID
+}
+
+// Open returns a cursor used to read events from this stream.
+//
+// offset is the position of the first event to read. The first event on a
+// stream is always at offset 0.
+//
+// filter is a set of zero-value event messages, the types of which indicate
+// which event types are returned by Cursor.Next(). If filter is empty, all
+// events types are returned. The filter is applied within the database query.
+// Types that are not supported by the marshaler can never appear on the
+// stream, and hence are ignored. The type ID of each filter type is obtained
+// by marshaling the zero-value message.
+func (s *Stream) Open(
+	ctx context.Context,
+	offset uint64,
+	filter []dogma.Message,
+) (ordered.Cursor, error) {
+	c := &cursor{
+		stream:   s,
+		offset:   offset,
+		filtered: len(filter) > 0,
+		typeIDs:  s.typeIDs(filter),
+	}
+
+	c.done, c.close = context.WithCancel(context.Background())
+
+	return c, nil
+}
+
+// Head returns the offset of the stream's head, that is, the offset at which
+// the next event will be appended.
+//
+// final is always false, as sealing is not supported.
+func (s *Stream) Head(ctx context.Context) (offset uint64, final bool, err error) {
+	var next int64
+
+	err = s.Pool.QueryRow(
+		ctx,
+		`SELECT next_offset FROM aperture.stream WHERE id = $1`,
+		s.ID(),
+	).Scan(&next)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+
+	return uint64(next), false, err
+}
+
+// Append appends messages to the end of the stream.
+//
+// Every message is recorded at the same time, t. The messages are inserted
+// within a single transaction, so either all of them are appended or none
+// are. Appends to the same stream are serialized by the database.
+func (s *Stream) Append(ctx context.Context, t time.Time, messages ...dogma.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	rows := make([][]any, len(messages))
+
+	for i, m := range messages {
+		data, id, err := s.Marshaler.Marshal(m)
+		if err != nil {
+			return fmt.Errorf("unable to marshal %T message: %w", m, err)
+		}
+
+		rows[i] = []any{s.ID(), nil, t, id, data}
+	}
+
+	return pgx.BeginFunc(ctx, s.Pool, func(tx pgx.Tx) error {
+		// Reserve a contiguous range of offsets. The row lock taken on the
+		// stream's row is held until the transaction is committed, which
+		// ensures events are committed in offset order.
+		var first int64
+		if err := tx.QueryRow(
+			ctx,
+			`INSERT INTO aperture.stream AS s (id, next_offset) VALUES ($1, $2)
+			ON CONFLICT (id) DO UPDATE SET next_offset = s.next_offset + $2
+			RETURNING s.next_offset - $2`,
+			s.ID(),
+			len(messages),
+		).Scan(&first); err != nil {
+			return err
+		}
+
+		for i := range rows {
+			rows[i][1] = first + int64(i)
+		}
+
+		if _, err := tx.CopyFrom(
+			ctx,
+			pgx.Identifier{"aperture", "event"},
+			[]string{"stream_id", "stream_offset", "recorded_at", "type_id", "data"},
+			pgx.CopyFromRows(rows),
+		); err != nil {
+			return err
+		}
+
+		// The notification is only delivered once the transaction commits.
+		_, err := tx.Exec(ctx, `SELECT pg_notify($1, $2)`, channel, s.ID())
+		return err
+	})
+}
+
+// typeIDs returns the type IDs of the messages in filter.
+func (s *Stream) typeIDs(filter []dogma.Message) []string {
+	var ids []string
+
+	for _, m := range filter {
+		if _, id, err := s.Marshaler.Marshal(m); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// pageSize returns the maximum number of events to read in a single query.
+func (s *Stream) pageSize() int {
+	if s.PageSize > 0 {
+		return s.PageSize
+	}
+
+	return DefaultPageSize
+}
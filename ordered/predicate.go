@@ -0,0 +1,58 @@
+package ordered
+
+import "context"
+
+// withPredicate returns a cursor that skips the events from cur for which fn
+// returns false.
+//
+// If cur is a PredicateCursor the predicate is applied by the cursor itself,
+// otherwise cur is wrapped. The wrapper preserves support for
+// NonBlockingCursor.
+func withPredicate(cur Cursor, fn func(Envelope) bool) Cursor {
+	if c, ok := cur.(PredicateCursor); ok {
+		c.SetPredicate(fn)
+		return c
+	}
+
+	if c, ok := cur.(NonBlockingCursor); ok {
+		return nonBlockingPredicateCursor{predicateCursor{c, fn}, c}
+	}
+
+	return predicateCursor{cur, fn}
+}
+
+// predicateCursor is a Cursor that skips the events for which a predicate
+// returns false.
+type predicateCursor struct {
+	Cursor
+	predicate func(Envelope) bool
+}
+
+// Next returns the next relevant event in the stream that satisfies the
+// predicate.
+func (c predicateCursor) Next(ctx context.Context) (Envelope, error) {
+	for {
+		env, err := c.Cursor.Next(ctx)
+		if err != nil || c.predicate(env) {
+			return env, err
+		}
+	}
+}
+
+// nonBlockingPredicateCursor is a NonBlockingCursor that skips the events for
+// which a predicate returns false.
+type nonBlockingPredicateCursor struct {
+	predicateCursor
+	cursor NonBlockingCursor
+}
+
+// TryNext returns the next relevant event in the stream that satisfies the
+// predicate, if one is immediately available.
+func (c nonBlockingPredicateCursor) TryNext(ctx context.Context) (Envelope, bool, error) {
+	for {
+		env, ok, err := c.cursor.TryNext(ctx)
+		if err != nil || !ok || c.predicate(env) {
+			return env, ok, err
+		}
+	}
+}
@@ -2,17 +2,26 @@ package ordered
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"reflect"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dogmatiq/aperture/internal/explainpanic"
+	"github.com/dogmatiq/aperture/internal/tracing"
 	"github.com/dogmatiq/aperture/ordered/resource"
 	"github.com/dogmatiq/configkit"
 	"github.com/dogmatiq/configkit/message"
 	"github.com/dogmatiq/dodeca/logging"
 	"github.com/dogmatiq/dogma"
 	"github.com/dogmatiq/linger"
+	"github.com/dogmatiq/linger/backoff"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -27,6 +36,18 @@ const (
 	// DefaultCompactionTimeout is the default timeout to use when compacting a
 	// projection.
 	DefaultCompactionTimeout = 5 * time.Minute
+
+	// DefaultBatchTimeout is the default amount of time to wait for a batch of
+	// events to fill before it is passed to the handler.
+	DefaultBatchTimeout = 100 * time.Millisecond
+
+	// DefaultReconnectDelay is the default delay before re-opening the stream
+	// after a temporary error.
+	DefaultReconnectDelay = 100 * time.Millisecond
+
+	// DefaultMaxReconnectDelay is the default maximum delay before re-opening
+	// the stream after consecutive temporary errors.
+	DefaultMaxReconnectDelay = 30 * time.Second
 )
 
 // Projector reads events from a stream and applies them to a projection.
@@ -41,27 +62,434 @@ type Projector struct {
 	// If it is nil, logging.DefaultLogger is used.
 	Logger logging.Logger
 
+	// LogFormat, if non-nil, is used to produce the prefix of log messages
+	// produced via the scopes passed to the handler, and by the projector
+	// when compaction times out. If it is nil, DefaultLogFormat is used.
+	LogFormat func(LogContext) string
+
+	// StructuredLogger, if non-nil, is the target for log messages produced
+	// via the scopes passed to the handler. Each message is logged at the info
+	// level with the handler name, resource and offset as attributes, instead
+	// of being prefixed according to LogFormat. If it is nil, these messages
+	// are sent to Logger.
+	StructuredLogger *slog.Logger
+
+	// Metrics is the set of instruments used to record metrics about the
+	// projector. If it is nil, no metrics are recorded.
+	Metrics *ProjectorMetrics
+
+	// Tracer is used to record spans about the operation of the projector. If
+	// it is nil, spans are recorded using the tracer provider of the span in
+	// the context passed to Run(), if any.
+	Tracer trace.Tracer
+
 	// DefaultTimeout is the timeout duration to use when hanlding an event if
 	// the handler does not provide a timeout hint. If it is zero the global
 	// DefaultTimeout constant is used.
 	DefaultTimeout time.Duration
 
+	// ShutdownGrace is the amount of time that the handler is given to finish
+	// handling the current event (or batch of events) after the context passed
+	// to Run() is canceled.
+	//
+	// During the grace period the handler's context remains valid, so an event
+	// that is partially applied when the projector is stopped can be
+	// completed. Run() then returns the context's error as usual. The event's
+	// own timeout still applies. If it is zero, the handler's context is
+	// canceled immediately.
+	ShutdownGrace time.Duration
+
+	// ConflictTimeout is the maximum amount of time that the projector
+	// continues to restart the consumer due to consecutive optimistic
+	// concurrency conflicts without applying any events.
+	//
+	// Each restart re-reads the resource version and retries the event, and
+	// each attempt may take up to the event's full timeout, so a handler that
+	// conflicts repeatedly can otherwise prevent the projection from advancing
+	// indefinitely. Once the timeout is exceeded a *ConflictError is returned.
+	// If it is zero, there is no limit.
+	ConflictTimeout time.Duration
+
 	// CompactionInterval is the interval at which the projector compacts the
 	// projection. If it is zero the global DefaultCompactionInterval constant
 	// is used.
+	//
+	// If it is negative, compaction is disabled entirely and the handler's
+	// Compact() method is never called. It is intended for handlers that do
+	// not perform any meaningful compaction.
 	CompactionInterval time.Duration
 
 	// CompactionTimeout is the default timeout to use when compacting the
 	// projection. If it is zero the global DefaultCompactionTimeout is used.
+	//
+	// If it is negative, no timeout is applied and compaction runs until it
+	// completes or the context passed to Run() is canceled. Use this with
+	// care; a compaction that never completes prevents any further compaction
+	// of the projection, and if SerializeCompaction is true it also prevents
+	// any further events from being handled.
 	CompactionTimeout time.Duration
 
+	// CompactionJitter is the maximum amount by which the interval between
+	// compactions is randomly lengthened or shortened.
+	//
+	// Each delay is chosen at random from the window CompactionInterval ±
+	// CompactionJitter, which prevents many projectors that are started at
+	// the same time from compacting at the same time. If it is zero, the
+	// projection is compacted at exactly CompactionInterval.
+	CompactionJitter time.Duration
+
+	// DeferInitialCompaction, if true, causes the projector to wait for
+	// CompactionInterval to elapse before compacting the projection for the
+	// first time.
+	//
+	// If it is false, the projection is compacted as soon as the projector
+	// starts. Deferring the initial compaction avoids redundant compaction
+	// when projectors are restarted frequently.
+	DeferInitialCompaction bool
+
+	// CompactionClock, if non-nil, is used to persist the time at which the
+	// projection was last compacted.
+	//
+	// By default the time of the last compaction is only tracked in memory, so
+	// a projector that is restarted more frequently than CompactionInterval may
+	// never compact the projection (if DeferInitialCompaction is true) or may
+	// compact it more often than necessary (if it is false). When a clock is
+	// provided the next compaction is scheduled relative to the persisted time
+	// and DeferInitialCompaction is ignored.
+	CompactionClock CompactionClock
+
+	// Clock is the source of the current time used to schedule compaction and
+	// to measure ConflictTimeout. It is also the time reported by the
+	// compaction scope's Now() method. If it is nil, SystemClock is used.
+	//
+	// It is intended for testing, it allows compaction intervals and the
+	// conflict timeout to be driven by a fake clock. Handler and compaction
+	// timeouts are context deadlines, and hence are always measured using the
+	// system clock, as is the compaction duration metric.
+	Clock Clock
+
+	// BatchSize is the maximum number of events to pass to the handler in a
+	// single call.
+	//
+	// Batching is only performed if the handler implements
+	// BatchProjectionMessageHandler and BatchSize is greater than one.
+	// Otherwise, events are passed to the handler one at a time.
+	BatchSize int
+
+	// BatchTimeout is the maximum amount of time to wait for a batch to fill
+	// after its first event has been read. Once the timeout elapses the
+	// partial batch is passed to the handler. If it is zero the global
+	// DefaultBatchTimeout constant is used.
+	//
+	// If it is negative, the projector does not wait for the batch to fill.
+	// Instead, the batch contains only those events that are immediately
+	// available from the cursor, which requires the cursor to implement
+	// NonBlockingCursor. Otherwise each batch contains a single event.
+	BatchTimeout time.Duration
+
+	// FailBeyondHead, if true, causes the projector to fail with an error if
+	// the offset recorded within the projection is beyond the head of the
+	// stream, which usually indicates that the stream has been reset or
+	// replaced without also resetting the projection.
+	//
+	// If it is false, a warning is logged and the projector waits for events
+	// to be appended at that offset. The check is only performed if the
+	// stream implements HeadStream or BoundsStream.
+	FailBeyondHead bool
+
+	// StopWhenSealed, if true, causes Run() to return nil once every event on
+	// a sealed stream has been applied, treating the end of the stream as the
+	// completion of the projection rather than a failure.
+	//
+	// If it is false, Run() returns an error that wraps ErrStreamSealed.
+	StopWhenSealed bool
+
+	// StrictOrdering, if true, causes the projector to verify that the offset
+	// of each event returned by the stream's cursor is greater than that of
+	// the previous event, as required by Cursor.Next().
+	//
+	// If an event is out of order, such as a duplicate offset on a durable
+	// stream, consumption fails with an *OrderingError rather than applying
+	// the event.
+	StrictOrdering bool
+
+	// StartupJitter is the maximum amount of time to wait before the projector
+	// first consumes from the stream or compacts the projection.
+	//
+	// Run() waits for a random duration between zero and StartupJitter before
+	// doing any work, which spreads the load when many projectors are started
+	// at the same time. If it is zero, there is no delay.
+	StartupJitter time.Duration
+
+	// ConsumerID identifies the projector to streams that implement
+	// ConsumerStream. If it is empty, the handler's identity key is used.
+	ConsumerID string
+
+	// OffsetStore, if non-nil, persists the offset of the next event to be
+	// consumed, instead of deriving it from the handler's resource version.
+	//
+	// The offset is loaded each time the projector opens the stream, and saved
+	// only after the handler has applied an event successfully. If the
+	// projector stops between handling an event and saving the offset, the
+	// event is delivered again when the projector restarts, so the handler
+	// must tolerate events being applied more than once.
+	//
+	// Offsets are stored under the same ID used for ConsumerStream.
+	OffsetStore OffsetStore
+
+	// ResumeOffset returns the offset of the next event to read from the
+	// stream, given the current resource version as returned by the handler's
+	// ResourceVersion() method.
+	//
+	// If it is nil, resource.UnmarshalOffset() is used, which expects the
+	// version to encode the offset of the last event that was applied, such
+	// that the next event to read is at the following offset.
+	//
+	// The projector always writes new versions using resource.MarshalOffset(),
+	// so a custom function must also accept versions in that format. It is
+	// intended for projections whose versions were initially written using a
+	// different convention.
+	ResumeOffset func(version []byte) (uint64, error)
+
+	// StartOffset, if non-nil, is the offset at which the projector begins
+	// consuming, regardless of the offset recorded within the projection.
+	//
+	// It bypasses the handler's persisted position and is intended for manual
+	// intervention, such as forcing a projection to resume from a known-good
+	// offset during incident recovery. The resource version is still read from
+	// the handler, and the real current and next versions are passed to the
+	// handler, so that OCC checks are unaffected.
+	//
+	// It applies only the first time the stream is opened by each call to
+	// Run(). If the stream is re-opened, such as after an OCC conflict, the
+	// projector resumes from the offset recorded within the projection.
+	StartOffset *uint64
+
+	// StartAtTail, if true, causes a projection that has no recorded offset to
+	// begin consuming from the tail of the stream, rather than from offset 0.
+	//
+	// It is intended for projections that are only interested in events that
+	// occur after the projector is started, such as those used for
+	// monitoring. The stream should implement TailStream, so that the head
+	// can be determined atomically, otherwise it must implement HeadStream or
+	// BoundsStream. The tail is used each time the stream is opened until the
+	// projection records an offset, so events that occur while the stream is
+	// being re-opened, such as after an error, may be missed.
+	//
+	// A projection that has been reset is indistinguishable from one that has
+	// never recorded an offset, so StartAtTail can not be used with Reset() or
+	// OffsetStore, which stores offset 0 both for new and reset projections.
+	StartAtTail bool
+
+	// Predicate, if non-nil, is called with each event of a type consumed by
+	// the handler. Events for which it returns false are skipped without
+	// being passed to the handler.
+	//
+	// It allows events to be filtered by their content, avoiding the overhead
+	// of calling the handler for irrelevant events. If the stream's cursors
+	// implement PredicateCursor the predicate is evaluated by the cursor
+	// itself. As with events of types that are not consumed, the
+	// projection's version is not advanced past skipped events until the next
+	// event is applied.
+	//
+	// Because it may be called while reading from the stream, such as with
+	// the MemoryStream locked, it must be cheap and must not use the stream
+	// or the cursor, otherwise it may deadlock.
+	Predicate func(Envelope) bool
+
+	// OnVersionRead, if non-nil, is called with the raw resource version
+	// returned by the handler's ResourceVersion() method each time the
+	// projector opens the stream, before the version is decoded.
+	//
+	// The slice must not be modified or retained after the function returns.
+	OnVersionRead func(current []byte)
+
+	// OnVersionAdvance, if non-nil, is called with the raw resource versions
+	// passed to the handler each time it successfully applies an event (or
+	// batch of events) to the projection.
+	//
+	// The slices must not be modified or retained after the function returns.
+	OnVersionAdvance func(current, next []byte)
+
+	// SerializeCompaction, if true, prevents the projector from handling
+	// events while the projection is being compacted, and vice versa.
+	//
+	// It is intended for handlers that can not safely compact the projection
+	// while events are being applied. If it is false, compaction and event
+	// handling may occur concurrently.
+	SerializeCompaction bool
+
+	// RecoverHandlerPanics, if true, causes panics that occur within the
+	// handler's HandleEvent(), TimeoutHint() or Compact() methods to be
+	// recovered and converted into a *PanicError, which is then treated like
+	// any other error returned by the handler.
+	//
+	// Combined with OnHandlerError this allows a "poison" event that causes the
+	// handler to panic to be skipped.
+	RecoverHandlerPanics bool
+
+	// OnHandlerError, if non-nil, is called when the handler returns an error
+	// (or panics, if RecoverHandlerPanics is true) while handling an event. It
+	// returns the action that the projector should take.
+	//
+	// err is always a *HandleError. It is not called for errors caused by the
+	// cancelation of the context passed to Run(). If it is nil, the projector
+	// always behaves as though ReturnError was returned.
+	//
+	// It is not called when handling events in batches.
+	OnHandlerError func(env Envelope, err error) ErrorAction
+
+	// ReconnectDelay is the delay before re-opening the stream after the
+	// stream or cursor returns a TransientError that is temporary. The delay
+	// doubles after each consecutive temporary error, up to MaxReconnectDelay.
+	// If it is zero the global DefaultReconnectDelay constant is used.
+	ReconnectDelay time.Duration
+
+	// MaxReconnectDelay is the maximum delay before re-opening the stream after
+	// consecutive temporary errors. If it is zero the global
+	// DefaultMaxReconnectDelay constant is used.
+	MaxReconnectDelay time.Duration
+
+	// Backoff, if non-nil, causes Run() to retry after consuming events or
+	// compacting the projection fails, instead of returning the error. It
+	// returns the delay to wait before each retry.
+	//
+	// The delay is increased for each consecutive failure, and is reset once
+	// an event is handled successfully. Run() still returns if ctx is canceled
+	// or the end of a sealed stream is reached. If it is nil, Run() returns as
+	// soon as an error occurs.
+	Backoff backoff.Strategy
+
+	// OnCaughtUp, if non-nil, is called the first time the projector catches
+	// up with the head of the stream during each call to Run(). offset is the
+	// offset of the head of the stream at the time the projector first opened
+	// the stream, which is the boundary between historical and live events.
+	//
+	// It is only called if the stream implements HeadStream or BoundsStream.
+	// See CaughtUpOffset() for details.
+	OnCaughtUp func(offset uint64)
+
+	// DryRun, if true, causes the projector to read events from the stream
+	// without applying them to the projection.
+	//
+	// Each event that would have been passed to the handler is logged and
+	// passed to OnDryRun, if it is non-nil. The handler's HandleEvent() and
+	// Compact() methods are never called, and hence the projection's version is
+	// never advanced.
+	DryRun bool
+
+	// OnDryRun, if non-nil, is called with each event that would have been
+	// passed to the handler when DryRun is true.
+	OnDryRun func(Envelope)
+
+	m        sync.Mutex
+	prepared bool
+	sem      chan struct{}
+	restart  context.CancelFunc
+	failures int
+	started  bool
+	occSince time.Time
+	boundary *uint64
+	caughtUp atomic.Pointer[uint64]
+	handled  atomic.Uint64
+	position atomic.Uint64
+	handling atomic.Bool
+	settings settings
 	name     string
-	types    message.TypeCollection
+	key      string
+	state    atomic.Pointer[handlerState]
 	resource []byte
 	current  []byte
 	next     []byte
 }
 
+// Name returns the name of the projection handler.
+//
+// It panics if the handler is configured incorrectly.
+func (p *Projector) Name() string {
+	p.prepare()
+	return p.name
+}
+
+// Identity returns the identity of the projection handler.
+//
+// It is the same identity that the projector uses in its own log messages,
+// metrics and spans. It panics if the handler is configured incorrectly.
+func (p *Projector) Identity() configkit.Identity {
+	p.prepare()
+
+	return configkit.Identity{
+		Name: p.name,
+		Key:  p.key,
+	}
+}
+
+// Resource returns the OCC resource that the projector uses to track its
+// position within the stream.
+//
+// It panics if the handler is configured incorrectly.
+func (p *Projector) Resource() []byte {
+	p.prepare()
+	return append([]byte(nil), p.resource...)
+}
+
+// ConsumedTypes returns the event types consumed by the projection handler,
+// sorted by name.
+//
+// It panics if the handler is configured incorrectly.
+func (p *Projector) ConsumedTypes() []message.Type {
+	p.prepare()
+
+	var types []message.Type
+	p.state.Load().types.Range(func(t message.Type) bool {
+		types = append(types, t)
+		return true
+	})
+
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].String() < types[j].String()
+	})
+
+	return types
+}
+
+// HandledCount returns the number of events that have been applied to the
+// projection since Run() was most recently called.
+//
+// It is safe to call HandledCount() while Run() is executing.
+func (p *Projector) HandledCount() uint64 {
+	return p.handled.Load()
+}
+
+// Offset returns the offset of the most recent event that has been applied to
+// the projection.
+//
+// The offset is loaded from the projection each time the projector opens the
+// stream, and updated as events are applied. Events that are skipped due to
+// handler errors are not considered to be applied. ok is false if no events
+// have been applied to the projection, or the projector has not yet opened the
+// stream.
+//
+// It is safe to call Offset() while Run() is executing.
+func (p *Projector) Offset() (offset uint64, ok bool) {
+	if n := p.position.Load(); n > 0 {
+		return n - 1, true
+	}
+
+	return 0, false
+}
+
+// IsHandling returns true if the projector is currently waiting for the
+// handler to apply an event (or batch of events) to the projection.
+//
+// It returns false while the projector is idle, such as while it is waiting
+// for events to be appended to the stream.
+//
+// It is safe to call IsHandling() while Run() is executing.
+func (p *Projector) IsHandling() bool {
+	return p.handling.Load()
+}
+
 // Run runs the projection until ctx is canceled or an error occurs.
 //
 // Event messages are obtained from the stream and passed to the handler for
@@ -72,43 +500,121 @@ type Projector struct {
 // the projection the consumer restarts automatically.
 //
 // Run() returns if any other error occurs during handling or compaction, in
-// which case it is the caller's responsibility to implement any retry logic.
+// which case it is the caller's responsibility to implement any retry logic,
+// unless p.Backoff is set.
+//
+// If the stream is sealed and p.StopWhenSealed is true, Run() returns nil once
+// every event on the stream has been applied.
+//
+// Errors that occur while opening the stream are wrapped in an *OpenError.
+// Errors returned by the handler while handling events are wrapped in a
+// *HandleError. Use errors.As() to distinguish between them.
+//
+// Run() can safely be called again after exiting with an error. Use
+// RunWithResult() to determine why the projector stopped.
+func (p *Projector) Run(ctx context.Context) error {
+	_, err := p.RunWithResult(ctx)
+	return err
+}
+
+// run is the implementation of Run(), RunWithResult() and
+// RunUntilCaughtUpWithResult().
 //
-// Run() can safely be called again after exiting with an error.
-func (p *Projector) Run(ctx context.Context) (err error) {
+// If oneShot is true the projector stops once it has caught up with the head
+// of the stream, as per RunUntilCaughtUp().
+func (p *Projector) run(ctx context.Context, oneShot bool) (err error) {
 	defer configkit.Recover(&err)
 
-	cfg := configkit.FromProjection(p.Handler)
+	p.prepare()
 
-	p.name = cfg.Identity().Name
-	p.types = cfg.MessageTypes().Consumed
-	p.resource = resource.FromStreamID(p.Stream.ID())
+	if p.StartAtTail && p.OffsetStore != nil {
+		return fmt.Errorf(
+			"unable to run the '%s' projection: StartAtTail can not be used with an OffsetStore",
+			p.name,
+		)
+	}
 
-	g, gctx := errgroup.WithContext(ctx)
+	p.Metrics.started(ctx, p.info()...)
 
-	g.Go(func() error {
-		for {
-			if err := p.compact(gctx); err != nil {
-				return fmt.Errorf(
-					"unable to compact the '%s' projection: %w",
-					p.name,
-					err,
-				)
-			}
+	if err := linger.SleepX(ctx, linger.FullJitter, p.StartupJitter); err != nil {
+		return err
+	}
 
-			if err := linger.Sleep(
-				gctx,
-				p.CompactionInterval,
-				DefaultCompactionInterval,
-			); err != nil {
-				return err
-			}
+	p.handled.Store(0)
+	p.failures = 0
+	p.started = false
+	p.occSince = time.Time{}
+	p.resetCatchUp()
+
+	p.sem = nil
+	if p.SerializeCompaction {
+		p.sem = make(chan struct{}, 1)
+	}
+
+	counter := backoff.Counter{Strategy: p.Backoff}
+
+	for {
+		handled := p.handled.Load()
+		err = p.attempt(ctx, oneShot)
+
+		if p.Backoff == nil ||
+			ctx.Err() != nil ||
+			errors.Is(err, ErrStreamSealed) ||
+			errors.Is(err, errCaughtUp) {
+			break
 		}
-	})
+
+		if p.handled.Load() > handled {
+			// Only back off further if no progress has been made since the
+			// previous failure.
+			counter.Reset()
+		}
+
+		delay := counter.Fail(err)
+
+		logging.Log(
+			p.Logger,
+			"[%s %s] retrying in %s: %s",
+			p.name,
+			p.resource,
+			delay,
+			err,
+		)
+
+		if err := linger.Sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+
+	if ctx.Err() != nil && !isGenuine(err) {
+		// Don't wrap the error at all if we have been asked to bail.
+		return ctx.Err()
+	}
+
+	if oneShot &&
+		(errors.Is(err, errCaughtUp) || errors.Is(err, ErrStreamSealed)) {
+		return p.compactOnce(ctx)
+	}
+
+	return err
+}
+
+// attempt consumes events and compacts the projection until ctx is canceled or
+// an error occurs.
+//
+// If oneShot is true the projection is not compacted periodically.
+func (p *Projector) attempt(ctx context.Context, oneShot bool) error {
+	g, gctx := errgroup.WithContext(ctx)
+
+	if !p.DryRun && !oneShot && p.compactionInterval() >= 0 {
+		g.Go(func() error {
+			return p.compactLoop(gctx)
+		})
+	}
 
 	g.Go(func() error {
 		for {
-			if err := p.consume(gctx); err != nil {
+			if err := p.consumeUntilReset(gctx, oneShot); err != nil {
 				return fmt.Errorf(
 					"unable to consume from '%s' for the '%s' projection: %w",
 					p.Stream.ID(),
@@ -119,42 +625,279 @@ func (p *Projector) Run(ctx context.Context) (err error) {
 		}
 	})
 
-	err = g.Wait()
+	return g.Wait()
+}
 
-	select {
-	case <-ctx.Done():
-		// Don't wrap the error at all if we have been asked to bail.
-		return ctx.Err()
-	default:
+// isGenuine returns true if err is a failure that did not occur merely as a
+// result of a context being canceled.
+//
+// It allows an error that occurs at the same time as the context passed to
+// Run() is canceled to be reported, instead of masking it with ctx.Err().
+func isGenuine(err error) bool {
+	return err != nil &&
+		!errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded)
+}
+
+// Start runs the projector in a new goroutine.
+//
+// It returns a function that stops the projector by canceling the context
+// passed to Run(), then waits for Run() to return. The stop function returns
+// the error returned by Run(), which is context.Canceled if the projector was
+// stopped before any other error occurred. It is safe to call the stop
+// function more than once.
+//
+// Panics that occur within the projector are not recovered; they propagate as
+// they would if Run() were called directly.
+func (p *Projector) Start(ctx context.Context) (stop func() error) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	var err error
+	go func() {
+		defer close(done)
+		err = p.Run(ctx)
+	}()
+
+	return func() error {
+		cancel()
+		<-done
 		return err
 	}
 }
 
+// prepare computes the state that is derived from the handler's configuration
+// and the stream, if it has not already been computed.
+//
+// It panics if the handler is configured incorrectly.
+func (p *Projector) prepare() {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.prepared {
+		return
+	}
+
+	cfg := configkit.FromProjection(p.Handler)
+
+	p.name = cfg.Identity().Name
+	p.key = cfg.Identity().Key
+	p.state.Store(&handlerState{
+		handler: p.Handler,
+		types:   cfg.MessageTypes().Consumed,
+	})
+	p.resource = resource.FromStreamID(p.Stream.ID())
+	p.prepared = true
+}
+
 // consume opens the streams, consumes messages ands applies them to the
 // projection.
 //
 // It consumes until ctx is canceled, and error occurs, or a message is not
 // applied due to an OCC conflict, in which case it returns nil.
-func (p *Projector) consume(ctx context.Context) error {
+//
+// If oneShot is true it returns errCaughtUp once it reaches the end of the
+// stream.
+func (p *Projector) consume(ctx context.Context, oneShot bool) error {
+	// Bail before reading the resource version if ctx has already been
+	// canceled, such as when the projector is stopped immediately after an
+	// OCC conflict.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	cur, err := p.open(ctx)
 	if err != nil {
-		return err
+		if isTemporary(err) {
+			return p.reconnect(ctx, err)
+		}
+
+		return &OpenError{err}
+	}
+	p.Metrics.cursorOpened(ctx)
+
+	defer func() {
+		cur.Close()
+		p.Metrics.cursorClosed(ctx)
+	}()
+
+	if p.Predicate != nil {
+		cur = withPredicate(cur, p.Predicate)
+	}
+
+	if oneShot {
+		c, err := caughtUp(cur)
+		if err != nil {
+			return err
+		}
+		cur = c
+	}
+
+	if p.DryRun {
+		for {
+			env, err := cur.Next(ctx)
+			if err != nil {
+				return err
+			}
+
+			p.dryRun(env)
+		}
 	}
-	defer cur.Close()
 
 	for {
-		ok, err := p.consumeNext(ctx, cur)
+		// Load the handler state before each event (or batch) so that a
+		// handler swapped by SwapHandler() takes effect between events.
+		st := p.state.Load()
+
+		var (
+			ok  bool
+			err error
+		)
+
+		if h, isBatch := st.handler.(BatchProjectionMessageHandler); isBatch && p.BatchSize > 1 {
+			ok, err = p.consumeBatch(ctx, cur, st, h)
+		} else {
+			ok, err = p.consumeNext(ctx, cur, st)
+		}
+
+		if isGenuine(err) &&
+			!errors.Is(err, ErrStreamSealed) &&
+			!errors.Is(err, errCaughtUp) {
+			p.Metrics.failed(ctx)
+		}
+
+		if isTemporary(err) {
+			return p.reconnect(ctx, err)
+		}
+
 		if !ok || err != nil {
 			return err
 		}
+
+		p.failures = 0
 	}
 }
 
 // open opens a cursor on the stream based on the offset recorded within the
 // projection.
-func (p *Projector) open(ctx context.Context) (Cursor, error) {
+func (p *Projector) open(ctx context.Context) (_ Cursor, err error) {
+	ctx, span := tracing.Start(
+		ctx,
+		p.Tracer,
+		"aperture.open",
+		tracing.HandlerName.String(p.name),
+		tracing.StreamID.String(p.Stream.ID()),
+	)
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	offset, err := p.resume(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	overridden := p.StartOffset != nil && !p.started
+	p.started = true
+
+	if overridden {
+		offset = *p.StartOffset
+
+		logging.Log(
+			p.Logger,
+			"[%s %s@%d] overriding the projection's offset with the start offset",
+			p.name,
+			p.resource,
+			offset,
+		)
+	} else if p.StartAtTail && offset == 0 && len(p.current) == 0 {
+		return p.openTail(ctx, span)
+	}
+
+	span.SetAttributes(tracing.StreamOffset.Int64(int64(offset)))
+	p.Metrics.resumed(ctx, offset)
+	p.position.Store(offset)
+
+	if err := p.checkFirst(ctx, offset); err != nil {
+		return nil, err
+	}
+
+	if err := p.checkHead(ctx, offset); err != nil {
+		return nil, err
+	}
+
+	return p.openAt(ctx, offset)
+}
+
+// openAt opens a cursor on the stream at the given offset.
+func (p *Projector) openAt(ctx context.Context, offset uint64) (Cursor, error) {
+	filter := filterOf(p.state.Load().types)
+
+	if s, ok := p.Stream.(ConsumerStream); ok {
+		return s.OpenAs(ctx, p.consumerID(), offset, filter)
+	}
+
+	return p.Stream.Open(ctx, offset, filter)
+}
+
+// openTail opens a cursor at the head of the stream, such that only events
+// appended after the cursor is opened are consumed.
+//
+// It uses OpenTail() if the stream is a TailStream that supports it, otherwise
+// it opens the stream at the offset of its head as reported by HeadStream or
+// BoundsStream.
+func (p *Projector) openTail(ctx context.Context, span trace.Span) (cur Cursor, err error) {
+	var offset uint64
+
+	_, isConsumer := p.Stream.(ConsumerStream)
+
+	if s, ok := p.Stream.(TailStream); ok && !isConsumer {
+		cur, offset, err = s.OpenTail(ctx, filterOf(p.state.Load().types))
+	} else {
+		err = errors.ErrUnsupported
+	}
+
+	if errors.Is(err, errors.ErrUnsupported) {
+		var ok bool
+		offset, ok, err = p.head(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			return nil, fmt.Errorf(
+				"unable to open the stream at its tail, %T does not implement TailStream, HeadStream or BoundsStream",
+				p.Stream,
+			)
+		}
+
+		cur, err = p.openAt(ctx, offset)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(tracing.StreamOffset.Int64(int64(offset)))
+	p.Metrics.resumed(ctx, offset)
+	p.position.Store(offset)
+	p.trackCatchUp(offset, offset)
+
+	logging.Log(
+		p.Logger,
+		"[%s %s@%d] the projection has no recorded offset, consuming from the tail of the stream",
+		p.name,
+		p.resource,
+		offset,
+	)
+
+	return cur, nil
+}
+
+// filterOf returns a stream filter that matches the given event types.
+func filterOf(tc message.TypeCollection) []dogma.Message {
 	var types []dogma.Message
-	p.types.Range(func(t message.Type) bool {
+	tc.Range(func(t message.Type) bool {
 		types = append(
 			types,
 			reflect.Zero(t.ReflectType()).Interface().(dogma.Message),
@@ -162,18 +905,34 @@ func (p *Projector) open(ctx context.Context) (Cursor, error) {
 		return true
 	})
 
-	var (
-		offset uint64
-		err    error
-	)
-	p.current, err = p.Handler.ResourceVersion(ctx, p.resource)
+	return types
+}
+
+// resume loads the current resource version from the handler and returns the
+// offset of the next event to be applied to the projection.
+func (p *Projector) resume(ctx context.Context) (uint64, error) {
+	if p.OffsetStore != nil {
+		return p.load(ctx)
+	}
+
+	var err error
+	p.current, err = p.state.Load().handler.ResourceVersion(ctx, p.resource)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+
+	if p.OnVersionRead != nil {
+		p.OnVersionRead(p.current)
+	}
+
+	unmarshal := resource.UnmarshalOffset
+	if p.ResumeOffset != nil {
+		unmarshal = p.ResumeOffset
 	}
 
-	offset, err = resource.UnmarshalOffset(p.current)
+	offset, err := unmarshal(p.current)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
 	logging.Log(
@@ -184,104 +943,585 @@ func (p *Projector) open(ctx context.Context) (Cursor, error) {
 		offset,
 	)
 
-	return p.Stream.Open(ctx, offset, types)
+	return offset, nil
+}
+
+// checkFirst checks that the event at offset has not been truncated from the
+// stream, if the stream is a BoundsStream.
+//
+// It allows the projector to fail before opening the cursor, rather than when
+// the cursor attempts to read the missing event.
+func (p *Projector) checkFirst(ctx context.Context, offset uint64) error {
+	s, ok := p.Stream.(BoundsStream)
+	if !ok {
+		return nil
+	}
+
+	first, _, err := s.Bounds(ctx)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if offset < first {
+		return fmt.Errorf(
+			"the projection's offset (%d) precedes the first available event on the stream (%d), the events in between have been truncated",
+			offset,
+			first,
+		)
+	}
+
+	return nil
+}
+
+// checkHead checks that offset is not beyond the head of the stream, if the
+// stream is able to report its head.
+//
+// An offset beyond the head usually indicates that the stream has been reset
+// or replaced without also resetting the projection.
+func (p *Projector) checkHead(ctx context.Context, offset uint64) error {
+	head, ok, err := p.head(ctx)
+	if !ok || err != nil {
+		return err
+	}
+
+	if offset > head {
+		if p.FailBeyondHead {
+			return fmt.Errorf(
+				"the projection's offset (%d) is beyond the head of the stream (%d)",
+				offset,
+				head,
+			)
+		}
+
+		logging.Log(
+			p.Logger,
+			"[%s %s@%d] the projection's offset is beyond the head of the stream (%d), waiting for events",
+			p.name,
+			p.resource,
+			offset,
+			head,
+		)
+	}
+
+	p.trackCatchUp(offset, head)
+
+	return nil
+}
+
+// head returns the offset of the stream's head.
+//
+// It uses Head() if the stream is a HeadStream, otherwise the next offset
+// reported by Bounds() if it is a BoundsStream. ok is false if the stream
+// implements neither interface, or if both report errors.ErrUnsupported.
+func (p *Projector) head(ctx context.Context) (offset uint64, ok bool, err error) {
+	if s, ok := p.Stream.(HeadStream); ok {
+		offset, _, err = s.Head(ctx)
+		if !errors.Is(err, errors.ErrUnsupported) {
+			return offset, true, err
+		}
+	}
+
+	if s, ok := p.Stream.(BoundsStream); ok {
+		_, offset, err = s.Bounds(ctx)
+		if !errors.Is(err, errors.ErrUnsupported) {
+			return offset, true, err
+		}
+	}
+
+	return 0, false, nil
 }
 
 // consumeNext waits for the next message on the stream then applies it to the
 // projection.
-func (p *Projector) consumeNext(ctx context.Context, cur Cursor) (bool, error) {
+func (p *Projector) consumeNext(ctx context.Context, cur Cursor, st *handlerState) (bool, error) {
 	env, err := cur.Next(ctx)
 	if err != nil {
 		return false, err
 	}
 
+	if err := p.checkOrder(env); err != nil {
+		return false, err
+	}
+
+	p.reached(env.Offset)
+
+	if !st.consumes(env) {
+		p.logReopen(env.Offset)
+		return false, nil
+	}
+
+	return p.handle(ctx, st.handler, env)
+}
+
+// checkOrder returns an *OrderingError if the events in envs are not in
+// strictly increasing offset order, starting from the projector's current
+// position.
+//
+// It always returns nil if p.StrictOrdering is false.
+func (p *Projector) checkOrder(envs ...Envelope) error {
+	if !p.StrictOrdering {
+		return nil
+	}
+
+	expected := p.position.Load()
+
+	for _, env := range envs {
+		if env.Synthetic {
+			continue
+		}
+
+		if env.Offset < expected {
+			return &OrderingError{
+				Offset:   env.Offset,
+				Expected: expected,
+			}
+		}
+
+		expected = env.Offset + 1
+	}
+
+	return nil
+}
+
+// handle applies the event in env to the projection using the handler h.
+//
+// It returns false if the event is not applied due to an OCC conflict.
+func (p *Projector) handle(
+	ctx context.Context,
+	h dogma.ProjectionMessageHandler,
+	env Envelope,
+) (bool, error) {
 	if p.next == nil {
 		p.next = make([]byte, 8)
 	}
 
 	resource.MarshalOffsetInto(p.next, env.Offset+1)
 
-	var hint time.Duration
-	explainpanic.UnexpectedMessage(
-		p.Handler,
-		"TimeoutHint",
+	release, err := p.acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	gctx, stop := p.graceful(ctx)
+	defer stop()
+
+	var ok bool
+
+	timeout, err := p.timeout(h, env)
+	if err == nil {
+		hctx, cancel := context.WithTimeoutCause(
+			withEvent(gctx, p.name, env.Offset),
+			timeout,
+			ErrHandleTimeout,
+		)
+		defer cancel()
+
+		ok, err = p.handleEvent(hctx, h, env)
+	}
+
+	if err != nil {
+		err = &HandleError{env.Offset, err}
+
+		if ctx.Err() != nil || p.OnHandlerError == nil {
+			p.Metrics.handled(ctx, statusError, 1)
+			return false, err
+		}
+
+		if p.OnHandlerError(env, err) != SkipEvent {
+			p.Metrics.handled(ctx, statusError, 1)
+			return false, err
+		}
+
+		p.Metrics.handled(ctx, statusSkipped, 1)
+
+		logging.Log(
+			p.Logger,
+			"[%s %s@%d] skipping %T event: %s",
+			p.name,
+			p.resource,
+			env.Offset,
+			env.Message,
+			err,
+		)
+
+		// The projection's version is not advanced, the next event to be
+		// applied successfully moves past the skipped event.
+		return true, nil
+	}
+
+	if ok {
+		if !env.Synthetic {
+			if err := p.save(gctx, env.Offset+1); err != nil {
+				return false, err
+			}
+
+			p.advance(1, env.Offset+1)
+			p.reached(env.Offset + 1)
+			p.Metrics.lagged(ctx, env.RecordedAt)
+		}
+
+		p.Metrics.handled(ctx, statusHandled, 1)
+		return true, nil
+	}
+
+	p.Metrics.handled(ctx, statusConflict, 1)
+
+	if err := p.conflict(env.Offset); err != nil {
+		return false, err
+	}
+
+	logging.Log(
+		p.Logger,
+		"[%s %s@%d] an optimisitic concurrency conflict occurred, restarting the consumer",
+		p.name,
+		p.resource,
+		env.Offset,
+	)
+
+	return false, nil
+}
+
+// conflict records that an optimistic concurrency conflict occurred while
+// handling the event (or batch of events) at the given offset.
+//
+// It returns a *ConflictError if conflicts have prevented the projection from
+// advancing for longer than p.ConflictTimeout.
+func (p *Projector) conflict(offset uint64) error {
+	now := p.clock().Now()
+
+	if p.occSince.IsZero() {
+		p.occSince = now
+		return nil
+	}
+
+	elapsed := now.Sub(p.occSince)
+
+	if p.ConflictTimeout > 0 && elapsed > p.ConflictTimeout {
+		return &ConflictError{
+			Offset:  offset,
+			Elapsed: elapsed,
+		}
+	}
+
+	return nil
+}
+
+// graceful returns a context for handling an event that is canceled
+// p.ShutdownGrace after ctx is canceled, rather than immediately.
+//
+// stop must be called once the event has been handled.
+func (p *Projector) graceful(ctx context.Context) (_ context.Context, stop func()) {
+	if p.ShutdownGrace <= 0 {
+		return ctx, func() {}
+	}
+
+	gctx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+		}
+
+		t := time.NewTimer(p.ShutdownGrace)
+		defer t.Stop()
+
+		select {
+		case <-done:
+		case <-t.C:
+			cancel(context.Cause(ctx))
+		}
+	}()
+
+	return gctx, func() {
+		close(done)
+		cancel(nil)
+	}
+}
+
+// dryRun reports that the event in env would have been passed to the handler.
+func (p *Projector) dryRun(env Envelope) {
+	logging.Log(
+		p.Logger,
+		"[%s %s@%d] dry run: %T event would be handled",
+		p.name,
+		p.resource,
+		env.Offset,
 		env.Message,
-		func() {
-			hint = p.Handler.TimeoutHint(env.Message)
-		},
 	)
 
-	ctx, cancel := linger.ContextWithTimeout(
+	if p.OnDryRun != nil {
+		p.OnDryRun(env)
+	}
+}
+
+// handleEvent calls the handler's HandleEvent() method within a span,
+// recovering from panics if p.RecoverHandlerPanics is true.
+func (p *Projector) handleEvent(
+	ctx context.Context,
+	h dogma.ProjectionMessageHandler,
+	env Envelope,
+) (ok bool, err error) {
+	ctx, span := tracing.Start(
 		ctx,
-		hint,
-		p.DefaultTimeout,
-		DefaultTimeout,
+		p.Tracer,
+		"aperture.handle",
+		tracing.HandlerName.String(p.name),
+		tracing.HandlerKey.String(p.key),
+		tracing.StreamID.String(p.Stream.ID()),
+		tracing.StreamOffset.Int64(int64(env.Offset)),
+		tracing.MessageType.String(message.TypeOf(env.Message).String()),
 	)
-	defer cancel()
+	defer func() {
+		tracing.EndHandle(span, env.Offset, ok, err)
+	}()
+
+	defer p.recoverPanic(&err, "HandleEvent", env)
 
-	var ok bool
 	explainpanic.UnexpectedMessage(
-		p.Handler,
+		h,
 		"HandleEvent",
 		env.Message,
 		func() {
-			ok, err = p.Handler.HandleEvent(
+			p.handling.Store(true)
+			defer p.handling.Store(false)
+
+			// A synthetic event does not advance the projection's version.
+			next := p.next
+			if env.Synthetic {
+				next = p.current
+			}
+
+			ok, err = h.HandleEvent(
 				ctx,
 				p.resource,
 				p.current,
-				p.next,
-				eventScope{
-					resource:   p.resource,
-					offset:     env.Offset,
-					handler:    p.name,
-					recordedAt: env.RecordedAt,
-					logger:     p.Logger,
-				},
+				next,
+				p.eventScope(ctx, env),
 				env.Message,
 			)
 		},
 	)
-	if err != nil {
-		return false, err
-	}
 
-	if ok {
-		// keep swapping between the two buffers to avoid repeat allocations
-		p.current, p.next = p.next, p.current
-		return true, nil
+	return ok, err
+}
+
+// advance makes the next version the current version after n events have been
+// applied successfully. next is the offset of the next event to be consumed.
+func (p *Projector) advance(n int, next uint64) {
+	p.handled.Add(uint64(n))
+	p.position.Store(next)
+	p.occSince = time.Time{}
+
+	if p.OnVersionAdvance != nil {
+		p.OnVersionAdvance(p.current, p.next)
 	}
 
-	logging.Log(
-		p.Logger,
-		"[%s %s@%d] an optimisitic concurrency conflict occurred, restarting the consumer",
-		p.name,
-		p.resource,
-		env.Offset,
+	// keep swapping between the two buffers to avoid repeat allocations
+	p.current, p.next = p.next, p.current
+}
+
+// timeout returns the timeout to use when handling the event in env.
+//
+// It returns a *PanicError if the handler's TimeoutHint() method panics and
+// p.RecoverHandlerPanics is true.
+func (p *Projector) timeout(h dogma.ProjectionMessageHandler, env Envelope) (_ time.Duration, err error) {
+	defer p.recoverPanic(&err, "TimeoutHint", env)
+
+	var hint time.Duration
+	explainpanic.UnexpectedMessage(
+		h,
+		"TimeoutHint",
+		env.Message,
+		func() {
+			hint = h.TimeoutHint(env.Message)
+		},
 	)
 
-	return false, nil
+	return linger.MustCoalesce(
+		hint,
+		p.defaultTimeout(),
+		DefaultTimeout,
+	), nil
 }
 
-// compact calls p.Handler.Compact() with a timeout as per p.CompactionTimeout.
+// recoverPanic converts a panic that occurred within the given method of the
+// handler into a *PanicError that is assigned to *err, if
+// p.RecoverHandlerPanics is true. env is the event being handled, if any.
+//
+// It must be called directly by a deferred statement.
+func (p *Projector) recoverPanic(err *error, method string, env Envelope) {
+	if !p.RecoverHandlerPanics {
+		return
+	}
+
+	if v := recover(); v != nil {
+		pe := &PanicError{
+			Value:  v,
+			Stack:  debug.Stack(),
+			Method: method,
+			Offset: env.Offset,
+		}
+
+		if env.Message != nil {
+			pe.MessageType = message.TypeOf(env.Message)
+		}
+
+		*err = pe
+	}
+}
+
+// eventScope returns the scope to use when handling the event in env.
+func (p *Projector) eventScope(ctx context.Context, env Envelope) eventScope {
+	id := env.MessageID
+	if id == "" && !env.Synthetic {
+		id = MessageID(p.Stream.ID(), env.Offset)
+	}
+
+	return eventScope{
+		resource:   p.resource,
+		offset:     env.Offset,
+		messageID:  id,
+		handler:    p.name,
+		recordedAt: env.RecordedAt,
+		ctx:        ctx,
+		logger:     p.Logger,
+		slog:       p.StructuredLogger,
+		format:     p.LogFormat,
+	}
+}
+
+// compactLoop compacts the projection at p.CompactionInterval until ctx is
+// canceled or an error occurs. It returns nil if compaction is disabled.
+func (p *Projector) compactLoop(ctx context.Context) error {
+	first := true
+
+	for {
+		if p.compactionInterval() < 0 {
+			// Compaction has been disabled.
+			return nil
+		}
+
+		if err := p.waitForCompaction(ctx, first); err != nil {
+			return err
+		}
+
+		first = false
+
+		if err := p.compact(ctx); err != nil {
+			return fmt.Errorf(
+				"unable to compact the '%s' projection: %w",
+				p.name,
+				err,
+			)
+		}
+	}
+}
+
+// waitForCompaction blocks until it is time to compact the projection, or
+// until ctx is canceled.
+//
+// first is true if the projection has not yet been compacted by this run.
+func (p *Projector) waitForCompaction(ctx context.Context, first bool) error {
+	if p.CompactionClock != nil {
+		last, err := p.CompactionClock.LastCompactedAt(ctx)
+		if err != nil {
+			return fmt.Errorf(
+				"unable to compact the '%s' projection: unable to load the last compaction time: %w",
+				p.name,
+				err,
+			)
+		}
+
+		// If the projection has never been compacted last is the zero-value,
+		// and hence the compaction is due immediately.
+		d := last.Add(p.compactionDelay()).Sub(p.clock().Now())
+		return sleep(ctx, p.clock(), d)
+	}
+
+	if first && !p.DeferInitialCompaction {
+		return nil
+	}
+
+	return sleep(ctx, p.clock(), p.compactionDelay())
+}
+
+// clock returns the clock used to schedule compaction.
+func (p *Projector) clock() Clock {
+	if p.Clock == nil {
+		return SystemClock
+	}
+
+	return p.Clock
+}
+
+// compactionDelay returns the delay between compactions, randomized by up to
+// p.CompactionJitter in either direction.
+func (p *Projector) compactionDelay() time.Duration {
+	d := linger.MustCoalesce(p.compactionInterval(), DefaultCompactionInterval)
+
+	if p.CompactionJitter <= 0 {
+		return d
+	}
+
+	return max(0, linger.Rand(d-p.CompactionJitter, d+p.CompactionJitter))
+}
+
+// compact calls p.Handler.Compact() with a timeout as per p.CompactionTimeout,
+// if it is non-negative.
 //
 // It returns an error if ctx is canceled or some unexpected error occurs. It is
 // *not* an error if compaction times out. It is simply retried again at the
 // next interval.
 func (p *Projector) compact(ctx context.Context) error {
-	ctx, cancel := linger.ContextWithTimeout(
-		ctx,
-		p.CompactionTimeout,
-		DefaultCompactionTimeout,
+	release, err := p.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// The compaction duration is measured using the system clock, as is the
+	// compaction timeout, whereas start is used to schedule compaction.
+	began := time.Now()
+	start := p.clock().Now()
+
+	var (
+		cctx   context.Context
+		cancel context.CancelFunc
 	)
+
+	timeout := p.compactionTimeout()
+
+	if timeout < 0 {
+		cctx, cancel = context.WithCancel(ctx)
+	} else {
+		cctx, cancel = linger.ContextWithTimeout(
+			ctx,
+			timeout,
+			DefaultCompactionTimeout,
+		)
+	}
 	defer cancel()
 
-	if err := p.Handler.Compact(
-		ctx,
-		compactScope{
-			handler: p.name,
-			logger:  p.Logger,
-		},
-	); err != nil {
+	scope := compactScope{
+		handler:  p.name,
+		resource: p.resource,
+		ctx:      cctx,
+		logger:   p.Logger,
+		slog:     p.StructuredLogger,
+		format:   p.LogFormat,
+		clock:    p.clock(),
+	}
+
+	err = p.compactHandler(cctx, scope)
+	p.Metrics.compacted(ctx, time.Since(began), err == context.DeadlineExceeded)
+
+	if err != nil {
 		if err != context.DeadlineExceeded {
 			// The error was something other than a timeout of the compaction
 			// process itself.
@@ -290,13 +1530,40 @@ func (p *Projector) compact(ctx context.Context) error {
 
 		// Otherwise, the compaction timed out, but this is allowed. Log about
 		// it but continue as normal.
-		logging.Log(
-			p.Logger,
-			"[%s compact] %s",
-			p.name,
-			err,
-		)
+		scope.Log("%s", err)
+	}
+
+	if p.CompactionClock != nil {
+		// The compaction time is recorded even if the compaction timed out,
+		// otherwise it would be retried immediately.
+		if err := p.CompactionClock.RecordCompaction(ctx, start); err != nil {
+			return fmt.Errorf("unable to record the compaction time: %w", err)
+		}
 	}
 
 	return nil
 }
+
+// compactHandler calls the handler's Compact() method, recovering from panics
+// if p.RecoverHandlerPanics is true.
+func (p *Projector) compactHandler(ctx context.Context, scope compactScope) (err error) {
+	defer p.recoverPanic(&err, "Compact", Envelope{})
+	return p.state.Load().handler.Compact(ctx, scope)
+}
+
+// acquire obtains exclusive access to the projection if p.SerializeCompaction
+// is true. It blocks until access is granted or ctx is canceled.
+//
+// release must be called to relinquish access.
+func (p *Projector) acquire(ctx context.Context) (release func(), err error) {
+	if p.sem == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case p.sem <- struct{}{}:
+		return func() { <-p.sem }, nil
+	}
+}
@@ -1,11 +1,20 @@
 package ordered_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	. "github.com/dogmatiq/aperture/aperturetest"
 	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/aperture/ordered/resource"
+	"github.com/dogmatiq/configkit"
+	"github.com/dogmatiq/configkit/message"
 	"github.com/dogmatiq/dodeca/logging"
 	"github.com/dogmatiq/dogma"
 	. "github.com/dogmatiq/dogma/fixtures"
@@ -154,6 +163,44 @@ var _ = Describe("type Projector", func() {
 			Expect(err).To(Equal(context.Canceled))
 		})
 
+		It("cancels the handler's context with ErrHandleTimeout when the timeout elapses", func() {
+			handler.TimeoutHintFunc = func(dogma.Message) time.Duration {
+				return 10 * time.Millisecond
+			}
+
+			handler.HandleEventFunc = func(
+				ctx context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				_ dogma.Message,
+			) (bool, error) {
+				<-ctx.Done()
+				Expect(ctx.Err()).To(Equal(context.DeadlineExceeded))
+				Expect(context.Cause(ctx)).To(Equal(ErrHandleTimeout))
+				return false, context.Cause(ctx)
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(MatchError(ErrHandleTimeout))
+		})
+
+		It("does not use ErrHandleTimeout as the cause when the projector is stopped", func() {
+			handler.HandleEventFunc = func(
+				ctx context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				_ dogma.Message,
+			) (bool, error) {
+				cancel()
+				<-ctx.Done()
+				Expect(context.Cause(ctx)).To(Equal(context.Canceled))
+				return false, ctx.Err()
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+		})
+
 		It("returns an error if the handler returns an error", func() {
 			handler.HandleEventFunc = func(
 				ctx context.Context,
@@ -170,76 +217,1079 @@ var _ = Describe("type Projector", func() {
 			))
 		})
 
-		It("returns an error if the handler returns an error while compacting", func() {
+		It("returns the handler's error if the context is canceled at the same time", func() {
+			handler.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				cancel()
+				return false, errors.New("<error>")
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(MatchError(
+				"unable to consume from '<id>' for the '<proj>' projection: <error>",
+			))
+		})
+
+		It("returns a HandleError if the handler returns an error", func() {
+			handler.HandleEventFunc = func(
+				ctx context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				_ dogma.Message,
+			) (bool, error) {
+				return false, errors.New("<error>")
+			}
+
+			err := proj.Run(context.Background())
+
+			var handleErr *HandleError
+			Expect(errors.As(err, &handleErr)).To(BeTrue())
+			Expect(handleErr.Offset).To(BeNumerically("==", 0))
+			Expect(handleErr.Err).To(MatchError("<error>"))
+
+			var openErr *OpenError
+			Expect(errors.As(err, &openErr)).To(BeFalse())
+		})
+
+		It("does not read the resource version again if the context is canceled after a conflict", func() {
+			var reads int32
+			handler.ResourceVersionFunc = func(context.Context, []byte) ([]byte, error) {
+				atomic.AddInt32(&reads, 1)
+				return nil, nil
+			}
+
+			handler.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				cancel()
+				return false, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+			Expect(atomic.LoadInt32(&reads)).To(BeNumerically("==", 1))
+		})
+
+		Context("when the projection's offset is beyond the head of the stream", func() {
+			BeforeEach(func() {
+				handler.ResourceVersionFunc = func(context.Context, []byte) ([]byte, error) {
+					return resource.MarshalOffset(10), nil
+				}
+			})
+
+			It("logs a warning and waits for events", func() {
+				handler.HandleEventFunc = func(
+					_ context.Context,
+					_, _, _ []byte,
+					_ dogma.ProjectionEventScope,
+					m dogma.Message,
+				) (bool, error) {
+					Expect(m).To(Equal(MessageA1))
+					cancel()
+					return true, nil
+				}
+
+				go func() {
+					defer GinkgoRecover()
+
+					Eventually(logger.Messages).Should(ContainElement(
+						logging.BufferedLogMessage{
+							Message: "[<proj> <id>@10] the projection's offset is beyond the head of the stream (6), waiting for events",
+						},
+					))
+
+					stream.Append(now, MessageB1, MessageB1, MessageB1, MessageB1, MessageA1)
+				}()
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+			})
+
+			It("returns an error if FailBeyondHead is true", func() {
+				proj.FailBeyondHead = true
+
+				err := proj.Run(ctx)
+				Expect(err).To(MatchError(
+					"unable to consume from '<id>' for the '<proj>' projection: the projection's offset (10) is beyond the head of the stream (6)",
+				))
+
+				var openErr *OpenError
+				Expect(errors.As(err, &openErr)).To(BeTrue())
+			})
+
+			It("uses the bounds of the stream if it is not a HeadStream", func() {
+				proj.Stream = boundsOnlyStream{stream}
+				proj.FailBeyondHead = true
+
+				err := proj.Run(ctx)
+				Expect(err).To(MatchError(
+					"unable to consume from '<id>' for the '<proj>' projection: the projection's offset (10) is beyond the head of the stream (6)",
+				))
+			})
+		})
+
+		It("returns an error if the projection's offset has been truncated from the stream", func() {
+			stream.Truncate(3)
+
+			handler.ResourceVersionFunc = func(context.Context, []byte) ([]byte, error) {
+				return resource.MarshalOffset(1), nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(MatchError(
+				"unable to consume from '<id>' for the '<proj>' projection: the projection's offset (1) precedes the first available event on the stream (3), the events in between have been truncated",
+			))
+
+			var openErr *OpenError
+			Expect(errors.As(err, &openErr)).To(BeTrue())
+		})
+
+		It("returns nil once every event on a sealed stream is applied if StopWhenSealed is true", func() {
+			proj.StopWhenSealed = true
+			stream.Seal()
+
+			var messages []dogma.Message
+			handler.HandleEventFunc = func(
+				_ context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				m dogma.Message,
+			) (bool, error) {
+				messages = append(messages, m)
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(messages).To(Equal([]dogma.Message{MessageA1, MessageA2, MessageA3}))
+			Expect(logger.Messages()).To(ContainElement(
+				logging.BufferedLogMessage{
+					Message: "[<proj> <id>] the stream is sealed, the projection is complete",
+				},
+			))
+		})
+
+		It("returns an *OrderingError if StrictOrdering is true and the stream returns a duplicate offset", func() {
+			events := make(chan Envelope, 3)
+			events <- Envelope{Offset: 0, RecordedAt: now, Message: MessageA1}
+			events <- Envelope{Offset: 1, RecordedAt: now, Message: MessageA2}
+			events <- Envelope{Offset: 1, RecordedAt: now, Message: MessageA2}
+
+			proj.Stream = &ChannelStream{
+				StreamID: "<id>",
+				Events:   events,
+			}
+			proj.StrictOrdering = true
+
+			var offsets []uint64
+			handler.HandleEventFunc = func(
+				ctx context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				_ dogma.Message,
+			) (bool, error) {
+				offset, _ := OffsetFromContext(ctx)
+				offsets = append(offsets, offset)
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(MatchError(
+				"unable to consume from '<id>' for the '<proj>' projection: stream returned out-of-order offset 1, expected 2 or greater",
+			))
+
+			var orderErr *OrderingError
+			Expect(errors.As(err, &orderErr)).To(BeTrue())
+			Expect(offsets).To(Equal([]uint64{0, 1}))
+		})
+
+		It("compacts the projection when it starts", func() {
 			handler.CompactFunc = func(
 				context.Context,
 				dogma.ProjectionCompactScope,
 			) error {
-				return errors.New("<error>")
+				cancel()
+				return nil
 			}
 
 			err := proj.Run(ctx)
-			Expect(err).To(MatchError(
-				"unable to compact the '<proj>' projection: <error>",
-			))
+			Expect(err).To(Equal(context.Canceled))
 		})
 
-		It("does not return an error if the compaction exceeds the deadline", func() {
+		It("waits for the compaction interval before compacting if DeferInitialCompaction is true", func() {
+			proj.DeferInitialCompaction = true
+			proj.CompactionInterval = 50 * time.Millisecond
+
+			start := time.Now()
 			handler.CompactFunc = func(
 				context.Context,
 				dogma.ProjectionCompactScope,
 			) error {
-				defer cancel()
-				return context.DeadlineExceeded
+				Expect(time.Since(start)).To(BeNumerically(">=", proj.CompactionInterval))
+				cancel()
+				return nil
 			}
 
 			err := proj.Run(ctx)
 			Expect(err).To(Equal(context.Canceled))
+		})
 
-			Expect(logger.Messages()).To(ContainElement(
-				logging.BufferedLogMessage{
-					Message: "[<proj> compact] context deadline exceeded",
-				},
+		It("randomizes the delay between compactions by up to CompactionJitter", func() {
+			proj.DeferInitialCompaction = true
+			proj.CompactionInterval = 100 * time.Millisecond
+			proj.CompactionJitter = 50 * time.Millisecond
+
+			start := time.Now()
+			handler.CompactFunc = func(
+				context.Context,
+				dogma.ProjectionCompactScope,
+			) error {
+				Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+				Expect(time.Since(start)).To(BeNumerically("<", 500*time.Millisecond))
+				cancel()
+				return nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+		})
+
+		It("returns if the context is canceled while waiting for a jittered compaction", func() {
+			proj.DeferInitialCompaction = true
+			proj.CompactionInterval = time.Hour
+			proj.CompactionJitter = 30 * time.Minute
+
+			handler.CompactFunc = func(
+				context.Context,
+				dogma.ProjectionCompactScope,
+			) error {
+				Fail("unexpected compaction")
+				return nil
+			}
+
+			go func() {
+				time.Sleep(20 * time.Millisecond)
+				cancel()
+			}()
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+		})
+
+		It("does not compact the projection if CompactionInterval is negative", func() {
+			proj.CompactionInterval = -1
+
+			handler.CompactFunc = func(
+				context.Context,
+				dogma.ProjectionCompactScope,
+			) error {
+				Fail("unexpected compaction")
+				return nil
+			}
+
+			handler.HandleEventFunc = func(
+				_ context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				m dogma.Message,
+			) (bool, error) {
+				if m == MessageA3 {
+					cancel()
+				}
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+		})
+
+		It("returns an error if the handler returns an error while compacting", func() {
+			handler.CompactFunc = func(
+				context.Context,
+				dogma.ProjectionCompactScope,
+			) error {
+				return errors.New("<error>")
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(MatchError(
+				"unable to compact the '<proj>' projection: <error>",
 			))
 		})
 
-		It("returns an error if the handler configuration is invalid", func() {
-			handler.ConfigureFunc = nil
-			err := proj.Run(ctx)
-			Expect(err).To(MatchError(
-				"*fixtures.ProjectionMessageHandler is configured without an identity, Identity() must be called exactly once within Configure()",
-			))
-		})
+		Context("when a compaction clock is provided", func() {
+			var clock *compactionClock
+
+			BeforeEach(func() {
+				clock = &compactionClock{}
+				proj.CompactionClock = clock
+				proj.CompactionInterval = 100 * time.Millisecond
+			})
+
+			It("compacts immediately if the projection has never been compacted", func() {
+				proj.DeferInitialCompaction = true
+
+				start := time.Now()
+				handler.CompactFunc = func(
+					context.Context,
+					dogma.ProjectionCompactScope,
+				) error {
+					Expect(time.Since(start)).To(BeNumerically("<", proj.CompactionInterval))
+					return nil
+				}
+
+				go func() {
+					defer cancel()
+					Eventually(clock.Last).ShouldNot(BeZero())
+				}()
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+				Expect(clock.Last()).To(BeTemporally("~", start, 50*time.Millisecond))
+			})
+
+			It("waits until the interval has elapsed since the last compaction", func() {
+				last := time.Now().Add(-50 * time.Millisecond)
+				clock.last = last
+
+				handler.CompactFunc = func(
+					context.Context,
+					dogma.ProjectionCompactScope,
+				) error {
+					Expect(time.Now()).To(BeTemporally(">=", last.Add(proj.CompactionInterval)))
+					cancel()
+					return nil
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+			})
+
+			It("returns an error if the last compaction time can not be loaded", func() {
+				clock.loadErr = errors.New("<error>")
+
+				err := proj.Run(ctx)
+				Expect(err).To(MatchError(
+					"unable to compact the '<proj>' projection: unable to load the last compaction time: <error>",
+				))
+			})
+
+			It("returns an error if the compaction time can not be recorded", func() {
+				clock.recordErr = errors.New("<error>")
+
+				err := proj.Run(ctx)
+				Expect(err).To(MatchError(
+					"unable to compact the '<proj>' projection: unable to record the compaction time: <error>",
+				))
+			})
+		})
+
+		Context("when a clock is provided", func() {
+			var clock *fakeClock
+
+			BeforeEach(func() {
+				clock = &fakeClock{
+					now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+				}
+				proj.Clock = clock
+				proj.CompactionInterval = time.Hour
+				proj.DeferInitialCompaction = true
+			})
+
+			It("uses the clock to schedule compaction", func() {
+				expect := clock.Now().Add(time.Hour)
+
+				handler.CompactFunc = func(
+					_ context.Context,
+					s dogma.ProjectionCompactScope,
+				) error {
+					Expect(s.Now()).To(Equal(expect))
+					cancel()
+					return nil
+				}
+
+				go func() {
+					defer GinkgoRecover()
+					Eventually(clock.Waiting).Should(BeTrue())
+					clock.Advance(time.Hour)
+				}()
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+			})
+		})
+
+		It("does not return an error if the compaction exceeds the deadline", func() {
+			handler.CompactFunc = func(
+				context.Context,
+				dogma.ProjectionCompactScope,
+			) error {
+				defer cancel()
+				return context.DeadlineExceeded
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+
+			Expect(logger.Messages()).To(ContainElement(
+				logging.BufferedLogMessage{
+					Message: "[<proj> compact] context deadline exceeded",
+				},
+			))
+		})
+
+		It("does not apply a deadline to the compaction if CompactionTimeout is negative", func() {
+			proj.CompactionTimeout = -1
+
+			// Use a context without a deadline of its own.
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			handler.CompactFunc = func(
+				ctx context.Context,
+				_ dogma.ProjectionCompactScope,
+			) error {
+				defer cancel()
+
+				_, ok := ctx.Deadline()
+				Expect(ok).To(BeFalse())
+
+				return nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+		})
+
+		It("does not handle events while compacting when SerializeCompaction is true", func() {
+			proj.SerializeCompaction = true
+
+			var compacting, handling int32
+
+			handler.CompactFunc = func(
+				context.Context,
+				dogma.ProjectionCompactScope,
+			) error {
+				atomic.StoreInt32(&compacting, 1)
+				defer atomic.StoreInt32(&compacting, 0)
+
+				Expect(atomic.LoadInt32(&handling)).To(BeZero())
+				time.Sleep(20 * time.Millisecond)
+
+				return nil
+			}
+
+			var count int
+			handler.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				atomic.StoreInt32(&handling, 1)
+				defer atomic.StoreInt32(&handling, 0)
+
+				Expect(atomic.LoadInt32(&compacting)).To(BeZero())
+				time.Sleep(20 * time.Millisecond)
+
+				count++
+				if count == 3 {
+					cancel()
+				}
+
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+		})
+
+		It("returns an error if the handler configuration is invalid", func() {
+			handler.ConfigureFunc = nil
+			err := proj.Run(ctx)
+			Expect(err).To(MatchError(
+				"*fixtures.ProjectionMessageHandler is configured without an identity, Identity() must be called exactly once within Configure()",
+			))
+		})
+
+		It("returns if the context is canceled", func() {
+			done := make(chan error)
+			go func() {
+				done <- proj.Run(ctx)
+			}()
+
+			cancel()
+			err := <-done
+			Expect(err).To(Equal(context.Canceled))
+		})
+
+		It("delays startup by no more than the startup jitter", func() {
+			proj.StartupJitter = 50 * time.Millisecond
+
+			start := time.Now()
+			handler.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+				cancel()
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+		})
+
+		It("returns if the context is canceled during the startup delay", func() {
+			proj.StartupJitter = time.Hour
+
+			handler.ResourceVersionFunc = func(
+				context.Context,
+				[]byte,
+			) ([]byte, error) {
+				Fail("unexpected call to ResourceVersion()")
+				return nil, nil
+			}
+
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				cancel()
+			}()
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+		})
+
+		Context("when ShutdownGrace is set", func() {
+			BeforeEach(func() {
+				proj.ShutdownGrace = 100 * time.Millisecond
+			})
+
+			It("allows the current event to finish handling after the context is canceled", func() {
+				var handled []dogma.Message
+				handler.HandleEventFunc = func(
+					hctx context.Context,
+					_, _, _ []byte,
+					_ dogma.ProjectionEventScope,
+					m dogma.Message,
+				) (bool, error) {
+					cancel()
+					time.Sleep(20 * time.Millisecond)
+					Expect(hctx.Err()).ShouldNot(HaveOccurred())
+
+					handled = append(handled, m)
+					return true, nil
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+				Expect(handled).To(Equal([]dogma.Message{MessageA1}))
+
+				offset, ok := proj.Offset()
+				Expect(ok).To(BeTrue())
+				Expect(offset).To(BeEquivalentTo(0))
+			})
+
+			It("cancels the handler's context once the grace period elapses", func() {
+				handler.HandleEventFunc = func(
+					hctx context.Context,
+					_, _, _ []byte,
+					_ dogma.ProjectionEventScope,
+					_ dogma.Message,
+				) (bool, error) {
+					start := time.Now()
+					cancel()
+					<-hctx.Done()
+
+					Expect(time.Since(start)).To(BeNumerically(">=", 100*time.Millisecond))
+					return false, hctx.Err()
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(MatchError(context.Canceled))
+			})
+		})
+
+		Context("when RecoverHandlerPanics is true", func() {
+			BeforeEach(func() {
+				proj.RecoverHandlerPanics = true
+			})
+
+			It("returns a PanicError if the handler panics", func() {
+				handler.HandleEventFunc = func(
+					context.Context,
+					[]byte, []byte, []byte,
+					dogma.ProjectionEventScope,
+					dogma.Message,
+				) (bool, error) {
+					panic("<panic>")
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(MatchError(
+					"unable to consume from '<id>' for the '<proj>' projection: handler panicked while handling fixtures.MessageA event at offset 0: <panic>",
+				))
+
+				var panicErr *PanicError
+				Expect(errors.As(err, &panicErr)).To(BeTrue())
+				Expect(panicErr.Value).To(Equal("<panic>"))
+				Expect(panicErr.Method).To(Equal("HandleEvent"))
+				Expect(panicErr.Offset).To(BeNumerically("==", 0))
+				Expect(panicErr.MessageType).To(Equal(message.TypeOf(MessageA{})))
+				Expect(panicErr.Stack).NotTo(BeEmpty())
+
+				var handleErr *HandleError
+				Expect(errors.As(err, &handleErr)).To(BeTrue())
+			})
+
+			It("preserves the description of UnexpectedMessage panics", func() {
+				handler.HandleEventFunc = func(
+					context.Context,
+					[]byte, []byte, []byte,
+					dogma.ProjectionEventScope,
+					dogma.Message,
+				) (bool, error) {
+					panic(dogma.UnexpectedMessage)
+				}
+
+				err := proj.Run(ctx)
+
+				var panicErr *PanicError
+				Expect(errors.As(err, &panicErr)).To(BeTrue())
+				Expect(panicErr.Value).To(Equal(
+					"*fixtures.ProjectionMessageHandler.HandleEvent() panicked due to an unexpected message of type fixtures.MessageA",
+				))
+			})
+
+			It("returns a PanicError if the handler panics while computing a timeout hint", func() {
+				handler.TimeoutHintFunc = func(dogma.Message) time.Duration {
+					panic(dogma.UnexpectedMessage)
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(MatchError(
+					"unable to consume from '<id>' for the '<proj>' projection: handler panicked while computing the timeout for fixtures.MessageA event at offset 0: *fixtures.ProjectionMessageHandler.TimeoutHint() panicked due to an unexpected message of type fixtures.MessageA",
+				))
+
+				var panicErr *PanicError
+				Expect(errors.As(err, &panicErr)).To(BeTrue())
+				Expect(panicErr.Method).To(Equal("TimeoutHint"))
+
+				var handleErr *HandleError
+				Expect(errors.As(err, &handleErr)).To(BeTrue())
+			})
+
+			It("returns a PanicError if the handler panics while compacting", func() {
+				handler.CompactFunc = func(context.Context, dogma.ProjectionCompactScope) error {
+					panic("<panic>")
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(MatchError(ContainSubstring(
+					"handler panicked while compacting the projection: <panic>",
+				)))
+
+				var panicErr *PanicError
+				Expect(errors.As(err, &panicErr)).To(BeTrue())
+				Expect(panicErr.Method).To(Equal("Compact"))
+				Expect(panicErr.Stack).NotTo(BeEmpty())
+			})
+
+			It("skips the event if OnHandlerError returns SkipEvent", func() {
+				handler.HandleEventFunc = func(
+					_ context.Context,
+					_, c, n []byte,
+					_ dogma.ProjectionEventScope,
+					m dogma.Message,
+				) (bool, error) {
+					if m == MessageA1 {
+						panic("<panic>")
+					}
+
+					Expect(m).To(Equal(MessageA2))
+					Expect(c).To(BeEmpty())
+					Expect(n).To(BeVersion(3))
+					cancel()
+					return true, nil
+				}
+
+				proj.OnHandlerError = func(env Envelope, err error) ErrorAction {
+					Expect(env.Offset).To(BeNumerically("==", 0))
+
+					var panicErr *PanicError
+					Expect(errors.As(err, &panicErr)).To(BeTrue())
+
+					return SkipEvent
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+
+				Expect(logger.Messages()).To(ContainElement(
+					logging.BufferedLogMessage{
+						Message: "[<proj> <id>@0] skipping fixtures.MessageA event: handler panicked while handling fixtures.MessageA event at offset 0: <panic>",
+					},
+				))
+			})
+		})
+
+		Context("when OnHandlerError is set", func() {
+			It("returns the error if OnHandlerError returns ReturnError", func() {
+				handler.HandleEventFunc = func(
+					context.Context,
+					[]byte, []byte, []byte,
+					dogma.ProjectionEventScope,
+					dogma.Message,
+				) (bool, error) {
+					return false, errors.New("<error>")
+				}
+
+				proj.OnHandlerError = func(Envelope, error) ErrorAction {
+					return ReturnError
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(MatchError(
+					"unable to consume from '<id>' for the '<proj>' projection: <error>",
+				))
+			})
+
+			It("skips events for which the handler returns an error", func() {
+				handler.HandleEventFunc = func(
+					_ context.Context,
+					_, _, _ []byte,
+					_ dogma.ProjectionEventScope,
+					m dogma.Message,
+				) (bool, error) {
+					if m == MessageA1 {
+						return false, errors.New("<error>")
+					}
+
+					Expect(m).To(Equal(MessageA2))
+					cancel()
+					return true, nil
+				}
+
+				proj.OnHandlerError = func(Envelope, error) ErrorAction {
+					return SkipEvent
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+			})
+		})
+
+		Context("when Backoff is set", func() {
+			var delays []uint
+
+			BeforeEach(func() {
+				delays = nil
+				proj.Backoff = func(_ error, n uint) time.Duration {
+					delays = append(delays, n)
+					return time.Millisecond
+				}
+			})
+
+			It("retries after an error occurs", func() {
+				calls := 0
+				handler.HandleEventFunc = func(
+					_ context.Context,
+					_, _, _ []byte,
+					_ dogma.ProjectionEventScope,
+					m dogma.Message,
+				) (bool, error) {
+					calls++
+					if calls == 1 {
+						return false, errors.New("<error>")
+					}
+
+					if m == MessageA2 {
+						cancel()
+					}
+
+					return true, nil
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+				Expect(delays).To(Equal([]uint{0}))
+				Expect(logger.Messages()).To(ContainElement(
+					logging.BufferedLogMessage{
+						Message: "[<proj> <id>] retrying in 1ms: unable to consume from '<id>' for the '<proj>' projection: <error>",
+					},
+				))
+			})
+
+			It("increases the delay for consecutive failures", func() {
+				proj.Backoff = func(_ error, n uint) time.Duration {
+					delays = append(delays, n)
+					if n == 2 {
+						cancel()
+					}
+					return time.Millisecond
+				}
+
+				handler.HandleEventFunc = func(
+					context.Context,
+					[]byte, []byte, []byte,
+					dogma.ProjectionEventScope,
+					dogma.Message,
+				) (bool, error) {
+					return false, errors.New("<error>")
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+				Expect(delays).To(Equal([]uint{0, 1, 2}))
+			})
+
+			It("resets the delay once an event is handled", func() {
+				calls := 0
+				handler.HandleEventFunc = func(
+					_ context.Context,
+					_, _, _ []byte,
+					_ dogma.ProjectionEventScope,
+					m dogma.Message,
+				) (bool, error) {
+					calls++
+					switch calls {
+					case 1, 3:
+						return false, errors.New("<error>")
+					case 4:
+						cancel()
+					}
+
+					return true, nil
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+				Expect(delays).To(Equal([]uint{0, 0}))
+			})
+
+			It("returns if the context is canceled while waiting to retry", func() {
+				proj.Backoff = func(error, uint) time.Duration {
+					cancel()
+					return time.Hour
+				}
+
+				handler.HandleEventFunc = func(
+					context.Context,
+					[]byte, []byte, []byte,
+					dogma.ProjectionEventScope,
+					dogma.Message,
+				) (bool, error) {
+					return false, errors.New("<error>")
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+			})
+
+			It("does not retry when the end of a sealed stream is reached", func() {
+				stream.Seal()
+
+				err := proj.Run(ctx)
+				Expect(err).To(MatchError(ErrStreamSealed))
+				Expect(delays).To(BeEmpty())
+			})
+		})
+
+		Context("when the stream is a ConsumerStream", func() {
+			var cs *consumerStream
+
+			BeforeEach(func() {
+				cs = &consumerStream{MemoryStream: stream}
+				proj.Stream = cs
+
+				handler.HandleEventFunc = func(
+					context.Context,
+					[]byte, []byte, []byte,
+					dogma.ProjectionEventScope,
+					dogma.Message,
+				) (bool, error) {
+					cancel()
+					return true, nil
+				}
+			})
+
+			It("identifies the consumer using the handler's key by default", func() {
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+				Expect(cs.consumerID).To(Equal("45804515-8b41-4d23-97b1-0cda5a0d782c"))
+			})
+
+			It("identifies the consumer using the ConsumerID field if it is set", func() {
+				proj.ConsumerID = "<consumer>"
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+				Expect(cs.consumerID).To(Equal("<consumer>"))
+			})
+		})
+
+		Context("when DryRun is true", func() {
+			BeforeEach(func() {
+				proj.DryRun = true
+
+				handler.HandleEventFunc = func(
+					context.Context,
+					[]byte, []byte, []byte,
+					dogma.ProjectionEventScope,
+					dogma.Message,
+				) (bool, error) {
+					Fail("unexpected call to HandleEvent()")
+					return false, nil
+				}
+
+				handler.CompactFunc = func(
+					context.Context,
+					dogma.ProjectionCompactScope,
+				) error {
+					Fail("unexpected call to Compact()")
+					return nil
+				}
+			})
+
+			It("passes the filtered events to the OnDryRun hook instead of the handler", func() {
+				var messages []dogma.Message
+				proj.OnDryRun = func(env Envelope) {
+					messages = append(messages, env.Message)
+
+					if len(messages) == 3 {
+						cancel()
+					}
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+				Expect(messages).To(Equal(
+					[]dogma.Message{
+						MessageA1,
+						MessageA2,
+						MessageA3,
+					},
+				))
+			})
+
+			It("logs each event that would be handled", func() {
+				proj.OnDryRun = func(env Envelope) {
+					cancel()
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+
+				Expect(logger.Messages()).To(ContainElement(
+					logging.BufferedLogMessage{
+						Message: "[<proj> <id>@0] dry run: fixtures.MessageA event would be handled",
+					},
+				))
+			})
+		})
+
+		Context("event scope", func() {
+			It("exposes the time that the event was recorded", func() {
+				handler.HandleEventFunc = func(
+					_ context.Context,
+					_, _, _ []byte,
+					s dogma.ProjectionEventScope,
+					_ dogma.Message,
+				) (bool, error) {
+					Expect(s.RecordedAt()).To(BeTemporally("==", now))
+					cancel()
+					return true, nil
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+			})
+
+			It("exposes a stable message ID", func() {
+				handler.HandleEventFunc = func(
+					_ context.Context,
+					_, _, _ []byte,
+					s dogma.ProjectionEventScope,
+					m dogma.Message,
+				) (bool, error) {
+					if m == MessageA2 {
+						Expect(s.(EventScope).MessageID()).To(Equal("<id>@2"))
+						cancel()
+					}
+					return true, nil
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+			})
+
+			It("logs messages to the logger", func() {
+				handler.HandleEventFunc = func(
+					_ context.Context,
+					_, _, _ []byte,
+					s dogma.ProjectionEventScope,
+					_ dogma.Message,
+				) (bool, error) {
+					s.Log("format %s", "<value>")
+					cancel()
+					return true, nil
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
 
-		It("returns if the context is canceled", func() {
-			done := make(chan error)
-			go func() {
-				done <- proj.Run(ctx)
-			}()
+				Expect(logger.Messages()).To(ContainElement(
+					logging.BufferedLogMessage{
+						Message: "[<proj> <id>@0] format <value>",
+					},
+				))
+			})
 
-			cancel()
-			err := <-done
-			Expect(err).To(Equal(context.Canceled))
-		})
+			It("logs messages to the structured logger if it is set", func() {
+				var buf bytes.Buffer
+				proj.StructuredLogger = slog.New(
+					slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+						ReplaceAttr: omitTime,
+					}),
+				)
 
-		Context("event scope", func() {
-			It("exposes the time that the event was recorded", func() {
 				handler.HandleEventFunc = func(
 					_ context.Context,
 					_, _, _ []byte,
 					s dogma.ProjectionEventScope,
 					_ dogma.Message,
 				) (bool, error) {
-					Expect(s.RecordedAt()).To(BeTemporally("==", now))
+					s.Log("format %s", "<value>")
 					cancel()
 					return true, nil
 				}
 
 				err := proj.Run(ctx)
 				Expect(err).To(Equal(context.Canceled))
+
+				Expect(buf.String()).To(Equal(
+					`{"level":"INFO","msg":"format <value>","handler":"<proj>","resource":"<id>","offset":0}` + "\n",
+				))
+				Expect(logger.Messages()).NotTo(ContainElement(
+					HaveField("Message", ContainSubstring("format <value>")),
+				))
 			})
 
-			It("logs messages to the logger", func() {
+			It("uses the projector's log format", func() {
+				proj.LogFormat = func(c LogContext) string {
+					Expect(c.Compact).To(BeFalse())
+					return fmt.Sprintf("%s/%s/%d:", c.Handler, c.Resource, c.Offset)
+				}
+
 				handler.HandleEventFunc = func(
 					_ context.Context,
 					_, _, _ []byte,
@@ -256,7 +1306,7 @@ var _ = Describe("type Projector", func() {
 
 				Expect(logger.Messages()).To(ContainElement(
 					logging.BufferedLogMessage{
-						Message: "[<proj> <id>@0] format <value>",
+						Message: "<proj>/<id>/0: format <value>",
 					},
 				))
 			})
@@ -283,6 +1333,56 @@ var _ = Describe("type Projector", func() {
 				))
 			})
 
+			It("logs messages to the structured logger if it is set", func() {
+				var buf bytes.Buffer
+				proj.StructuredLogger = slog.New(
+					slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+						ReplaceAttr: omitTime,
+					}),
+				)
+
+				handler.CompactFunc = func(
+					_ context.Context,
+					s dogma.ProjectionCompactScope,
+				) error {
+					s.Log("format %s", "<value>")
+					cancel()
+					return nil
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+
+				Expect(buf.String()).To(Equal(
+					`{"level":"INFO","msg":"format <value>","handler":"<proj>","resource":"<id>","compact":true}` + "\n",
+				))
+			})
+
+			It("uses the projector's log format", func() {
+				proj.LogFormat = func(c LogContext) string {
+					Expect(c.Compact).To(BeTrue())
+					return fmt.Sprintf("%s/%s/compact:", c.Handler, c.Resource)
+				}
+
+				handler.CompactFunc = func(
+					_ context.Context,
+					s dogma.ProjectionCompactScope,
+				) error {
+					s.Log("format %s", "<value>")
+					cancel()
+					return nil
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+
+				Expect(logger.Messages()).To(ContainElement(
+					logging.BufferedLogMessage{
+						Message: "<proj>/<id>/compact: format <value>",
+					},
+				))
+			})
+
 			It("exposes the current time", func() {
 				handler.CompactFunc = func(
 					_ context.Context,
@@ -339,33 +1439,303 @@ var _ = Describe("type Projector", func() {
 					_ dogma.Message,
 				) (bool, error) {
 					Expect(r).To(Equal([]byte("<id>")))
-					Expect(c).To(Equal([]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02}))
-					Expect(n).To(Equal([]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x04}))
+					Expect(c).To(BeVersion(3))
+					Expect(n).To(BeVersion(5))
+					cancel()
+					return true, nil
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+			})
+
+			It("passes the correct resource and versions to the handler when the resource does not exist", func() {
+				handler.ResourceVersionFunc = func(
+					_ context.Context,
+					res []byte,
+				) ([]byte, error) {
+					return nil, nil
+				}
+
+				handler.HandleEventFunc = func(
+					_ context.Context,
+					r, c, n []byte,
+					_ dogma.ProjectionEventScope,
+					_ dogma.Message,
+				) (bool, error) {
+					Expect(r).To(Equal([]byte("<id>")))
+					Expect(c).To(BeEmpty())
+					Expect(n).To(BeVersion(1))
+					cancel()
+					return true, nil
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+			})
+
+			It("restarts the consumer when a conflict occurs", func() {
+				handler.HandleEventFunc = func(
+					_ context.Context,
+					_, _, _ []byte,
+					_ dogma.ProjectionEventScope,
+					_ dogma.Message,
+				) (bool, error) {
+					handler.ResourceVersionFunc = func(
+						_ context.Context,
+						res []byte,
+					) ([]byte, error) {
+						return []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02}, nil
+					}
+
+					handler.HandleEventFunc = func(
+						_ context.Context,
+						_, _, _ []byte,
+						_ dogma.ProjectionEventScope,
+						m dogma.Message,
+					) (bool, error) {
+						Expect(m).To(Equal(MessageA3))
+						cancel()
+						return true, nil
+					}
+
+					return false, nil
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+			})
+
+			It("returns a *ConflictError if conflicts persist beyond the conflict timeout", func() {
+				proj.ConflictTimeout = 20 * time.Millisecond
+
+				handler.HandleEventFunc = func(
+					context.Context,
+					[]byte, []byte, []byte,
+					dogma.ProjectionEventScope,
+					dogma.Message,
+				) (bool, error) {
+					time.Sleep(5 * time.Millisecond)
+					return false, nil
+				}
+
+				err := proj.Run(ctx)
+
+				var target *ConflictError
+				Expect(errors.As(err, &target)).To(BeTrue())
+				Expect(target.Offset).To(BeNumerically("==", 0))
+				Expect(target.Elapsed).To(BeNumerically(">", proj.ConflictTimeout))
+			})
+
+			It("uses the clock to measure the conflict timeout", func() {
+				clock := &fakeClock{
+					now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+				}
+				proj.Clock = clock
+				proj.ConflictTimeout = time.Hour
+
+				conflicts := 0
+				handler.HandleEventFunc = func(
+					context.Context,
+					[]byte, []byte, []byte,
+					dogma.ProjectionEventScope,
+					dogma.Message,
+				) (bool, error) {
+					// Advance the clock past the deadline after the first
+					// conflict, which starts measuring the timeout.
+					conflicts++
+					if conflicts == 2 {
+						clock.Advance(time.Hour + time.Second)
+					}
+					return false, nil
+				}
+
+				err := proj.Run(ctx)
+
+				var target *ConflictError
+				Expect(errors.As(err, &target)).To(BeTrue())
+				Expect(target.Elapsed).To(Equal(time.Hour + time.Second))
+				Expect(conflicts).To(Equal(2))
+			})
+
+			It("resets the conflict timeout when an event is applied", func() {
+				proj.ConflictTimeout = 20 * time.Millisecond
+
+				var (
+					version   []byte
+					conflicts int
+				)
+
+				handler.ResourceVersionFunc = func(context.Context, []byte) ([]byte, error) {
+					return version, nil
+				}
+
+				handler.HandleEventFunc = func(
+					_ context.Context,
+					_, _, n []byte,
+					_ dogma.ProjectionEventScope,
+					m dogma.Message,
+				) (bool, error) {
+					// Each event conflicts twice before being applied, such
+					// that the total time spent in conflicts exceeds the
+					// timeout, but no run of consecutive conflicts does.
+					time.Sleep(15 * time.Millisecond)
+
+					conflicts++
+					if conflicts%3 != 0 {
+						return false, nil
+					}
+
+					if m == MessageA3 {
+						cancel()
+					}
+
+					version = append([]byte(nil), n...)
+					return true, nil
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+			})
+
+			It("uses the custom ResumeOffset function if one is provided", func() {
+				handler.ResourceVersionFunc = func(
+					context.Context,
+					[]byte,
+				) ([]byte, error) {
+					return []byte("<version>"), nil
+				}
+
+				proj.ResumeOffset = func(v []byte) (uint64, error) {
+					Expect(v).To(Equal([]byte("<version>")))
+					return 4, nil
+				}
+
+				handler.HandleEventFunc = func(
+					_ context.Context,
+					_, c, n []byte,
+					_ dogma.ProjectionEventScope,
+					m dogma.Message,
+				) (bool, error) {
+					Expect(m).To(Equal(MessageA3))
+					Expect(c).To(Equal([]byte("<version>")))
+					Expect(n).To(BeVersion(5))
+					cancel()
+					return true, nil
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+			})
+
+			It("returns an error if the custom ResumeOffset function fails", func() {
+				proj.ResumeOffset = func([]byte) (uint64, error) {
+					return 0, errors.New("<error>")
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(MatchError(
+					"unable to consume from '<id>' for the '<proj>' projection: <error>",
+				))
+			})
+
+			It("starts from StartOffset if it is provided", func() {
+				handler.ResourceVersionFunc = func(
+					context.Context,
+					[]byte,
+				) ([]byte, error) {
+					return resource.MarshalOffset(1), nil
+				}
+
+				offset := uint64(4)
+				proj.StartOffset = &offset
+
+				handler.HandleEventFunc = func(
+					_ context.Context,
+					_, c, n []byte,
+					_ dogma.ProjectionEventScope,
+					m dogma.Message,
+				) (bool, error) {
+					Expect(m).To(Equal(MessageA3))
+					Expect(c).To(BeVersion(1))
+					Expect(n).To(BeVersion(5))
+					cancel()
+					return true, nil
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+			})
+
+			It("resumes from the recorded offset if the stream is re-opened after StartOffset is used", func() {
+				offset := uint64(4)
+				proj.StartOffset = &offset
+
+				var messages []dogma.Message
+				handler.HandleEventFunc = func(
+					_ context.Context,
+					_, _, _ []byte,
+					_ dogma.ProjectionEventScope,
+					m dogma.Message,
+				) (bool, error) {
+					messages = append(messages, m)
+
+					if len(messages) == 2 {
+						cancel()
+					}
+
+					// Report an OCC conflict, causing the stream to be
+					// re-opened.
+					return false, nil
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+				Expect(messages).To(Equal([]dogma.Message{MessageA3, MessageA1}))
+			})
+
+			It("starts from the tail of the stream if StartAtTail is true and there is no recorded offset", func() {
+				proj.StartAtTail = true
+
+				handler.HandleEventFunc = func(
+					_ context.Context,
+					_, c, n []byte,
+					_ dogma.ProjectionEventScope,
+					m dogma.Message,
+				) (bool, error) {
+					Expect(m).To(Equal(MessageA{Value: "A4"}))
+					Expect(c).To(BeEmpty())
+					Expect(n).To(BeVersion(7))
 					cancel()
 					return true, nil
 				}
 
+				go func() {
+					time.Sleep(20 * time.Millisecond)
+					stream.Append(time.Now(), MessageA{Value: "A4"})
+				}()
+
 				err := proj.Run(ctx)
 				Expect(err).To(Equal(context.Canceled))
 			})
 
-			It("passes the correct resource and versions to the handler when the resource does not exist", func() {
+			It("resumes from the recorded offset if StartAtTail is true", func() {
+				proj.StartAtTail = true
+
 				handler.ResourceVersionFunc = func(
-					_ context.Context,
-					res []byte,
+					context.Context,
+					[]byte,
 				) ([]byte, error) {
-					return nil, nil
+					return resource.MarshalOffset(1), nil
 				}
 
 				handler.HandleEventFunc = func(
 					_ context.Context,
-					r, c, n []byte,
+					_, _, _ []byte,
 					_ dogma.ProjectionEventScope,
-					_ dogma.Message,
+					m dogma.Message,
 				) (bool, error) {
-					Expect(r).To(Equal([]byte("<id>")))
-					Expect(c).To(BeEmpty())
-					Expect(n).To(Equal([]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}))
+					Expect(m).To(Equal(MessageA2))
 					cancel()
 					return true, nil
 				}
@@ -374,31 +1744,118 @@ var _ = Describe("type Projector", func() {
 				Expect(err).To(Equal(context.Canceled))
 			})
 
-			It("restarts the consumer when a conflict occurs", func() {
+			It("returns an error if StartAtTail is true and OffsetStore is set", func() {
+				proj.StartAtTail = true
+				proj.OffsetStore = &MemoryOffsetStore{}
+
 				handler.HandleEventFunc = func(
-					_ context.Context,
-					_, _, _ []byte,
-					_ dogma.ProjectionEventScope,
-					_ dogma.Message,
+					context.Context,
+					[]byte, []byte, []byte,
+					dogma.ProjectionEventScope,
+					dogma.Message,
 				) (bool, error) {
-					handler.ResourceVersionFunc = func(
-						_ context.Context,
-						res []byte,
-					) ([]byte, error) {
-						return []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02}, nil
+					Fail("unexpected call")
+					return false, nil
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(MatchError(
+					"unable to run the '<proj>' projection: StartAtTail can not be used with an OffsetStore",
+				))
+			})
+
+			It("returns an error if StartAtTail is true and the stream can not report its head", func() {
+				proj.StartAtTail = true
+				proj.Stream = &struct{ Stream }{stream}
+
+				err := proj.Run(ctx)
+				Expect(err).To(MatchError(
+					MatchRegexp(`unable to open the stream at its tail, .* does not implement TailStream, HeadStream or BoundsStream$`),
+				))
+			})
+
+			DescribeTable(
+				"it does not pass events that do not satisfy the predicate to the handler",
+				func(wrap func(*MemoryStream) Stream) {
+					proj.Stream = wrap(stream)
+					proj.Predicate = func(env Envelope) bool {
+						return env.Message != MessageA2
 					}
 
+					var messages []dogma.Message
 					handler.HandleEventFunc = func(
 						_ context.Context,
 						_, _, _ []byte,
 						_ dogma.ProjectionEventScope,
 						m dogma.Message,
 					) (bool, error) {
-						Expect(m).To(Equal(MessageA3))
-						cancel()
+						messages = append(messages, m)
+						if m == MessageA3 {
+							cancel()
+						}
 						return true, nil
 					}
 
+					err := proj.Run(ctx)
+					Expect(err).To(Equal(context.Canceled))
+					Expect(messages).To(Equal([]dogma.Message{MessageA1, MessageA3}))
+				},
+				Entry("when the cursor implements PredicateCursor", func(s *MemoryStream) Stream { return s }),
+				Entry("when the cursor does not implement PredicateCursor", func(s *MemoryStream) Stream { return &blockingStream{s} }),
+			)
+
+			It("passes the raw version to the OnVersionRead hook", func() {
+				handler.ResourceVersionFunc = func(
+					context.Context,
+					[]byte,
+				) ([]byte, error) {
+					return []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02}, nil
+				}
+
+				proj.OnVersionRead = func(c []byte) {
+					Expect(c).To(BeVersion(3))
+					cancel()
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+			})
+
+			It("passes the raw versions to the OnVersionAdvance hook", func() {
+				var versions [][]byte
+				proj.OnVersionAdvance = func(c, n []byte) {
+					versions = append(
+						versions,
+						append([]byte(nil), c...),
+						append([]byte(nil), n...),
+					)
+
+					if len(versions) == 4 {
+						cancel()
+					}
+				}
+
+				err := proj.Run(ctx)
+				Expect(err).To(Equal(context.Canceled))
+				Expect(versions).To(HaveLen(4))
+				Expect(versions[0]).To(BeEmpty())
+				Expect(versions[1]).To(BeVersion(1))
+				Expect(versions[2]).To(BeVersion(1))
+				Expect(versions[3]).To(BeVersion(3))
+			})
+
+			It("does not call the OnVersionAdvance hook when a conflict occurs", func() {
+				proj.OnVersionAdvance = func(c, n []byte) {
+					Fail("unexpected call to OnVersionAdvance()")
+				}
+
+				handler.HandleEventFunc = func(
+					context.Context,
+					[]byte, []byte, []byte,
+					dogma.ProjectionEventScope,
+					dogma.Message,
+				) (bool, error) {
+					cancel()
 					return false, nil
 				}
 
@@ -418,6 +1875,10 @@ var _ = Describe("type Projector", func() {
 				Expect(err).To(MatchError(
 					"unable to consume from '<id>' for the '<proj>' projection: version is 1 byte(s), expected 0 or 8",
 				))
+
+				var target *resource.InvalidVersionError
+				Expect(errors.As(err, &target)).To(BeTrue())
+				Expect(target.Length).To(Equal(1))
 			})
 
 			It("returns an error if the current version can not be read", func() {
@@ -433,6 +1894,419 @@ var _ = Describe("type Projector", func() {
 					"unable to consume from '<id>' for the '<proj>' projection: <error>",
 				))
 			})
+
+			It("returns an OpenError if the current version can not be read", func() {
+				handler.ResourceVersionFunc = func(
+					context.Context,
+					[]byte,
+				) ([]byte, error) {
+					return nil, errors.New("<error>")
+				}
+
+				err := proj.Run(ctx)
+
+				var openErr *OpenError
+				Expect(errors.As(err, &openErr)).To(BeTrue())
+				Expect(openErr.Err).To(MatchError("<error>"))
+
+				var handleErr *HandleError
+				Expect(errors.As(err, &handleErr)).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("func Start()", func() {
+		It("runs the projector until the stop function is called", func() {
+			handled := make(chan struct{})
+			handler.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				select {
+				case handled <- struct{}{}:
+				default:
+				}
+				return true, nil
+			}
+
+			stop := proj.Start(ctx)
+			<-handled
+
+			err := stop()
+			Expect(err).To(Equal(context.Canceled))
+		})
+
+		It("can be called more than once", func() {
+			stop := proj.Start(ctx)
+
+			err := stop()
+			Expect(err).To(Equal(context.Canceled))
+
+			err = stop()
+			Expect(err).To(Equal(context.Canceled))
+		})
+	})
+
+	Describe("func IsHandling()", func() {
+		It("returns true only while the handler is handling an event", func() {
+			Expect(proj.IsHandling()).To(BeFalse())
+
+			handler.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				Expect(proj.IsHandling()).To(BeTrue())
+				cancel()
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+			Expect(proj.IsHandling()).To(BeFalse())
+		})
+
+		It("returns false while waiting for events", func() {
+			handler.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				return true, nil
+			}
+
+			stop := proj.Start(ctx)
+			defer stop()
+
+			Eventually(proj.HandledCount).Should(BeNumerically(">", 0))
+			Consistently(proj.IsHandling).Should(BeFalse())
+		})
+	})
+
+	Describe("func HandledCount()", func() {
+		It("returns the number of events handled by the current run", func() {
+			Expect(proj.HandledCount()).To(BeNumerically("==", 0))
+
+			var count int
+			handler.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				count++
+				if count == 2 {
+					cancel()
+				}
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+			Expect(proj.HandledCount()).To(BeNumerically("==", 2))
+		})
+
+		It("does not count events that are not applied due to a conflict", func() {
+			handler.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				cancel()
+				return false, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+			Expect(proj.HandledCount()).To(BeNumerically("==", 0))
+		})
+
+		It("is reset each time the projector is run", func() {
+			handler.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				cancel()
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+			Expect(proj.HandledCount()).To(BeNumerically("==", 1))
+
+			handler.ResourceVersionFunc = func(
+				context.Context,
+				[]byte,
+			) ([]byte, error) {
+				return nil, errors.New("<error>")
+			}
+
+			err = proj.Run(context.Background())
+			Expect(err).To(HaveOccurred())
+			Expect(proj.HandledCount()).To(BeNumerically("==", 0))
+		})
+	})
+
+	Describe("func Offset()", func() {
+		It("returns false if no events have been applied", func() {
+			_, ok := proj.Offset()
+			Expect(ok).To(BeFalse())
+		})
+
+		It("returns the offset of the most recently applied event", func() {
+			handler.HandleEventFunc = func(
+				_ context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				m dogma.Message,
+			) (bool, error) {
+				if m == MessageA2 {
+					cancel()
+				}
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+
+			offset, ok := proj.Offset()
+			Expect(ok).To(BeTrue())
+			Expect(offset).To(BeNumerically("==", 2))
+		})
+
+		It("does not include events that are not applied due to a conflict", func() {
+			handler.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				cancel()
+				return false, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+
+			_, ok := proj.Offset()
+			Expect(ok).To(BeFalse())
+		})
+
+		It("returns the offset recorded in the projection once the stream is opened", func() {
+			handler.ResourceVersionFunc = func(
+				context.Context,
+				[]byte,
+			) ([]byte, error) {
+				return resource.MarshalOffset(6), nil
+			}
+
+			result := make(chan error, 1)
+			go func() {
+				result <- proj.Run(ctx)
+			}()
+
+			Eventually(func() bool {
+				offset, ok := proj.Offset()
+				return ok && offset == 5
+			}).Should(BeTrue())
+
+			cancel()
+			Expect(<-result).To(Equal(context.Canceled))
+		})
+	})
+
+	Describe("func Name()", func() {
+		It("returns the handler's name", func() {
+			Expect(proj.Name()).To(Equal("<proj>"))
+		})
+
+		It("panics if the handler configuration is invalid", func() {
+			handler.ConfigureFunc = nil
+			Expect(func() {
+				proj.Name()
+			}).To(Panic())
+		})
+	})
+
+	Describe("func Identity()", func() {
+		It("returns the handler's identity", func() {
+			Expect(proj.Identity()).To(Equal(
+				configkit.Identity{
+					Name: "<proj>",
+					Key:  "45804515-8b41-4d23-97b1-0cda5a0d782c",
+				},
+			))
+		})
+
+		It("panics if the handler configuration is invalid", func() {
+			handler.ConfigureFunc = nil
+			Expect(func() {
+				proj.Identity()
+			}).To(Panic())
+		})
+	})
+
+	Describe("func Resource()", func() {
+		It("returns the resource derived from the stream ID", func() {
+			Expect(proj.Resource()).To(Equal([]byte("<id>")))
+		})
+	})
+
+	Describe("func ConsumedTypes()", func() {
+		It("returns the event types consumed by the handler", func() {
+			handler.ConfigureFunc = func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageB{})
+				c.ConsumesEventType(MessageA{})
+			}
+
+			Expect(proj.ConsumedTypes()).To(Equal(
+				[]message.Type{
+					message.TypeOf(MessageA{}),
+					message.TypeOf(MessageB{}),
+				},
+			))
 		})
 	})
 })
+
+// compactionClock is a test implementation of CompactionClock.
+type compactionClock struct {
+	m         sync.Mutex
+	last      time.Time
+	loadErr   error
+	recordErr error
+}
+
+func (c *compactionClock) Last() time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.last
+}
+
+func (c *compactionClock) LastCompactedAt(context.Context) (time.Time, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.last, c.loadErr
+}
+
+func (c *compactionClock) RecordCompaction(_ context.Context, t time.Time) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.recordErr != nil {
+		return c.recordErr
+	}
+
+	c.last = t
+	return nil
+}
+
+// fakeClock is a test implementation of Clock that only advances when
+// Advance() is called.
+type fakeClock struct {
+	m       sync.Mutex
+	now     time.Time
+	waiting []fakeTimer
+}
+
+type fakeTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	ch := make(chan time.Time, 1)
+	c.waiting = append(c.waiting, fakeTimer{c.now.Add(d), ch})
+	return ch
+}
+
+func (c *fakeClock) Waiting() bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return len(c.waiting) > 0
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.now = c.now.Add(d)
+
+	var waiting []fakeTimer
+	for _, t := range c.waiting {
+		if t.at.After(c.now) {
+			waiting = append(waiting, t)
+		} else {
+			t.ch <- c.now
+		}
+	}
+	c.waiting = waiting
+}
+
+// consumerStream is a test implementation of ConsumerStream.
+type consumerStream struct {
+	*MemoryStream
+	consumerID string
+}
+
+func (s *consumerStream) Open(
+	context.Context,
+	uint64,
+	[]dogma.Message,
+) (Cursor, error) {
+	return nil, errors.New("unexpected call to Open()")
+}
+
+func (s *consumerStream) OpenAs(
+	ctx context.Context,
+	consumerID string,
+	offset uint64,
+	filter []dogma.Message,
+) (Cursor, error) {
+	s.consumerID = consumerID
+	return s.MemoryStream.Open(ctx, offset, filter)
+}
+
+// omitTime is an slog.HandlerOptions.ReplaceAttr function that removes the
+// time from log records, so that they can be compared in tests.
+func omitTime(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.TimeKey {
+		return slog.Attr{}
+	}
+	return a
+}
+
+// boundsOnlyStream is a Stream that implements BoundsStream, but not
+// HeadStream.
+type boundsOnlyStream struct {
+	stream *MemoryStream
+}
+
+func (s boundsOnlyStream) ID() string {
+	return s.stream.ID()
+}
+
+func (s boundsOnlyStream) Open(ctx context.Context, offset uint64, filter []dogma.Message) (Cursor, error) {
+	return s.stream.Open(ctx, offset, filter)
+}
+
+func (s boundsOnlyStream) Bounds(ctx context.Context) (first, next uint64, err error) {
+	return s.stream.Bounds(ctx)
+}
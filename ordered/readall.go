@@ -0,0 +1,47 @@
+package ordered
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dogmatiq/dogma"
+)
+
+// ReadAll returns the events in s, beginning at offset.
+//
+// The offset and filter parameters have the same semantics as for
+// Stream.Open(). It reads events until the stream is sealed, in which case it
+// returns the events without an error.
+//
+// If s is never sealed, ReadAll() reads until ctx is canceled or its deadline
+// is exceeded, in which case it returns the events read so far along with the
+// context's error.
+func ReadAll(
+	ctx context.Context,
+	s Stream,
+	offset uint64,
+	filter []dogma.Message,
+) ([]Envelope, error) {
+	cur, err := s.Open(ctx, offset, filter)
+	if err != nil {
+		if errors.Is(err, ErrStreamSealed) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer cur.Close()
+
+	var envs []Envelope
+
+	for {
+		env, err := cur.Next(ctx)
+		if err != nil {
+			if errors.Is(err, ErrStreamSealed) {
+				return envs, nil
+			}
+			return envs, err
+		}
+
+		envs = append(envs, env)
+	}
+}
@@ -0,0 +1,71 @@
+package ordered_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("func ReadAll()", func() {
+	var (
+		now    time.Time
+		ctx    context.Context
+		cancel func()
+		stream *MemoryStream
+	)
+
+	BeforeEach(func() {
+		now = time.Now()
+
+		ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+
+		stream = &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		stream.Append(
+			now,
+			MessageA1,
+			MessageB1,
+			MessageA2,
+		)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("returns the filtered events up to the end of a sealed stream", func() {
+		stream.Seal()
+
+		envs, err := ReadAll(ctx, stream, 1, []dogma.Message{MessageA{}})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(envs).To(Equal(
+			[]Envelope{
+				{Offset: 2, RecordedAt: now, Message: MessageA2, MessageID: "<id>@2"},
+			},
+		))
+	})
+
+	It("returns no events if the offset is beyond the end of a sealed stream", func() {
+		stream.Seal()
+
+		envs, err := ReadAll(ctx, stream, 10, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(envs).To(BeEmpty())
+	})
+
+	It("returns the events read before the context deadline is exceeded", func() {
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		envs, err := ReadAll(ctx, stream, 0, nil)
+		Expect(err).To(Equal(context.DeadlineExceeded))
+		Expect(envs).To(HaveLen(3))
+	})
+})
@@ -0,0 +1,68 @@
+package ordered
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dogmatiq/dodeca/logging"
+	"github.com/dogmatiq/linger"
+)
+
+// TransientError is an error that may be caused by a temporary condition, such
+// as the loss of a connection to the storage underlying a stream.
+//
+// If a stream's Open() method, or a cursor's Next() method, returns an error
+// that implements TransientError and Temporary() returns true, the projector
+// re-opens the stream after a delay instead of returning the error from Run().
+type TransientError interface {
+	error
+
+	// Temporary returns true if the error is temporary, such that re-opening
+	// the stream may succeed.
+	Temporary() bool
+}
+
+// isTemporary returns true if err is a temporary error returned by the stream.
+//
+// Errors returned by the handler are never considered temporary, even if they
+// implement TransientError.
+func isTemporary(err error) bool {
+	var handleErr *HandleError
+	if errors.As(err, &handleErr) {
+		return false
+	}
+
+	var transient TransientError
+	return errors.As(err, &transient) && transient.Temporary()
+}
+
+// reconnect logs the temporary error err, then waits before returning so that
+// the stream is re-opened.
+//
+// The delay doubles for each consecutive temporary error. It returns a non-nil
+// error only if ctx is canceled while waiting.
+func (p *Projector) reconnect(ctx context.Context, err error) error {
+	limit := linger.MustCoalesce(p.MaxReconnectDelay, DefaultMaxReconnectDelay)
+	delay := linger.MustCoalesce(p.ReconnectDelay, DefaultReconnectDelay)
+
+	for i := 0; i < p.failures && delay < limit; i++ {
+		delay *= 2
+	}
+
+	if delay > limit {
+		delay = limit
+	}
+
+	p.failures++
+
+	logging.Log(
+		p.Logger,
+		"[%s %s] temporary error, re-opening the stream in %s: %s",
+		p.name,
+		p.resource,
+		delay,
+		err,
+	)
+
+	return linger.Sleep(ctx, delay)
+}
@@ -0,0 +1,202 @@
+package ordered_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// temporaryError is a TransientError used for testing.
+type temporaryError struct {
+	temporary bool
+}
+
+func (e temporaryError) Error() string   { return "<temporary error>" }
+func (e temporaryError) Temporary() bool { return e.temporary }
+
+// flakyStream is a Stream that returns an error from Open() or Next() a fixed
+// number of times before delegating to the underlying stream.
+type flakyStream struct {
+	Stream
+	openErrors []error
+	nextErrors []error
+	opened     int
+}
+
+func (s *flakyStream) Open(
+	ctx context.Context,
+	offset uint64,
+	filter []dogma.Message,
+) (Cursor, error) {
+	s.opened++
+
+	if len(s.openErrors) > 0 {
+		err := s.openErrors[0]
+		s.openErrors = s.openErrors[1:]
+		return nil, err
+	}
+
+	cur, err := s.Stream.Open(ctx, offset, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &flakyCursor{cur, s}, nil
+}
+
+type flakyCursor struct {
+	Cursor
+	stream *flakyStream
+}
+
+func (c *flakyCursor) Next(ctx context.Context) (Envelope, error) {
+	if len(c.stream.nextErrors) > 0 {
+		err := c.stream.nextErrors[0]
+		c.stream.nextErrors = c.stream.nextErrors[1:]
+		return Envelope{}, err
+	}
+
+	return c.Cursor.Next(ctx)
+}
+
+var _ TransientError = temporaryError{}
+
+var _ = Describe("type Projector (with transient errors)", func() {
+	var (
+		ctx     context.Context
+		cancel  func()
+		stream  *flakyStream
+		handler *ProjectionMessageHandler
+		proj    *Projector
+		handled []dogma.Message
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), DefaultTimeout*2)
+
+		mem := &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		mem.Append(
+			time.Now(),
+			MessageA1,
+			MessageA2,
+		)
+
+		stream = &flakyStream{Stream: mem}
+		handled = nil
+
+		handler = &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageA{})
+			},
+			HandleEventFunc: func(
+				_ context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				m dogma.Message,
+			) (bool, error) {
+				handled = append(handled, m)
+				if len(handled) == 2 {
+					cancel()
+				}
+				return true, nil
+			},
+		}
+
+		proj = &Projector{
+			Stream:         stream,
+			Handler:        handler,
+			ReconnectDelay: time.Millisecond,
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("re-opens the stream if the cursor returns a temporary error", func() {
+		stream.nextErrors = []error{temporaryError{true}}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+		Expect(stream.opened).To(Equal(2))
+		Expect(handled).To(Equal([]dogma.Message{MessageA1, MessageA2}))
+	})
+
+	It("re-opens the stream if opening the stream returns a temporary error", func() {
+		stream.openErrors = []error{temporaryError{true}, temporaryError{true}}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+		Expect(stream.opened).To(Equal(3))
+		Expect(handled).To(Equal([]dogma.Message{MessageA1, MessageA2}))
+	})
+
+	It("waits longer after each consecutive temporary error", func() {
+		proj.ReconnectDelay = 20 * time.Millisecond
+		stream.openErrors = []error{temporaryError{true}, temporaryError{true}}
+
+		start := time.Now()
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+		Expect(time.Since(start)).To(BeNumerically(">=", 60*time.Millisecond))
+	})
+
+	It("does not wait longer than MaxReconnectDelay", func() {
+		proj.ReconnectDelay = 20 * time.Millisecond
+		proj.MaxReconnectDelay = 20 * time.Millisecond
+		stream.openErrors = []error{temporaryError{true}, temporaryError{true}, temporaryError{true}}
+
+		start := time.Now()
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+		Expect(time.Since(start)).To(BeNumerically("<", 120*time.Millisecond))
+	})
+
+	It("returns errors that are not temporary", func() {
+		stream.nextErrors = []error{temporaryError{false}}
+
+		err := proj.Run(ctx)
+		Expect(err).To(MatchError(ContainSubstring("<temporary error>")))
+		Expect(stream.opened).To(Equal(1))
+	})
+
+	It("returns temporary errors returned by the handler", func() {
+		handler.HandleEventFunc = func(
+			context.Context,
+			[]byte, []byte, []byte,
+			dogma.ProjectionEventScope,
+			dogma.Message,
+		) (bool, error) {
+			return false, temporaryError{true}
+		}
+
+		err := proj.Run(ctx)
+
+		var handleErr *HandleError
+		Expect(errors.As(err, &handleErr)).To(BeTrue())
+		Expect(stream.opened).To(Equal(1))
+	})
+
+	It("returns if the context is canceled while waiting to re-open the stream", func() {
+		proj.ReconnectDelay = time.Hour
+		stream.openErrors = []error{temporaryError{true}}
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+		}()
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+	})
+})
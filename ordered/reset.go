@@ -0,0 +1,160 @@
+package ordered
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dogmatiq/configkit"
+	"github.com/dogmatiq/dodeca/logging"
+	"github.com/dogmatiq/dogma"
+)
+
+// ResettableProjectionMessageHandler is a projection message handler that can
+// reset the version of a resource, such that the projection is rebuilt from
+// the start of the stream.
+type ResettableProjectionMessageHandler interface {
+	dogma.ProjectionMessageHandler
+
+	// ResetResourceVersion resets the version of the resource r to the empty
+	// version, as though no events from the stream have ever been applied.
+	//
+	// The handler is responsible for discarding, or otherwise invalidating,
+	// the projection data derived from those events.
+	ResetResourceVersion(ctx context.Context, r []byte) error
+}
+
+// Reset resets the projection's version of the stream such that it is rebuilt
+// from the first event.
+//
+// The handler must implement ResettableProjectionMessageHandler, otherwise an
+// error is returned. An error is also returned if p.StartAtTail is true. If
+// p.OffsetStore is non-nil, the stored offset is also reset to 0. If the
+// projector is running, the consumer is restarted from the beginning of the
+// stream once the version has been reset.
+func (p *Projector) Reset(ctx context.Context) (err error) {
+	defer configkit.Recover(&err)
+
+	p.prepare()
+
+	// The projection's version is empty once reset, which StartAtTail would
+	// treat as a new projection, skipping the events it is meant to rebuild.
+	if p.StartAtTail {
+		return fmt.Errorf(
+			"unable to reset the '%s' projection: StartAtTail can not be used with Reset()",
+			p.name,
+		)
+	}
+
+	handler := p.state.Load().handler
+
+	h, ok := handler.(ResettableProjectionMessageHandler)
+	if !ok {
+		return fmt.Errorf(
+			"unable to reset the '%s' projection: %T does not implement ResettableProjectionMessageHandler",
+			p.name,
+			handler,
+		)
+	}
+
+	if err := h.ResetResourceVersion(ctx, p.resource); err != nil {
+		return fmt.Errorf(
+			"unable to reset the '%s' projection: %w",
+			p.name,
+			err,
+		)
+	}
+
+	// If offsets are persisted independently of the projection the stored
+	// offset must also be reset, otherwise the consumer would resume from it.
+	if err := p.save(ctx, 0); err != nil {
+		return fmt.Errorf(
+			"unable to reset the '%s' projection: %w",
+			p.name,
+			err,
+		)
+	}
+
+	logging.Log(
+		p.Logger,
+		"[%s %s] reset",
+		p.name,
+		p.resource,
+	)
+
+	p.m.Lock()
+	restart := p.restart
+	p.m.Unlock()
+
+	if restart != nil {
+		restart()
+	}
+
+	return nil
+}
+
+// consumeUntilReset calls consume() with a context that is canceled if the
+// projection is reset, or if the event types consumed by a
+// DynamicProjectionMessageHandler change.
+//
+// It returns nil if consumption was stopped because the projection was reset
+// or the types changed.
+func (p *Projector) consumeUntilReset(ctx context.Context, oneShot bool) error {
+	cctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// reset is set if the consumer is stopped by Reset(), as opposed to a
+	// change in the consumed event types.
+	var reset atomic.Bool
+
+	p.m.Lock()
+	p.restart = func() {
+		reset.Store(true)
+		cancel()
+	}
+	p.m.Unlock()
+
+	defer func() {
+		p.m.Lock()
+		p.restart = nil
+		p.m.Unlock()
+	}()
+
+	var (
+		wg       sync.WaitGroup
+		watchErr error
+	)
+
+	st := p.state.Load()
+	if h, ok := st.handler.(DynamicProjectionMessageHandler); ok {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			watchErr = p.watchConsumedTypes(cctx, st, h, cancel)
+		}()
+	}
+
+	err := p.consume(cctx, oneShot)
+	restarted := ctx.Err() == nil && cctx.Err() != nil
+
+	cancel()
+	wg.Wait()
+
+	if watchErr != nil {
+		return watchErr
+	}
+
+	if restarted {
+		// The consumer may have saved an offset after Reset() saved the
+		// offset of the first event, so it is saved again now that the
+		// consumer has stopped.
+		if reset.Load() {
+			return p.save(ctx, 0)
+		}
+
+		return nil
+	}
+
+	return err
+}
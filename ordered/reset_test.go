@@ -0,0 +1,244 @@
+package ordered_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// resettableHandler is a test implementation of
+// ResettableProjectionMessageHandler.
+type resettableHandler struct {
+	ProjectionMessageHandler
+
+	ResetResourceVersionFunc func(ctx context.Context, r []byte) error
+}
+
+func (h *resettableHandler) ResetResourceVersion(ctx context.Context, r []byte) error {
+	if h.ResetResourceVersionFunc != nil {
+		return h.ResetResourceVersionFunc(ctx, r)
+	}
+
+	return nil
+}
+
+var _ = Describe("type Projector (resetting)", func() {
+	var (
+		ctx     context.Context
+		cancel  func()
+		stream  *MemoryStream
+		handler *resettableHandler
+		proj    *Projector
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), DefaultTimeout*2)
+
+		stream = &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		stream.Append(
+			time.Now(),
+			MessageA1,
+			MessageA2,
+		)
+
+		handler = &resettableHandler{
+			ProjectionMessageHandler: ProjectionMessageHandler{
+				ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+					c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+					c.ConsumesEventType(MessageA{})
+				},
+			},
+		}
+
+		proj = &Projector{
+			Stream:  stream,
+			Handler: handler,
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Describe("func Reset()", func() {
+		It("restarts a running projector from the beginning of the stream", func() {
+			var (
+				m        sync.Mutex
+				version  []byte
+				messages []dogma.Message
+			)
+
+			handler.ResourceVersionFunc = func(context.Context, []byte) ([]byte, error) {
+				m.Lock()
+				defer m.Unlock()
+				return version, nil
+			}
+
+			handler.ResetResourceVersionFunc = func(context.Context, []byte) error {
+				m.Lock()
+				defer m.Unlock()
+				version = nil
+				return nil
+			}
+
+			handler.HandleEventFunc = func(
+				_ context.Context,
+				_, c, n []byte,
+				_ dogma.ProjectionEventScope,
+				msg dogma.Message,
+			) (bool, error) {
+				m.Lock()
+				defer m.Unlock()
+
+				if !bytes.Equal(c, version) {
+					return false, nil
+				}
+
+				version = n
+				messages = append(messages, msg)
+
+				switch len(messages) {
+				case 2:
+					go func() {
+						defer GinkgoRecover()
+						err := proj.Reset(ctx)
+						Expect(err).ShouldNot(HaveOccurred())
+					}()
+				case 4:
+					cancel()
+				}
+
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+			Expect(messages).To(Equal(
+				[]dogma.Message{MessageA1, MessageA2, MessageA1, MessageA2},
+			))
+		})
+
+		It("resets the version of the projector's resource when it is not running", func() {
+			var resource []byte
+			handler.ResetResourceVersionFunc = func(_ context.Context, r []byte) error {
+				resource = r
+				return nil
+			}
+
+			err := proj.Reset(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resource).To(Equal([]byte("<id>")))
+		})
+
+		It("resets the offset in the offset store, if set", func() {
+			store := &MemoryOffsetStore{}
+			proj.OffsetStore = store
+
+			err := store.Save(ctx, "45804515-8b41-4d23-97b1-0cda5a0d782c", 2)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = proj.Reset(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			offset, err := store.Load(ctx, "45804515-8b41-4d23-97b1-0cda5a0d782c")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(offset).To(BeEquivalentTo(0))
+		})
+
+		It("restarts a running projector with an offset store from the beginning of the stream", func() {
+			var (
+				m        sync.Mutex
+				messages []dogma.Message
+			)
+
+			proj.OffsetStore = &MemoryOffsetStore{}
+
+			handler.HandleEventFunc = func(
+				_ context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				msg dogma.Message,
+			) (bool, error) {
+				m.Lock()
+				defer m.Unlock()
+
+				messages = append(messages, msg)
+
+				switch len(messages) {
+				case 2:
+					go func() {
+						defer GinkgoRecover()
+						err := proj.Reset(ctx)
+						Expect(err).ShouldNot(HaveOccurred())
+					}()
+				case 4:
+					cancel()
+				}
+
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+			Expect(messages).To(Equal(
+				[]dogma.Message{MessageA1, MessageA2, MessageA1, MessageA2},
+			))
+		})
+
+		It("returns an error if the offset can not be reset", func() {
+			store := &failingOffsetStore{}
+			store.err = errors.New("<error>")
+			proj.OffsetStore = store
+
+			err := proj.Reset(ctx)
+			Expect(err).To(MatchError(
+				"unable to reset the '<proj>' projection: unable to save the offset: <error>",
+			))
+		})
+
+		It("returns an error if the handler can not be reset", func() {
+			proj.Handler = &handler.ProjectionMessageHandler
+
+			err := proj.Reset(ctx)
+			Expect(err).To(MatchError(
+				"unable to reset the '<proj>' projection: *fixtures.ProjectionMessageHandler does not implement ResettableProjectionMessageHandler",
+			))
+		})
+
+		It("returns an error without resetting the version if StartAtTail is true", func() {
+			proj.StartAtTail = true
+
+			handler.ResetResourceVersionFunc = func(context.Context, []byte) error {
+				Fail("unexpected call")
+				return nil
+			}
+
+			err := proj.Reset(ctx)
+			Expect(err).To(MatchError(
+				"unable to reset the '<proj>' projection: StartAtTail can not be used with Reset()",
+			))
+		})
+
+		It("returns an error if the version can not be reset", func() {
+			handler.ResetResourceVersionFunc = func(context.Context, []byte) error {
+				return errors.New("<error>")
+			}
+
+			err := proj.Reset(ctx)
+			Expect(err).To(MatchError(
+				"unable to reset the '<proj>' projection: <error>",
+			))
+		})
+	})
+})
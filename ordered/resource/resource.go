@@ -3,6 +3,7 @@ package resource
 import (
 	"encoding/binary"
 	"fmt"
+	"strconv"
 )
 
 // FromStreamID returns the resource to use for the given stream ID.
@@ -38,7 +39,8 @@ func MarshalOffsetInto(buf []byte, o uint64) []byte {
 // UnmarshalOffset unmarshals a stream offset from a resource version.
 //
 // It returns the next offset to be read from the stream, not the last offset
-// that was applied to the projection.
+// that was applied to the projection. It returns an *InvalidVersionError if v
+// is neither empty nor 8 bytes in length.
 func UnmarshalOffset(v []byte) (uint64, error) {
 	switch len(v) {
 	case 0:
@@ -46,9 +48,62 @@ func UnmarshalOffset(v []byte) (uint64, error) {
 	case 8:
 		return binary.BigEndian.Uint64(v) + 1, nil
 	default:
-		return 0, fmt.Errorf(
-			"version is %d byte(s), expected 0 or 8",
-			len(v),
-		)
+		return 0, &InvalidVersionError{Length: len(v)}
+	}
+}
+
+// InvalidVersionError is returned by UnmarshalOffset() when a resource version
+// is not a valid marshaled offset.
+type InvalidVersionError struct {
+	// Length is the length of the version, in bytes.
+	Length int
+}
+
+func (e *InvalidVersionError) Error() string {
+	return fmt.Sprintf(
+		"version is %d byte(s), expected 0 or 8",
+		e.Length,
+	)
+}
+
+// FormatOffset returns a human-readable decimal representation of a stream
+// offset.
+//
+// It is intended for display purposes, such as within logs. Use
+// MarshalOffset() to produce a resource version.
+func FormatOffset(o uint64) string {
+	return strconv.FormatUint(o, 10)
+}
+
+// ParseOffset parses a decimal representation of a stream offset, as returned
+// by FormatOffset().
+func ParseOffset(s string) (uint64, error) {
+	o, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid offset %q: %w", s, err.(*strconv.NumError).Err)
+	}
+
+	return o, nil
+}
+
+// FormatOffsetHex returns a human-readable hexadecimal representation of a
+// stream offset, with a "0x" prefix.
+func FormatOffsetHex(o uint64) string {
+	return "0x" + strconv.FormatUint(o, 16)
+}
+
+// ParseOffsetHex parses a hexadecimal representation of a stream offset, as
+// returned by FormatOffsetHex(). The "0x" prefix is optional.
+func ParseOffsetHex(s string) (uint64, error) {
+	h := s
+	if len(h) >= 2 && h[0] == '0' && (h[1] == 'x' || h[1] == 'X') {
+		h = h[2:]
 	}
+
+	o, err := strconv.ParseUint(h, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid offset %q: %w", s, err.(*strconv.NumError).Err)
+	}
+
+	return o, nil
 }
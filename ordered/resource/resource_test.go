@@ -93,5 +93,57 @@ var _ = Describe("func UnmarshalOffsetInto()", func() {
 	It("returns an error if the byte-slice is an unexpected length", func() {
 		_, err := UnmarshalOffset([]byte{0})
 		Expect(err).To(MatchError("version is 1 byte(s), expected 0 or 8"))
+		Expect(err).To(Equal(&InvalidVersionError{Length: 1}))
+	})
+})
+
+var _ = Describe("func FormatOffset()", func() {
+	It("returns the offset in decimal", func() {
+		Expect(FormatOffset(0)).To(Equal("0"))
+		Expect(FormatOffset(1234)).To(Equal("1234"))
+	})
+})
+
+var _ = Describe("func ParseOffset()", func() {
+	It("parses the decimal representation of an offset", func() {
+		o, err := ParseOffset("1234")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(o).To(BeNumerically("==", 1234))
+	})
+
+	It("returns an error if the string is not a valid offset", func() {
+		_, err := ParseOffset("-1")
+		Expect(err).To(MatchError(`invalid offset "-1": invalid syntax`))
+	})
+})
+
+var _ = Describe("func FormatOffsetHex()", func() {
+	It("returns the offset in hexadecimal", func() {
+		Expect(FormatOffsetHex(0)).To(Equal("0x0"))
+		Expect(FormatOffsetHex(0x1f2e)).To(Equal("0x1f2e"))
+	})
+})
+
+var _ = Describe("func ParseOffsetHex()", func() {
+	It("parses the hexadecimal representation of an offset", func() {
+		o, err := ParseOffsetHex("0x1f2e")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(o).To(BeNumerically("==", 0x1f2e))
+	})
+
+	It("does not require the prefix", func() {
+		o, err := ParseOffsetHex("1F2E")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(o).To(BeNumerically("==", 0x1f2e))
+	})
+
+	It("returns an error if the string is not a valid offset", func() {
+		_, err := ParseOffsetHex("0x1ffffffffffffffff")
+		Expect(err).To(MatchError(`invalid offset "0x1ffffffffffffffff": value out of range`))
+	})
+
+	It("returns an error if the string has more than one prefix", func() {
+		_, err := ParseOffsetHex("0x0Xff")
+		Expect(err).To(MatchError(`invalid offset "0x0Xff": invalid syntax`))
 	})
 })
@@ -0,0 +1,90 @@
+package ordered
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dogmatiq/dodeca/logging"
+)
+
+// RunResult describes the reason that a projector stopped running.
+type RunResult int
+
+const (
+	// RunFailed indicates that the projector stopped because an error
+	// occurred while consuming events or compacting the projection.
+	RunFailed RunResult = iota
+
+	// RunCanceled indicates that the projector stopped because the context
+	// passed to RunWithResult() was canceled.
+	RunCanceled
+
+	// RunSealed indicates that the projector stopped because it consumed
+	// every event on a sealed stream, such as at the end of a bounded replay,
+	// and p.StopWhenSealed is true.
+	RunSealed
+
+	// RunCompleted indicates that the projector stopped without error because
+	// it finished its work, such as when RunUntilCaughtUpWithResult() has
+	// applied every event that was on the stream.
+	RunCompleted
+)
+
+func (r RunResult) String() string {
+	switch r {
+	case RunFailed:
+		return "failed"
+	case RunCanceled:
+		return "canceled"
+	case RunSealed:
+		return "sealed"
+	case RunCompleted:
+		return "completed"
+	default:
+		return "unknown"
+	}
+}
+
+// RunWithResult runs the projection until ctx is canceled or an error occurs.
+//
+// It behaves exactly like Run(), and additionally returns a RunResult that
+// describes why the projector stopped. It allows supervisors to make retry
+// decisions without inspecting the error.
+//
+// If the stream is sealed and p.StopWhenSealed is true, it returns RunSealed
+// and a nil error. Otherwise, a sealed stream is a failure and it returns
+// RunFailed and an error that wraps ErrStreamSealed.
+func (p *Projector) RunWithResult(ctx context.Context) (RunResult, error) {
+	return p.result(ctx, p.run(ctx, false))
+}
+
+// RunUntilCaughtUpWithResult runs the projection until every event that is
+// currently on the stream has been applied, then compacts the projection once.
+//
+// It behaves exactly like RunUntilCaughtUp(), and additionally returns a
+// RunResult that describes why the projector stopped. It returns RunCompleted
+// and a nil error once the projector has caught up.
+func (p *Projector) RunUntilCaughtUpWithResult(ctx context.Context) (RunResult, error) {
+	return p.result(ctx, p.run(ctx, true))
+}
+
+// result returns the RunResult that describes why p.run() returned err.
+func (p *Projector) result(ctx context.Context, err error) (RunResult, error) {
+	switch {
+	case err == nil:
+		return RunCompleted, nil
+	case ctx.Err() != nil && errors.Is(err, ctx.Err()):
+		return RunCanceled, err
+	case errors.Is(err, ErrStreamSealed) && p.StopWhenSealed:
+		logging.Log(
+			p.Logger,
+			"[%s %s] the stream is sealed, the projection is complete",
+			p.name,
+			p.resource,
+		)
+
+		return RunSealed, nil
+	default:
+		return RunFailed, err
+	}
+}
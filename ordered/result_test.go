@@ -0,0 +1,177 @@
+package ordered_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("func (*Projector) RunWithResult()", func() {
+	var (
+		ctx     context.Context
+		cancel  func()
+		stream  *MemoryStream
+		handler *ProjectionMessageHandler
+		proj    *Projector
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), DefaultTimeout*2)
+
+		stream = &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		stream.Append(
+			time.Now(),
+			MessageA1,
+		)
+
+		handler = &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageA{})
+			},
+		}
+
+		proj = &Projector{
+			Stream:  stream,
+			Handler: handler,
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("returns RunCanceled if the context is canceled", func() {
+		handler.HandleEventFunc = func(
+			context.Context,
+			[]byte, []byte, []byte,
+			dogma.ProjectionEventScope,
+			dogma.Message,
+		) (bool, error) {
+			cancel()
+			return true, nil
+		}
+
+		res, err := proj.RunWithResult(ctx)
+		Expect(err).To(Equal(context.Canceled))
+		Expect(res).To(Equal(RunCanceled))
+	})
+
+	It("returns RunFailed if the stream is sealed and StopWhenSealed is false", func() {
+		stream.Seal()
+
+		res, err := proj.RunWithResult(ctx)
+		Expect(err).To(MatchError(ErrStreamSealed))
+		Expect(res).To(Equal(RunFailed))
+	})
+
+	It("returns RunSealed without an error if the stream is sealed and StopWhenSealed is true", func() {
+		proj.StopWhenSealed = true
+		stream.Seal()
+
+		res, err := proj.RunWithResult(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(res).To(Equal(RunSealed))
+	})
+
+	It("returns RunFailed if an error occurs", func() {
+		handler.HandleEventFunc = func(
+			context.Context,
+			[]byte, []byte, []byte,
+			dogma.ProjectionEventScope,
+			dogma.Message,
+		) (bool, error) {
+			return false, errors.New("<error>")
+		}
+
+		res, err := proj.RunWithResult(ctx)
+		Expect(err).To(MatchError(ContainSubstring("<error>")))
+		Expect(res).To(Equal(RunFailed))
+	})
+
+	It("returns RunFailed if the handler returns a context error of its own", func() {
+		handler.HandleEventFunc = func(
+			context.Context,
+			[]byte, []byte, []byte,
+			dogma.ProjectionEventScope,
+			dogma.Message,
+		) (bool, error) {
+			return false, context.DeadlineExceeded
+		}
+
+		res, err := proj.RunWithResult(ctx)
+		Expect(err).Should(HaveOccurred())
+		Expect(res).To(Equal(RunFailed))
+	})
+})
+
+var _ = Describe("func (*Projector) RunUntilCaughtUpWithResult()", func() {
+	var (
+		ctx    context.Context
+		cancel func()
+		stream *MemoryStream
+		proj   *Projector
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), DefaultTimeout*2)
+
+		stream = &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		stream.Append(
+			time.Now(),
+			MessageA1,
+		)
+
+		proj = &Projector{
+			Stream: stream,
+			Handler: &ProjectionMessageHandler{
+				ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+					c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+					c.ConsumesEventType(MessageA{})
+				},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("returns RunCompleted once the projector has caught up", func() {
+		res, err := proj.RunUntilCaughtUpWithResult(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(res).To(Equal(RunCompleted))
+	})
+
+	It("returns RunCanceled if the context is canceled", func() {
+		cancel()
+
+		res, err := proj.RunUntilCaughtUpWithResult(ctx)
+		Expect(err).To(Equal(context.Canceled))
+		Expect(res).To(Equal(RunCanceled))
+	})
+})
+
+var _ = Describe("type RunResult", func() {
+	Describe("func String()", func() {
+		It("returns a description of the result", func() {
+			Expect(RunFailed.String()).To(Equal("failed"))
+			Expect(RunCanceled.String()).To(Equal("canceled"))
+			Expect(RunSealed.String()).To(Equal("sealed"))
+			Expect(RunCompleted.String()).To(Equal("completed"))
+			Expect(RunResult(-1).String()).To(Equal("unknown"))
+		})
+	})
+})
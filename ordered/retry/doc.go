@@ -0,0 +1,3 @@
+// Package retry provides a projection message handler decorator that retries
+// events that fail due to transient errors.
+package retry
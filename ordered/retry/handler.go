@@ -0,0 +1,130 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dogmatiq/dogma"
+	"github.com/dogmatiq/linger/backoff"
+)
+
+// DefaultMaxRetries is the default number of times that a failed event is
+// retried before the error is returned to the projector.
+const DefaultMaxRetries = 3
+
+// Handler is a dogma.ProjectionMessageHandler that retries HandleEvent() calls
+// that fail with a retryable error.
+//
+// All other methods are forwarded to the underlying handler unchanged. Events
+// that are not applied due to an optimistic concurrency conflict are not
+// retried, as the projector already restarts its consumer in that case.
+//
+// Every attempt uses the context passed by the projector, so all attempts
+// share the event's timeout. Retrying stops as soon as that context is
+// canceled.
+type Handler struct {
+	// Handler is the handler to which calls are forwarded.
+	Handler dogma.ProjectionMessageHandler
+
+	// MaxRetries is the maximum number of times that HandleEvent() is retried
+	// after the first attempt fails. If it is zero, DefaultMaxRetries is used.
+	// If it is negative, the event is never retried.
+	MaxRetries int
+
+	// Backoff returns the delay to wait before each retry. If it is nil,
+	// backoff.DefaultStrategy is used.
+	Backoff backoff.Strategy
+
+	// IsRetryable, if non-nil, returns true if err is a transient error that
+	// may be resolved by retrying. If it is nil, every error is retryable.
+	//
+	// Errors caused by the cancelation of the context are never retried.
+	IsRetryable func(err error) bool
+
+	// OnRetry, if non-nil, is called before each retry. n is the number of the
+	// retry, starting at 1, and err is the error that caused it.
+	OnRetry func(n int, err error)
+}
+
+var _ dogma.ProjectionMessageHandler = (*Handler)(nil)
+
+// Configure describes the handler's configuration to the engine.
+func (h *Handler) Configure(c dogma.ProjectionConfigurer) {
+	h.Handler.Configure(c)
+}
+
+// HandleEvent updates the projection to reflect the occurrence of an event.
+//
+// If the underlying handler returns a retryable error, the call is repeated
+// after a delay, up to h.MaxRetries times.
+func (h *Handler) HandleEvent(
+	ctx context.Context,
+	r, c, n []byte,
+	s dogma.ProjectionEventScope,
+	m dogma.Message,
+) (bool, error) {
+	counter := backoff.Counter{Strategy: h.Backoff}
+
+	for retry := 0; ; retry++ {
+		ok, err := h.Handler.HandleEvent(ctx, r, c, n, s, m)
+		if err == nil || retry >= h.maxRetries() || !h.retryable(ctx, err) {
+			return ok, err
+		}
+
+		if h.OnRetry != nil {
+			h.OnRetry(retry+1, err)
+		}
+
+		if err := counter.Sleep(ctx, err); err != nil {
+			return false, err
+		}
+	}
+}
+
+// ResourceVersion returns the version of the resource r.
+func (h *Handler) ResourceVersion(ctx context.Context, r []byte) ([]byte, error) {
+	return h.Handler.ResourceVersion(ctx, r)
+}
+
+// CloseResource informs the projection that the resource r will not be used
+// in any future calls to HandleEvent().
+func (h *Handler) CloseResource(ctx context.Context, r []byte) error {
+	return h.Handler.CloseResource(ctx, r)
+}
+
+// TimeoutHint returns a duration that is suitable for computing a deadline
+// for the handling of the given message by this handler.
+func (h *Handler) TimeoutHint(m dogma.Message) time.Duration {
+	return h.Handler.TimeoutHint(m)
+}
+
+// Compact reduces the size of the projection's data.
+func (h *Handler) Compact(ctx context.Context, s dogma.ProjectionCompactScope) error {
+	return h.Handler.Compact(ctx, s)
+}
+
+// maxRetries returns the maximum number of retries.
+func (h *Handler) maxRetries() int {
+	if h.MaxRetries == 0 {
+		return DefaultMaxRetries
+	}
+
+	return h.MaxRetries
+}
+
+// retryable returns true if the HandleEvent() call that failed with err should
+// be retried.
+func (h *Handler) retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if h.IsRetryable == nil {
+		return true
+	}
+
+	return h.IsRetryable(err)
+}
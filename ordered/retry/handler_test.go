@@ -0,0 +1,196 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/dogmatiq/aperture/ordered/retry"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	"github.com/dogmatiq/linger/backoff"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type Handler", func() {
+	var (
+		ctx      context.Context
+		cancel   context.CancelFunc
+		upstream *ProjectionMessageHandler
+		handler  *Handler
+		attempts int
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+		DeferCleanup(cancel)
+
+		attempts = 0
+		upstream = &ProjectionMessageHandler{}
+
+		handler = &Handler{
+			Handler: upstream,
+			Backoff: backoff.Constant(time.Millisecond),
+		}
+	})
+
+	failTimes := func(n int, err error) {
+		upstream.HandleEventFunc = func(
+			context.Context,
+			[]byte, []byte, []byte,
+			dogma.ProjectionEventScope,
+			dogma.Message,
+		) (bool, error) {
+			attempts++
+			if attempts <= n {
+				return false, err
+			}
+			return true, nil
+		}
+	}
+
+	handle := func() (bool, error) {
+		return handler.HandleEvent(ctx, nil, nil, nil, nil, MessageA1)
+	}
+
+	Describe("func HandleEvent()", func() {
+		It("retries the event until it succeeds", func() {
+			failTimes(2, errors.New("<error>"))
+
+			ok, err := handle()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(attempts).To(Equal(3))
+		})
+
+		It("returns the error once the maximum number of retries is exceeded", func() {
+			handler.MaxRetries = 2
+			failTimes(10, errors.New("<error>"))
+
+			_, err := handle()
+			Expect(err).To(MatchError("<error>"))
+			Expect(attempts).To(Equal(3))
+		})
+
+		It("uses DefaultMaxRetries by default", func() {
+			failTimes(10, errors.New("<error>"))
+
+			_, err := handle()
+			Expect(err).To(MatchError("<error>"))
+			Expect(attempts).To(Equal(DefaultMaxRetries + 1))
+		})
+
+		It("does not retry if MaxRetries is negative", func() {
+			handler.MaxRetries = -1
+			failTimes(10, errors.New("<error>"))
+
+			_, err := handle()
+			Expect(err).To(MatchError("<error>"))
+			Expect(attempts).To(Equal(1))
+		})
+
+		It("does not retry errors that are not retryable", func() {
+			retryable := errors.New("<retryable>")
+			handler.IsRetryable = func(err error) bool {
+				return errors.Is(err, retryable)
+			}
+			failTimes(10, errors.New("<error>"))
+
+			_, err := handle()
+			Expect(err).To(MatchError("<error>"))
+			Expect(attempts).To(Equal(1))
+		})
+
+		It("does not retry errors caused by context cancelation", func() {
+			failTimes(10, context.Canceled)
+
+			_, err := handle()
+			Expect(err).To(Equal(context.Canceled))
+			Expect(attempts).To(Equal(1))
+		})
+
+		It("stops retrying if the context is canceled while waiting", func() {
+			handler.Backoff = backoff.Constant(time.Hour)
+			handler.OnRetry = func(int, error) {
+				cancel()
+			}
+			failTimes(10, errors.New("<error>"))
+
+			_, err := handle()
+			Expect(err).To(Equal(context.Canceled))
+			Expect(attempts).To(Equal(1))
+		})
+
+		It("does not retry events that are not applied due to a conflict", func() {
+			upstream.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				attempts++
+				return false, nil
+			}
+
+			ok, err := handle()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ok).To(BeFalse())
+			Expect(attempts).To(Equal(1))
+		})
+
+		It("calls OnRetry before each retry", func() {
+			var retries []int
+			handler.OnRetry = func(n int, err error) {
+				Expect(err).To(MatchError("<error>"))
+				retries = append(retries, n)
+			}
+			failTimes(2, errors.New("<error>"))
+
+			_, err := handle()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(retries).To(Equal([]int{1, 2}))
+		})
+	})
+
+	It("forwards other methods to the underlying handler", func() {
+		upstream.ConfigureFunc = func(c dogma.ProjectionConfigurer) {
+			c.Identity("<proj>", "dab7d6ad-a729-4ab4-9496-a84b4e5b4893")
+		}
+		upstream.ResourceVersionFunc = func(context.Context, []byte) ([]byte, error) {
+			return []byte("<version>"), nil
+		}
+		upstream.TimeoutHintFunc = func(dogma.Message) time.Duration {
+			return 10 * time.Second
+		}
+		compacted := false
+		upstream.CompactFunc = func(context.Context, dogma.ProjectionCompactScope) error {
+			compacted = true
+			return nil
+		}
+
+		var id string
+		handler.Configure(&configurer{identity: &id})
+		Expect(id).To(Equal("<proj>"))
+
+		v, err := handler.ResourceVersion(ctx, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(v).To(Equal([]byte("<version>")))
+
+		Expect(handler.TimeoutHint(MessageA1)).To(Equal(10 * time.Second))
+
+		err = handler.Compact(ctx, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(compacted).To(BeTrue())
+	})
+})
+
+// configurer is a dogma.ProjectionConfigurer that records the handler's name.
+type configurer struct {
+	dogma.ProjectionConfigurer
+	identity *string
+}
+
+func (c *configurer) Identity(n, _ string) {
+	*c.identity = n
+}
@@ -0,0 +1,136 @@
+package ordered
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/dogmatiq/configkit/message"
+	"github.com/dogmatiq/dogma"
+)
+
+// RouteFunc is a function that applies events of a specific type to a
+// projection on behalf of a TypeRouter.
+//
+// The OCC semantics are the same as those of
+// dogma.ProjectionMessageHandler.HandleEvent().
+type RouteFunc func(
+	ctx context.Context,
+	r, c, n []byte,
+	s dogma.ProjectionEventScope,
+	m dogma.Message,
+) (ok bool, err error)
+
+// TypeRouter is an implementation of dogma.ProjectionMessageHandler that
+// dispatches each event to a function based on the event's type.
+//
+// The handler is configured to consume each of the event types in Routes.
+type TypeRouter struct {
+	// Name and Key are the identity of the projection.
+	Name, Key string
+
+	// Routes is a map of event type to the function used to apply events of
+	// that type to the projection.
+	Routes map[message.Type]RouteFunc
+
+	// Unrouted is called by HandleEvent() with events that have no route. If
+	// it is nil, HandleEvent() returns an error.
+	//
+	// Even if it does not otherwise apply the event, Unrouted must update the
+	// resource version to n when it returns ok == true, otherwise the OCC
+	// check for the next event fails.
+	//
+	// A Projector only passes the event types in Routes to the handler, so
+	// unrouted events only occur if Routes is modified after the handler has
+	// been configured.
+	Unrouted RouteFunc
+
+	// ResourceVersionFunc is called by ResourceVersion(). If it is nil, the
+	// empty version is returned for every resource.
+	ResourceVersionFunc func(ctx context.Context, r []byte) ([]byte, error)
+
+	// CloseResourceFunc is called by CloseResource(). If it is nil, closing a
+	// resource is a no-op.
+	CloseResourceFunc func(ctx context.Context, r []byte) error
+
+	// CompactFunc is called by Compact(). If it is nil, compaction is a no-op.
+	CompactFunc func(ctx context.Context, s dogma.ProjectionCompactScope) error
+}
+
+// Configure produces a configuration for this handler by calling methods on
+// the configurer c.
+func (h *TypeRouter) Configure(c dogma.ProjectionConfigurer) {
+	c.Identity(h.Name, h.Key)
+
+	types := make([]message.Type, 0, len(h.Routes))
+	for t := range h.Routes {
+		types = append(types, t)
+	}
+
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].String() < types[j].String()
+	})
+
+	for _, t := range types {
+		c.ConsumesEventType(
+			reflect.Zero(t.ReflectType()).Interface().(dogma.Message),
+		)
+	}
+}
+
+// HandleEvent updates the projection to reflect the occurrence of an event by
+// calling the RouteFunc for the event's type.
+func (h *TypeRouter) HandleEvent(
+	ctx context.Context,
+	r, c, n []byte,
+	s dogma.ProjectionEventScope,
+	m dogma.Message,
+) (bool, error) {
+	if fn, ok := h.Routes[message.TypeOf(m)]; ok {
+		return fn(ctx, r, c, n, s, m)
+	}
+
+	if h.Unrouted != nil {
+		return h.Unrouted(ctx, r, c, n, s, m)
+	}
+
+	return false, fmt.Errorf("no route for %T events", m)
+}
+
+// ResourceVersion returns the version of the resource r.
+func (h *TypeRouter) ResourceVersion(ctx context.Context, r []byte) ([]byte, error) {
+	if h.ResourceVersionFunc != nil {
+		return h.ResourceVersionFunc(ctx, r)
+	}
+
+	return nil, nil
+}
+
+// CloseResource informs the projection that the resource r will not be used
+// in any future calls to HandleEvent().
+func (h *TypeRouter) CloseResource(ctx context.Context, r []byte) error {
+	if h.CloseResourceFunc != nil {
+		return h.CloseResourceFunc(ctx, r)
+	}
+
+	return nil
+}
+
+// TimeoutHint returns a duration that is suitable for computing a deadline
+// for the handling of the given message by this handler.
+//
+// It always returns zero, such that the projector's default timeout is used.
+func (h *TypeRouter) TimeoutHint(m dogma.Message) time.Duration {
+	return 0
+}
+
+// Compact reduces the size of the projection's data.
+func (h *TypeRouter) Compact(ctx context.Context, s dogma.ProjectionCompactScope) error {
+	if h.CompactFunc != nil {
+		return h.CompactFunc(ctx, s)
+	}
+
+	return nil
+}
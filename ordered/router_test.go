@@ -0,0 +1,261 @@
+package ordered_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/configkit"
+	"github.com/dogmatiq/configkit/message"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ dogma.ProjectionMessageHandler = (*TypeRouter)(nil)
+
+var _ = Describe("type TypeRouter", func() {
+	var (
+		ctx      context.Context
+		cancel   func()
+		messages []dogma.Message
+		router   *TypeRouter
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), DefaultTimeout*2)
+
+		messages = nil
+		route := func(
+			_ context.Context,
+			_, _, _ []byte,
+			_ dogma.ProjectionEventScope,
+			m dogma.Message,
+		) (bool, error) {
+			messages = append(messages, m)
+			return true, nil
+		}
+
+		router = &TypeRouter{
+			Name: "<proj>",
+			Key:  "45804515-8b41-4d23-97b1-0cda5a0d782c",
+			Routes: map[message.Type]RouteFunc{
+				message.TypeOf(MessageA{}): route,
+				message.TypeOf(MessageB{}): route,
+			},
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Describe("func Configure()", func() {
+		It("configures the handler to consume each of the routed types", func() {
+			cfg := configkit.FromProjection(router)
+			Expect(cfg.Identity()).To(Equal(
+				configkit.MustNewIdentity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c"),
+			))
+
+			consumed := cfg.MessageTypes().Consumed
+			Expect(consumed.Len()).To(Equal(2))
+			Expect(consumed.Has(message.TypeOf(MessageA{}))).To(BeTrue())
+			Expect(consumed.Has(message.TypeOf(MessageB{}))).To(BeTrue())
+		})
+	})
+
+	Describe("func HandleEvent()", func() {
+		It("calls the route function for the event's type", func() {
+			ok, err := router.HandleEvent(ctx, nil, nil, nil, nil, MessageB1)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(messages).To(Equal([]dogma.Message{MessageB1}))
+		})
+
+		It("returns an error if the event's type has no route", func() {
+			_, err := router.HandleEvent(ctx, nil, nil, nil, nil, MessageC1)
+			Expect(err).To(MatchError("no route for fixtures.MessageC events"))
+		})
+
+		It("calls Unrouted if the event's type has no route", func() {
+			router.Unrouted = func(
+				_ context.Context,
+				_, _, n []byte,
+				_ dogma.ProjectionEventScope,
+				m dogma.Message,
+			) (bool, error) {
+				Expect(n).To(Equal([]byte("<next>")))
+				Expect(m).To(Equal(MessageC1))
+				return true, nil
+			}
+
+			ok, err := router.HandleEvent(ctx, nil, nil, []byte("<next>"), nil, MessageC1)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(messages).To(BeEmpty())
+		})
+	})
+
+	Describe("func ResourceVersion()", func() {
+		It("returns the empty version if ResourceVersionFunc is nil", func() {
+			v, err := router.ResourceVersion(ctx, []byte("<resource>"))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(v).To(BeEmpty())
+		})
+
+		It("calls ResourceVersionFunc", func() {
+			router.ResourceVersionFunc = func(
+				_ context.Context,
+				r []byte,
+			) ([]byte, error) {
+				Expect(r).To(Equal([]byte("<resource>")))
+				return nil, errors.New("<error>")
+			}
+
+			_, err := router.ResourceVersion(ctx, []byte("<resource>"))
+			Expect(err).To(MatchError("<error>"))
+		})
+	})
+
+	Describe("func CloseResource()", func() {
+		It("calls CloseResourceFunc", func() {
+			router.CloseResourceFunc = func(context.Context, []byte) error {
+				return errors.New("<error>")
+			}
+
+			err := router.CloseResource(ctx, []byte("<resource>"))
+			Expect(err).To(MatchError("<error>"))
+		})
+	})
+
+	Describe("func Compact()", func() {
+		It("calls CompactFunc", func() {
+			router.CompactFunc = func(context.Context, dogma.ProjectionCompactScope) error {
+				return errors.New("<error>")
+			}
+
+			err := router.Compact(ctx, nil)
+			Expect(err).To(MatchError("<error>"))
+		})
+	})
+
+	It("can be used by a projector", func() {
+		stream := &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		stream.Append(
+			time.Now(),
+			MessageA1,
+			MessageC1,
+			MessageB1,
+		)
+
+		router.Routes[message.TypeOf(MessageB{})] = func(
+			_ context.Context,
+			_, _, _ []byte,
+			_ dogma.ProjectionEventScope,
+			m dogma.Message,
+		) (bool, error) {
+			messages = append(messages, m)
+			cancel()
+			return true, nil
+		}
+
+		proj := &Projector{
+			Stream:  stream,
+			Handler: router,
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+		Expect(messages).To(Equal(
+			[]dogma.Message{MessageA1, MessageB1},
+		))
+	})
+
+	It("continues with routed events after an unrouted event", func() {
+		stream := &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		stream.Append(
+			time.Now(),
+			MessageA1,
+			MessageB1,
+			MessageC1,
+		)
+
+		var version []byte
+		store := func(c, n []byte) bool {
+			if !bytes.Equal(c, version) {
+				return false
+			}
+			version = n
+			return true
+		}
+
+		router.ResourceVersionFunc = func(context.Context, []byte) ([]byte, error) {
+			return version, nil
+		}
+
+		router.Routes = map[message.Type]RouteFunc{
+			message.TypeOf(MessageA{}): func(
+				_ context.Context,
+				_, c, n []byte,
+				_ dogma.ProjectionEventScope,
+				m dogma.Message,
+			) (bool, error) {
+				// Remove the route for MessageB after the handler has been
+				// configured, such that MessageB1 is unrouted.
+				delete(router.Routes, message.TypeOf(MessageB{}))
+				messages = append(messages, m)
+				return store(c, n), nil
+			},
+			message.TypeOf(MessageB{}): func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				panic("unexpected call")
+			},
+			message.TypeOf(MessageC{}): func(
+				_ context.Context,
+				_, c, n []byte,
+				_ dogma.ProjectionEventScope,
+				m dogma.Message,
+			) (bool, error) {
+				ok := store(c, n)
+				if ok {
+					messages = append(messages, m)
+					cancel()
+				}
+				return ok, nil
+			},
+		}
+
+		router.Unrouted = func(
+			_ context.Context,
+			_, c, n []byte,
+			_ dogma.ProjectionEventScope,
+			_ dogma.Message,
+		) (bool, error) {
+			return store(c, n), nil
+		}
+
+		proj := &Projector{
+			Stream:  stream,
+			Handler: router,
+		}
+
+		err := proj.Run(ctx)
+		Expect(err).To(Equal(context.Canceled))
+		Expect(messages).To(Equal(
+			[]dogma.Message{MessageA1, MessageC1},
+		))
+	})
+})
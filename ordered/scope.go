@@ -1,19 +1,40 @@
 package ordered
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/dogmatiq/dodeca/logging"
+	"github.com/dogmatiq/dogma"
 )
 
-// eventScope is an implementation of dogma.ProjectionEventScope.
+// EventScope is the dogma.ProjectionEventScope passed to handlers by a
+// Projector.
+//
+// Handlers may type-assert the scope they are given to EventScope to obtain
+// information about the event that the Dogma interface does not expose.
+type EventScope interface {
+	dogma.ProjectionEventScope
+
+	// MessageID returns a stable identifier for the event that is unique
+	// across all streams, suitable for use when deduplicating events. It is
+	// empty if the event is synthetic.
+	MessageID() string
+}
+
+// eventScope is an implementation of EventScope.
 type eventScope struct {
 	resource   []byte
 	offset     uint64
+	messageID  string
 	handler    string
 	recordedAt time.Time
+	ctx        context.Context
 	logger     logging.Logger
+	slog       *slog.Logger
+	format     func(LogContext) string
 }
 
 // RecordedAt returns the time at which the event was recorded.
@@ -21,6 +42,12 @@ func (s eventScope) RecordedAt() time.Time {
 	return s.recordedAt
 }
 
+// MessageID returns a stable identifier for the event that is unique across
+// all streams. It is empty if the event is synthetic.
+func (s eventScope) MessageID() string {
+	return s.messageID
+}
+
 // IsPrimaryDelivery returns true on one of the application instances that
 // receive the event, and false on all other instances.
 func (s eventScope) IsPrimaryDelivery() bool {
@@ -30,34 +57,73 @@ func (s eventScope) IsPrimaryDelivery() bool {
 // Log records an informational message within the context of the message
 // that is being handled.
 func (s eventScope) Log(f string, v ...interface{}) {
+	if s.slog != nil {
+		s.slog.InfoContext(
+			s.ctx,
+			fmt.Sprintf(f, v...),
+			slog.String("handler", s.handler),
+			slog.String("resource", string(s.resource)),
+			slog.Uint64("offset", s.offset),
+		)
+		return
+	}
+
 	logging.Log(
 		s.logger,
-		"[%s %s@%d] %s",
-		s.handler,
-		s.resource,
-		s.offset,
+		"%s %s",
+		logPrefix(
+			s.format,
+			LogContext{
+				Handler:  s.handler,
+				Resource: s.resource,
+				Offset:   s.offset,
+			},
+		),
 		fmt.Sprintf(f, v...),
 	)
 }
 
 // compactScope is an implementation of dogma.ProjectionCompactScope.
 type compactScope struct {
-	handler string
-	logger  logging.Logger
+	handler  string
+	resource []byte
+	ctx      context.Context
+	logger   logging.Logger
+	slog     *slog.Logger
+	format   func(LogContext) string
+	clock    Clock
 }
 
 // Log records an informational message within the context of the message
 // that is being handled.
 func (s compactScope) Log(f string, v ...interface{}) {
+	if s.slog != nil {
+		s.slog.InfoContext(
+			s.ctx,
+			fmt.Sprintf(f, v...),
+			slog.String("handler", s.handler),
+			slog.String("resource", string(s.resource)),
+			slog.Bool("compact", true),
+		)
+		return
+	}
+
 	logging.Log(
 		s.logger,
-		"[%s compact] %s",
-		s.handler,
+		"%s %s",
+		logPrefix(
+			s.format,
+			LogContext{
+				Handler:  s.handler,
+				Resource: s.resource,
+				Compact:  true,
+			},
+		),
 		fmt.Sprintf(f, v...),
 	)
 }
 
 // Now returns the current time.
 func (s compactScope) Now() time.Time {
-	return time.Now()
+	return s.clock.Now()
 }
@@ -0,0 +1,71 @@
+package ordered
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// settings holds the durations that can be changed while the projector is
+// running. A nil pointer indicates that the value of the corresponding field
+// on Projector is used.
+type settings struct {
+	defaultTimeout     atomic.Pointer[time.Duration]
+	compactionInterval atomic.Pointer[time.Duration]
+	compactionTimeout  atomic.Pointer[time.Duration]
+}
+
+// SetDefaultTimeout changes the timeout duration to use when handling an event
+// if the handler does not provide a timeout hint.
+//
+// It overrides the DefaultTimeout field and is safe to call while Run() is
+// executing. The change takes effect for the next event that is handled.
+func (p *Projector) SetDefaultTimeout(d time.Duration) {
+	p.settings.defaultTimeout.Store(&d)
+}
+
+// SetCompactionInterval changes the interval at which the projector compacts
+// the projection.
+//
+// It overrides the CompactionInterval field and is safe to call while Run() is
+// executing. The change takes effect the next time the projector begins
+// waiting to compact the projection; a wait that is already in progress is not
+// affected.
+//
+// A negative interval disables compaction, as per the CompactionInterval field.
+// Once disabled, compaction is not resumed until Run() is called again.
+func (p *Projector) SetCompactionInterval(d time.Duration) {
+	p.settings.compactionInterval.Store(&d)
+}
+
+// SetCompactionTimeout changes the timeout to use when compacting the
+// projection, with the same semantics as the CompactionTimeout field.
+//
+// It overrides the CompactionTimeout field and is safe to call while Run() is
+// executing. The change takes effect for the next compaction.
+func (p *Projector) SetCompactionTimeout(d time.Duration) {
+	p.settings.compactionTimeout.Store(&d)
+}
+
+// defaultTimeout returns the default timeout to use when handling an event.
+func (p *Projector) defaultTimeout() time.Duration {
+	return load(&p.settings.defaultTimeout, p.DefaultTimeout)
+}
+
+// compactionInterval returns the interval at which to compact the projection.
+func (p *Projector) compactionInterval() time.Duration {
+	return load(&p.settings.compactionInterval, p.CompactionInterval)
+}
+
+// compactionTimeout returns the timeout to use when compacting the projection.
+func (p *Projector) compactionTimeout() time.Duration {
+	return load(&p.settings.compactionTimeout, p.CompactionTimeout)
+}
+
+// load returns the value stored in v, or def if v is nil.
+func load(v *atomic.Pointer[time.Duration], def time.Duration) time.Duration {
+	if d := v.Load(); d != nil {
+		return *d
+	}
+
+	return def
+}
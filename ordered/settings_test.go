@@ -0,0 +1,115 @@
+package ordered_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type Projector (runtime settings)", func() {
+	var (
+		ctx     context.Context
+		cancel  func()
+		handler *ProjectionMessageHandler
+		proj    *Projector
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), DefaultTimeout*2)
+
+		stream := &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		stream.Append(
+			time.Now(),
+			MessageA1,
+			MessageA2,
+		)
+
+		handler = &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageA{})
+			},
+		}
+
+		proj = &Projector{
+			Stream:  stream,
+			Handler: handler,
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Describe("func SetDefaultTimeout()", func() {
+		It("changes the timeout for subsequent events", func() {
+			proj.DefaultTimeout = 500 * time.Millisecond
+
+			count := 0
+			handler.HandleEventFunc = func(
+				ctx context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				_ dogma.Message,
+			) (bool, error) {
+				count++
+				dl, ok := ctx.Deadline()
+				Expect(ok).To(BeTrue())
+
+				if count == 1 {
+					Expect(dl).To(BeTemporally("~", time.Now().Add(500*time.Millisecond)))
+					proj.SetDefaultTimeout(200 * time.Millisecond)
+					return true, nil
+				}
+
+				Expect(dl).To(BeTemporally("~", time.Now().Add(200*time.Millisecond)))
+				cancel()
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+			Expect(count).To(Equal(2))
+		})
+	})
+
+	Describe("func SetCompactionInterval()", func() {
+		It("changes the interval between compactions", func() {
+			proj.DeferInitialCompaction = true
+			proj.SetCompactionInterval(10 * time.Millisecond)
+
+			handler.CompactFunc = func(context.Context, dogma.ProjectionCompactScope) error {
+				cancel()
+				return nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+		})
+	})
+
+	Describe("func SetCompactionTimeout()", func() {
+		It("changes the compaction timeout", func() {
+			proj.SetCompactionTimeout(200 * time.Millisecond)
+
+			handler.CompactFunc = func(ctx context.Context, _ dogma.ProjectionCompactScope) error {
+				dl, ok := ctx.Deadline()
+				Expect(ok).To(BeTrue())
+				Expect(dl).To(BeTemporally("~", time.Now().Add(200*time.Millisecond)))
+				cancel()
+				return nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+		})
+	})
+})
@@ -0,0 +1,118 @@
+package ordered
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dogmatiq/aperture/marshaling"
+)
+
+// snapshot is the serialized representation of a MemoryStream.
+type snapshot struct {
+	StreamID string          `json:"stream_id"`
+	First    uint64          `json:"first"`
+	Next     uint64          `json:"next"`
+	Sealed   bool            `json:"sealed,omitempty"`
+	Events   []snapshotEvent `json:"events"`
+}
+
+// snapshotEvent is the serialized representation of a single event within a
+// snapshot.
+type snapshotEvent struct {
+	Offset     uint64    `json:"offset"`
+	RecordedAt time.Time `json:"recorded_at"`
+	TypeID     string    `json:"type_id"`
+	Data       []byte    `json:"data"`
+}
+
+// Snapshot writes the stream's ID, its retained events, the offsets of the
+// first and next events and whether it is sealed to w.
+//
+// The event messages are marshaled using m, which must support every type of
+// message on the stream. The snapshot can be restored using
+// LoadMemoryStream(). OnTruncate and MaxLen are not included in the snapshot.
+func (s *MemoryStream) Snapshot(w io.Writer, m marshaling.Marshaler) error {
+	s.m.RLock()
+
+	snap := snapshot{
+		StreamID: s.StreamID,
+		First:    s.first,
+		Next:     s.next,
+		Sealed:   s.sealed,
+		Events:   make([]snapshotEvent, len(s.messages)),
+	}
+
+	for i, env := range s.messages {
+		data, id, err := m.Marshal(env.Message)
+		if err != nil {
+			s.m.RUnlock()
+			return fmt.Errorf("unable to marshal event at offset %d: %w", env.Offset, err)
+		}
+
+		snap.Events[i] = snapshotEvent{
+			Offset:     env.Offset,
+			RecordedAt: env.RecordedAt,
+			TypeID:     id,
+			Data:       data,
+		}
+	}
+
+	s.m.RUnlock()
+
+	return json.NewEncoder(w).Encode(snap)
+}
+
+// LoadMemoryStream returns a new MemoryStream restored from a snapshot written
+// by MemoryStream.Snapshot().
+//
+// The event messages are unmarshaled using m. Every event retains the offset
+// it had when the snapshot was written, so a projection resumes from the
+// same position. If the stream was sealed, the restored stream is also sealed.
+//
+// The snapshot does not include each envelope's MessageID. It is derived again
+// from the stream ID and offset by the restored stream's cursors, so it is the
+// same as it was before the snapshot was written.
+func LoadMemoryStream(r io.Reader, m marshaling.Marshaler) (*MemoryStream, error) {
+	var snap snapshot
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("unable to decode snapshot: %w", err)
+	}
+
+	if snap.First > snap.Next || snap.Next-snap.First != uint64(len(snap.Events)) {
+		return nil, errors.New("snapshot is inconsistent, the number of events does not match the offsets")
+	}
+
+	s := &MemoryStream{
+		StreamID: snap.StreamID,
+		first:    snap.First,
+		next:     snap.Next,
+		sealed:   snap.Sealed,
+		messages: make([]Envelope, len(snap.Events)),
+	}
+
+	for i, ev := range snap.Events {
+		if ev.Offset != snap.First+uint64(i) {
+			return nil, fmt.Errorf(
+				"snapshot is inconsistent, expected event at offset %d, found offset %d",
+				snap.First+uint64(i),
+				ev.Offset,
+			)
+		}
+
+		msg, err := m.Unmarshal(ev.TypeID, ev.Data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to unmarshal event at offset %d: %w", ev.Offset, err)
+		}
+
+		s.messages[i] = Envelope{
+			Offset:     ev.Offset,
+			RecordedAt: ev.RecordedAt,
+			Message:    msg,
+		}
+	}
+
+	return s, nil
+}
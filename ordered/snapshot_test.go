@@ -0,0 +1,124 @@
+package ordered_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/dogmatiq/aperture/marshaling"
+	. "github.com/dogmatiq/aperture/ordered"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("func LoadMemoryStream()", func() {
+	var (
+		ctx       context.Context
+		cancel    context.CancelFunc
+		now       time.Time
+		marshaler marshaling.Marshaler
+		stream    *MemoryStream
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+		DeferCleanup(cancel)
+
+		now = time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+
+		var err error
+		marshaler, err = marshaling.NewMarshaler(MessageA{}, MessageB{})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		stream = &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		stream.Append(
+			now,
+			MessageA1,
+			MessageB1,
+			MessageA2,
+		)
+	})
+
+	roundTrip := func() *MemoryStream {
+		var buf bytes.Buffer
+		err := stream.Snapshot(&buf, marshaler)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		s, err := LoadMemoryStream(&buf, marshaler)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		return s
+	}
+
+	It("restores the stream ID and events", func() {
+		s := roundTrip()
+		Expect(s.ID()).To(Equal("<id>"))
+
+		cur, err := s.Open(ctx, 0, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		defer cur.Close()
+
+		for i, m := range []any{MessageA1, MessageB1, MessageA2} {
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeEquivalentTo(i))
+			Expect(env.Message).To(Equal(m))
+			Expect(env.RecordedAt).To(BeTemporally("==", now))
+			Expect(env.MessageID).To(Equal(MessageID("<id>", uint64(i))))
+		}
+	})
+
+	It("preserves the offsets of truncated streams", func() {
+		stream.Truncate(2)
+
+		s := roundTrip()
+		Expect(s.FirstOffset()).To(BeEquivalentTo(2))
+		Expect(s.NextOffset()).To(BeEquivalentTo(3))
+
+		cur, err := s.Open(ctx, 2, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		defer cur.Close()
+
+		env, err := cur.Next(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(env.Offset).To(BeEquivalentTo(2))
+		Expect(env.Message).To(Equal(MessageA2))
+
+		s.Append(now, MessageB2)
+
+		env, err = cur.Next(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(env.Offset).To(BeEquivalentTo(3))
+		Expect(env.Message).To(Equal(MessageB2))
+	})
+
+	It("restores sealed streams as sealed", func() {
+		stream.Seal()
+
+		s := roundTrip()
+
+		_, final, err := s.Head(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(final).To(BeTrue())
+	})
+
+	It("returns an error if a message can not be marshaled", func() {
+		stream.Append(now, MessageC1)
+
+		err := stream.Snapshot(&bytes.Buffer{}, marshaler)
+		Expect(err).To(MatchError(ContainSubstring("unable to marshal event at offset 3")))
+	})
+
+	It("returns an error if the snapshot is inconsistent", func() {
+		_, err := LoadMemoryStream(
+			strings.NewReader(`{"stream_id":"<id>","first":0,"next":2,"events":[]}`),
+			marshaler,
+		)
+		Expect(err).Should(HaveOccurred())
+	})
+})
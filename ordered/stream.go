@@ -13,8 +13,87 @@ import (
 
 // ErrStreamSealed is returned by Stream.Open() and Cursor.Next() to indicate
 // that a stream will never produce any more events.
+//
+// Implementations may return an error that wraps ErrStreamSealed, such as a
+// *SealedError, use errors.Is() to check for this condition.
 var ErrStreamSealed = errors.New("stream sealed")
 
+// ErrStartOfStream is returned by the Next() method of a cursor opened by
+// ReverseStream.OpenReverse() once it has returned every relevant event down to
+// the start of the stream.
+var ErrStartOfStream = errors.New("reached the start of the stream")
+
+// SealedError is returned by Stream.Open() when the requested offset is beyond
+// the end of a sealed stream.
+//
+// errors.Is(err, ErrStreamSealed) is true for any *SealedError.
+type SealedError struct {
+	// RequestedOffset is the offset that was passed to Stream.Open().
+	RequestedOffset uint64
+
+	// LastOffset is the offset of the last event on the stream. It is zero if
+	// the stream contains no events.
+	LastOffset uint64
+}
+
+func (e *SealedError) Error() string {
+	return fmt.Sprintf(
+		"%s: can not open at offset %d, the last offset is %d",
+		ErrStreamSealed,
+		e.RequestedOffset,
+		e.LastOffset,
+	)
+}
+
+// Is returns true if target is ErrStreamSealed.
+func (e *SealedError) Is(target error) bool {
+	return target == ErrStreamSealed
+}
+
+// sealedError returns a *SealedError for an attempt to open a sealed stream at
+// the given offset, where next is the offset after the last event.
+func sealedError(offset, next uint64) *SealedError {
+	err := &SealedError{RequestedOffset: offset}
+	if next > 0 {
+		err.LastOffset = next - 1
+	}
+	return err
+}
+
+// TruncatedError is returned by Cursor.Next() when the next event to be read
+// has been truncated from the start of the stream.
+type TruncatedError struct {
+	// RequestedOffset is the offset of the event that was to be read.
+	RequestedOffset uint64
+
+	// FirstOffset is the offset of the first event that remains on the stream.
+	FirstOffset uint64
+}
+
+func (e *TruncatedError) Error() string {
+	return fmt.Sprintf(
+		"can not read truncated event at offset %d, the first available offset is %d",
+		e.RequestedOffset,
+		e.FirstOffset,
+	)
+}
+
+// FilterNone is a stream filter that matches no events.
+//
+// A cursor opened with this filter never returns any events. Its Next() method
+// blocks until ctx is canceled or the stream is sealed. This is useful for
+// detecting when a stream is sealed without consuming its events.
+//
+// Stream implementations need not treat this filter specially, as it contains
+// a single message type that never appears on any stream.
+var FilterNone = []dogma.Message{noEvents{}}
+
+// noEvents is a message type that never appears on a stream.
+type noEvents struct{}
+
+func (noEvents) MessageDescription() string { return "no events" }
+func (noEvents) Validate() error            { return nil }
+
 // A Stream is an ordered sequence of event messages.
 //
 // Stream implementations may optionally allow for streams to be marked as
@@ -29,14 +108,133 @@ type Stream interface {
 	//
 	// offset is the position of the first event to read. The first event on a
 	// stream is always at offset 0. If the given offset is beyond the end of a
-	// sealed stream, ErrStreamSealed is returned.
+	// sealed stream, an error that wraps ErrStreamSealed is returned.
 	//
 	// filter is a set of zero-value event messages, the types of which indicate
 	// which event types are returned by Cursor.Next(). If filter is empty, all
-	// events types are returned.
+	// events types are returned. If filter is FilterNone, no events are
+	// returned.
 	Open(ctx context.Context, offset uint64, filter []dogma.Message) (Cursor, error)
 }
 
+// A HeadStream is a Stream that can report the offset of its head.
+type HeadStream interface {
+	Stream
+
+	// Head returns the offset of the stream's head, that is, the offset at
+	// which the next event will be appended.
+	//
+	// final is true if the stream is sealed, in which case the head never
+	// changes.
+	Head(ctx context.Context) (offset uint64, final bool, err error)
+}
+
+// A SealedStream is a Stream that can report whether it is sealed without
+// opening a cursor.
+type SealedStream interface {
+	Stream
+
+	// IsSealed returns true if the stream is sealed, in which case no new
+	// events will ever be appended to it.
+	IsSealed(ctx context.Context) (bool, error)
+}
+
+// A BoundsStream is a Stream that can report the range of offsets that may be
+// read from it.
+type BoundsStream interface {
+	Stream
+
+	// Bounds returns the range of offsets of the events that are available
+	// on the stream.
+	//
+	// first is the offset of the first event that can be read, which is
+	// greater than zero if events have been truncated from the start of the
+	// stream. next is the offset at which the next event will be appended.
+	// The stream is empty if first == next.
+	Bounds(ctx context.Context) (first, next uint64, err error)
+}
+
+// A CountStream is a Stream that can report the number of events it contains.
+//
+// The count may be lower than the offset of the stream's head, for example if
+// events have been truncated from the start of the stream, or if the stream's
+// offsets are not contiguous.
+type CountStream interface {
+	Stream
+
+	// Count returns the number of events on the stream.
+	//
+	// exact is false if count is only an estimate, as may be the case for
+	// streams backed by durable storage.
+	Count(ctx context.Context) (count uint64, exact bool)
+}
+
+// A TailStream is a Stream that can open a cursor at its head atomically.
+type TailStream interface {
+	Stream
+
+	// OpenTail returns a cursor used to read events that are appended to the
+	// stream after the cursor is opened.
+	//
+	// offset is the offset of the first event that the cursor reads, which is
+	// the offset of the stream's head at the time the cursor is opened. No
+	// events are skipped or duplicated between determining the head and
+	// opening the cursor. If the stream is sealed, an error that wraps
+	// ErrStreamSealed is returned.
+	//
+	// filter has the same semantics as for Open().
+	OpenTail(ctx context.Context, filter []dogma.Message) (cur Cursor, offset uint64, err error)
+}
+
+// A TypedStream is a Stream that can report the event types that it produces.
+type TypedStream interface {
+	Stream
+
+	// Types returns the types of the events that may appear on the stream.
+	Types() message.TypeCollection
+}
+
+// A ConsumerStream is a Stream that requires each consumer to identify itself
+// when opening a cursor.
+//
+// It is intended for backends that track consumers by name, such as those
+// that support consumer groups or durable subscriptions.
+type ConsumerStream interface {
+	Stream
+
+	// OpenAs returns a cursor used to read events from this stream on behalf
+	// of the consumer identified by consumerID.
+	//
+	// consumerID must be stable across restarts of the consumer. The offset
+	// and filter parameters have the same semantics as for Open().
+	OpenAs(
+		ctx context.Context,
+		consumerID string,
+		offset uint64,
+		filter []dogma.Message,
+	) (Cursor, error)
+}
+
+// A ReverseStream is a Stream that can read its events in reverse order.
+//
+// It allows the most recent events on a stream to be read without first
+// reading all of the events that precede them.
+type ReverseStream interface {
+	Stream
+
+	// OpenReverse returns a cursor that reads events from this stream in
+	// descending offset order.
+	//
+	// offset is the offset of the first event to read. If it is beyond the
+	// last event on the stream, the cursor begins with the last event. filter
+	// has the same semantics as for Open().
+	//
+	// The cursor's Next() method never blocks waiting for events. It returns
+	// ErrStartOfStream once it has returned the event at the start of the
+	// stream, or the first event that has not been truncated.
+	OpenReverse(ctx context.Context, offset uint64, filter []dogma.Message) (Cursor, error)
+}
+
 // A Cursor reads events from a stream.
 //
 // Cursors are not intended to be used by multiple goroutines concurrently.
@@ -46,6 +244,14 @@ type Cursor interface {
 	// If the end of the stream is reached it blocks until a relevant event is
 	// appended to the stream, ctx is canceled or the stream is sealed. If the
 	// stream is sealed, ErrStreamSealed is returned.
+	//
+	// If ctx is canceled before an event is returned, the cursor's position
+	// within the stream must not be advanced.
+	//
+	// The offset of each event must be greater than the offset of the event
+	// returned by the previous call, and no less than the offset at which the
+	// cursor was opened. Synthetic events are exempt. See
+	// Projector.StrictOrdering.
 	Next(ctx context.Context) (Envelope, error)
 
 	// Close stops the cursor.
@@ -54,6 +260,62 @@ type Cursor interface {
 	Close() error
 }
 
+// A NonBlockingCursor is a Cursor that can read an event without waiting for
+// one to be appended to the stream.
+type NonBlockingCursor interface {
+	Cursor
+
+	// TryNext returns the next relevant event in the stream, if one is
+	// immediately available.
+	//
+	// ok is false if the end of the stream has been reached. If the stream is
+	// sealed, ErrStreamSealed is returned.
+	TryNext(ctx context.Context) (env Envelope, ok bool, err error)
+}
+
+// A FilterCursor is a Cursor whose message-type filter can be changed after it
+// is opened.
+type FilterCursor interface {
+	Cursor
+
+	// SetFilter replaces the cursor's message-type filter.
+	//
+	// filter has the same semantics as for Stream.Open(). The new filter
+	// applies to events read from the cursor's current position onwards;
+	// events that have already been returned by the cursor are never
+	// re-read, even if they were excluded by the previous filter.
+	SetFilter(filter []dogma.Message)
+}
+
+// A PredicateCursor is a Cursor that can skip events based on their content,
+// in addition to their type.
+type PredicateCursor interface {
+	Cursor
+
+	// SetPredicate sets a function that is called with each event that matches
+	// the cursor's message-type filter. Events for which it returns false are
+	// skipped. If fn is nil, no events are skipped.
+	//
+	// fn is called while reading from the stream, and hence must be cheap. It
+	// must not use the stream or the cursor. Like SetFilter(), the predicate
+	// applies to events read from the cursor's current position onwards.
+	SetPredicate(fn func(Envelope) bool)
+}
+
+// A PeekCursor is a Cursor that can return the next event without advancing
+// past it.
+type PeekCursor interface {
+	Cursor
+
+	// Peek returns the next relevant event in the stream without advancing
+	// the cursor's position. A subsequent call to Next() returns the same
+	// event.
+	//
+	// It blocks in the same way as Next() when the end of the stream is
+	// reached.
+	Peek(ctx context.Context) (Envelope, error)
+}
+
 // Envelope is a container for an event on a stream.
 type Envelope struct {
 	// Offset is the zero-based offset of the message on the stream.
@@ -64,6 +326,33 @@ type Envelope struct {
 
 	// Message is the application-defined message.
 	Message dogma.Message
+
+	// MessageID is a stable identifier for the message that is unique across
+	// all streams, as produced by MessageID(). It is empty for synthetic
+	// events.
+	//
+	// The cursors provided by this package always populate MessageID. If a
+	// cursor returns an envelope without one, the projector derives it from
+	// the stream ID and offset.
+	MessageID string
+
+	// Synthetic is true if the event was generated by the cursor rather than
+	// read from the stream, such as the heartbeats returned by a
+	// HeartbeatStream.
+	//
+	// A synthetic event does not occupy an offset on the stream. Its Offset is
+	// that of the next real event the cursor would return. A projector applies
+	// synthetic events without advancing the projection's version.
+	Synthetic bool
+}
+
+// MessageID returns a stable, unique identifier for the event at the given
+// offset of the stream with the given ID.
+//
+// It produces "<stream ID>@<offset>", which is unique because the tuple of
+// stream ID and offset uniquely identifies a message.
+func MessageID(streamID string, offset uint64) string {
+	return fmt.Sprintf("%s@%d", streamID, offset)
 }
 
 // MemoryStream is an implementation of Stream that stores messages in-memory.
@@ -74,8 +363,22 @@ type MemoryStream struct {
 	// The tuple of stream ID and event offset must uniquely identify a message.
 	StreamID string
 
+	// OnTruncate, if non-nil, is called after Truncate() discards events from
+	// the stream. first is the offset of the first event that remains on the
+	// stream and count is the number of events that were discarded.
+	//
+	// It is called after the stream's internal lock is released, so it may
+	// safely call other methods on the stream.
+	OnTruncate func(first, count uint64)
+
+	// MaxLen is the maximum number of events retained by the stream. If it is
+	// positive, the oldest events are truncated after each append such that no
+	// more than MaxLen events remain, and OnTruncate is called as though
+	// Truncate() had been called. If it is zero, the stream is unbounded.
+	MaxLen int
+
 	m        sync.RWMutex
-	ready    chan struct{}
+	waiters  map[*memoryCursor]chan struct{}
 	first    uint64
 	next     uint64
 	sealed   bool
@@ -97,11 +400,11 @@ func (s *MemoryStream) ID() string {
 //
 // offset is the position of the first event to read. The first event on a
 // stream is always at offset 0. If the given offset is beyond the end of a
-// sealed stream, ErrStreamSealed is returned.
+// sealed stream, a *SealedError is returned.
 //
 // filter is a set of zero-value event messages, the types of which indicate
 // which event types are returned by Cursor.Next(). If filter is empty, all
-// events types are returned.
+// events types are returned. If filter is FilterNone, no events are returned.
 func (s *MemoryStream) Open(
 	ctx context.Context,
 	offset uint64,
@@ -110,8 +413,31 @@ func (s *MemoryStream) Open(
 	s.m.RLock()
 	defer s.m.RUnlock()
 
+	return s.open(offset, filter)
+}
+
+// OpenTail returns a cursor used to read events that are appended to the
+// stream after the cursor is opened.
+//
+// offset is the offset of the first event that the cursor reads. If the stream
+// is sealed, a *SealedError is returned.
+//
+// filter has the same semantics as for Open().
+func (s *MemoryStream) OpenTail(
+	ctx context.Context,
+	filter []dogma.Message,
+) (Cursor, uint64, error) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	c, err := s.open(s.next, filter)
+	return c, s.next, err
+}
+
+// open returns a cursor that begins at the given offset. s.m must be locked.
+func (s *MemoryStream) open(offset uint64, filter []dogma.Message) (Cursor, error) {
 	if s.sealed && offset >= s.next {
-		return nil, ErrStreamSealed
+		return nil, sealedError(offset, s.next)
 	}
 
 	c := &memoryCursor{
@@ -127,16 +453,151 @@ func (s *MemoryStream) Open(
 	return c, nil
 }
 
+// OpenReverse returns a cursor that reads events from this stream in
+// descending offset order.
+//
+// offset is the offset of the first event to read. If it is beyond the last
+// event on the stream, the cursor begins with the last event. filter has the
+// same semantics as for Open().
+//
+// The cursor's Next() method never blocks. It returns ErrStartOfStream once
+// the first event that has not been truncated has been read.
+func (s *MemoryStream) OpenReverse(
+	ctx context.Context,
+	offset uint64,
+	filter []dogma.Message,
+) (Cursor, error) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	c := &reverseMemoryCursor{
+		stream: s,
+		end:    s.next,
+		closed: make(chan struct{}),
+	}
+
+	if offset < s.next {
+		c.end = offset + 1
+	}
+
+	if len(filter) > 0 {
+		c.filter = message.TypesOf(filter...)
+	}
+
+	return c, nil
+}
+
+// Head returns the offset of the stream's head, that is, the offset at which
+// the next event will be appended.
+//
+// final is true if the stream is sealed, in which case the head never changes.
+func (s *MemoryStream) Head(ctx context.Context) (offset uint64, final bool, err error) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	return s.next, s.sealed, nil
+}
+
+// IsSealed returns true if the stream has been sealed.
+func (s *MemoryStream) IsSealed(ctx context.Context) (bool, error) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	return s.sealed, nil
+}
+
+// Bounds returns the range of offsets of the events that are available on the
+// stream.
+//
+// first is the offset of the first event that has not been truncated. next is
+// the offset at which the next event will be appended.
+func (s *MemoryStream) Bounds(ctx context.Context) (first, next uint64, err error) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	return s.first, s.next, nil
+}
+
+// Count returns the number of events on the stream, excluding those that have
+// been truncated.
+//
+// The count is always exact.
+func (s *MemoryStream) Count(ctx context.Context) (count uint64, exact bool) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	return s.next - s.first, true
+}
+
+// Len returns the number of events retained by the stream, excluding those
+// that have been truncated.
+func (s *MemoryStream) Len() int {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	return len(s.messages)
+}
+
+// FirstOffset returns the offset of the first event retained by the stream.
+//
+// If every event has been truncated, or no events have been appended, it is
+// equal to NextOffset().
+func (s *MemoryStream) FirstOffset() uint64 {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	return s.first
+}
+
+// NextOffset returns the offset at which the next event will be appended.
+func (s *MemoryStream) NextOffset() uint64 {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	return s.next
+}
+
 // Append appends messages to the end of the stream.
 //
+// Every message is recorded at the same time, t.
+//
 // It panics if the stream is sealed.
 func (s *MemoryStream) Append(t time.Time, messages ...dogma.Message) {
-	for _, m := range messages {
-		if m == nil {
+	envs := make([]Envelope, len(messages))
+	for i, m := range messages {
+		envs[i] = Envelope{RecordedAt: t, Message: m}
+	}
+
+	s.AppendEnvelopes(envs...)
+}
+
+// AppendEnvelopes appends pre-built envelopes to the end of the stream.
+//
+// It allows each event to be recorded at a different time, such as when
+// replaying events captured from another stream. The offset of each envelope
+// is ignored; each event is assigned the next sequential offset on the stream.
+//
+// It panics if the stream is sealed.
+func (s *MemoryStream) AppendEnvelopes(envs ...Envelope) {
+	for _, env := range envs {
+		if env.Message == nil {
 			panic("can not append nil messages")
 		}
 	}
 
+	first, count := s.append(envs)
+
+	if count > 0 && s.OnTruncate != nil {
+		s.OnTruncate(first, count)
+	}
+}
+
+// append appends envs to the end of the stream, then truncates the stream to
+// s.MaxLen events, if necessary.
+//
+// It returns the offset of the first event that remains on the stream and the
+// number of truncated events.
+func (s *MemoryStream) append(envs []Envelope) (first, count uint64) {
 	s.m.Lock()
 	defer s.m.Unlock()
 
@@ -144,25 +605,41 @@ func (s *MemoryStream) Append(t time.Time, messages ...dogma.Message) {
 		panic("can not append to sealed stream")
 	}
 
-	for _, m := range messages {
-		env := Envelope{s.next, t, m}
+	for _, env := range envs {
+		env.Offset = s.next
 		s.next++
 		s.messages = append(s.messages, env)
 	}
 
-	if s.ready != nil {
-		close(s.ready)
-		s.ready = nil
+	s.wake(envs)
+
+	if s.MaxLen > 0 && len(s.messages) > s.MaxLen {
+		count = s.discard(s.next - uint64(s.MaxLen))
 	}
+
+	return s.first, count
 }
 
 // Truncate discards any events before the given offset.
 //
-// It returns the number of truncated events.
+// It returns the number of truncated events. If any events are discarded, and
+// s.OnTruncate is non-nil, it is called before Truncate() returns.
 //
 // It panics if the offset is greater than the total number of events appended
 // to the stream.
 func (s *MemoryStream) Truncate(offset uint64) uint64 {
+	count := s.truncate(offset)
+
+	if count > 0 && s.OnTruncate != nil {
+		s.OnTruncate(offset, count)
+	}
+
+	return count
+}
+
+// truncate discards any events before the given offset and returns the number
+// of truncated events.
+func (s *MemoryStream) truncate(offset uint64) uint64 {
 	s.m.Lock()
 	defer s.m.Unlock()
 
@@ -174,12 +651,18 @@ func (s *MemoryStream) Truncate(offset uint64) uint64 {
 		))
 	}
 
-	count := offset - s.first
+	return s.discard(offset)
+}
 
-	if count <= 0 {
+// discard discards any events before the given offset and returns the number
+// of discarded events. s.m must be locked for writing.
+func (s *MemoryStream) discard(offset uint64) uint64 {
+	if offset <= s.first {
 		return 0
 	}
 
+	count := offset - s.first
+
 	s.first = offset
 	s.messages = s.messages[count:]
 
@@ -196,10 +679,42 @@ func (s *MemoryStream) Seal() {
 	}
 
 	s.sealed = true
+	s.wake(nil)
+}
+
+// wait returns a channel that is closed when an event relevant to c is
+// appended to the stream, or the stream is sealed. s.m must be locked for
+// writing.
+func (s *MemoryStream) wait(c *memoryCursor) <-chan struct{} {
+	if ch, ok := s.waiters[c]; ok {
+		return ch
+	}
+
+	if s.waiters == nil {
+		s.waiters = map[*memoryCursor]chan struct{}{}
+	}
+
+	ch := make(chan struct{})
+	s.waiters[c] = ch
+
+	return ch
+}
 
-	if s.ready != nil {
-		close(s.ready)
-		s.ready = nil
+// wake wakes the cursors that are waiting for any of the events in envs.
+//
+// Cursors whose filter excludes every one of the events remain waiting, so
+// that many filtered cursors can share a stream without being woken for events
+// they would discard. Their offset is advanced past envs so that the events
+// they skip may later be truncated without invalidating the cursor. If envs is
+// nil, every waiting cursor is woken. s.m must be locked for writing.
+func (s *MemoryStream) wake(envs []Envelope) {
+	for c, ch := range s.waiters {
+		if envs == nil || c.relevant(envs) {
+			close(ch)
+			delete(s.waiters, c)
+		} else if c.offset < s.next {
+			c.offset = s.next
+		}
 	}
 }
 
@@ -207,6 +722,7 @@ type memoryCursor struct {
 	stream    *MemoryStream
 	offset    uint64
 	filter    message.TypeSet
+	predicate func(Envelope) bool
 	closeOnce sync.Once
 	closed    chan struct{}
 }
@@ -219,6 +735,21 @@ var errCursorClosed = errors.New("cursor is closed")
 // appended to the stream, ctx is canceled or the stream is sealed. If the
 // stream is sealed, ErrStreamSealed is returned.
 func (c *memoryCursor) Next(ctx context.Context) (Envelope, error) {
+	env, err := c.Peek(ctx)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	c.offset = env.Offset + 1
+
+	return env, nil
+}
+
+// Peek returns the next relevant event in the stream without advancing the
+// cursor past it.
+//
+// It blocks in the same way as Next().
+func (c *memoryCursor) Peek(ctx context.Context) (Envelope, error) {
 	for {
 		select {
 		case <-ctx.Done():
@@ -244,34 +775,117 @@ func (c *memoryCursor) Next(ctx context.Context) (Envelope, error) {
 	}
 }
 
+// TryNext returns the next relevant event in the stream, if one is immediately
+// available.
+//
+// ok is false if the end of the stream has been reached. If the stream is
+// sealed, ErrStreamSealed is returned.
+func (c *memoryCursor) TryNext(ctx context.Context) (Envelope, bool, error) {
+	select {
+	case <-ctx.Done():
+		return Envelope{}, false, ctx.Err()
+	case <-c.closed:
+		return Envelope{}, false, errCursorClosed
+	default:
+	}
+
+	env, ready, err := c.get()
+	if err != nil || ready != nil {
+		return Envelope{}, false, err
+	}
+
+	c.offset = env.Offset + 1
+
+	return env, true, nil
+}
+
+// SetFilter replaces the cursor's message-type filter.
+//
+// The new filter applies to events read from the cursor's current position
+// onwards. Events that have already been returned are never re-read.
+func (c *memoryCursor) SetFilter(filter []dogma.Message) {
+	var f message.TypeSet
+	if len(filter) > 0 {
+		f = message.TypesOf(filter...)
+	}
+
+	c.stream.m.Lock()
+	defer c.stream.m.Unlock()
+
+	c.filter = f
+}
+
+// SetPredicate sets a function that is used to skip events based on their
+// content.
+//
+// fn is called with the stream locked, so it must not use the stream.
+func (c *memoryCursor) SetPredicate(fn func(Envelope) bool) {
+	c.stream.m.Lock()
+	defer c.stream.m.Unlock()
+
+	c.predicate = fn
+}
+
 // Close stops the cursor.
 //
 // Any current or future calls to Next() return a non-nil error.
 func (c *memoryCursor) Close() error {
 	c.closeOnce.Do(func() {
 		close(c.closed)
+
+		c.stream.m.Lock()
+		defer c.stream.m.Unlock()
+
+		delete(c.stream.waiters, c)
 	})
 
 	return nil
 }
 
+// relevant returns true if any of the events in envs matches the cursor's
+// filter. c.stream.m must be locked.
+func (c *memoryCursor) relevant(envs []Envelope) bool {
+	if c.filter == nil {
+		return true
+	}
+
+	for _, env := range envs {
+		if c.filter.HasM(env.Message) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// get returns the next relevant event without advancing the cursor past it.
+//
+// It advances the cursor past any irrelevant events. If there are no more
+// relevant events it returns a channel that is closed when a relevant event is
+// appended or the stream is sealed.
 func (c *memoryCursor) get() (Envelope, <-chan struct{}, error) {
 	c.stream.m.Lock()
 	defer c.stream.m.Unlock()
 
 	if c.offset < c.stream.first {
-		return Envelope{}, nil, fmt.Errorf(
-			"can not read truncated event at offset %d, the first available offset is %d",
-			c.offset,
-			c.stream.first,
-		)
+		return Envelope{}, nil, &TruncatedError{
+			RequestedOffset: c.offset,
+			FirstOffset:     c.stream.first,
+		}
 	}
 
 	for c.stream.next > c.offset {
 		env := c.stream.messages[c.offset-c.stream.first]
-		c.offset++
 
 		if c.filter != nil && !c.filter.HasM(env.Message) {
+			c.offset++
+			continue
+		}
+
+		env.MessageID = MessageID(c.stream.StreamID, env.Offset)
+
+		if c.predicate != nil && !c.predicate(env) {
+			c.offset++
 			continue
 		}
 
@@ -282,9 +896,58 @@ func (c *memoryCursor) get() (Envelope, <-chan struct{}, error) {
 		return Envelope{}, nil, ErrStreamSealed
 	}
 
-	if c.stream.ready == nil {
-		c.stream.ready = make(chan struct{})
+	return Envelope{}, c.stream.wait(c), nil
+}
+
+// reverseMemoryCursor is a cursor that reads events from a MemoryStream in
+// descending offset order.
+type reverseMemoryCursor struct {
+	stream    *MemoryStream
+	end       uint64 // the offset after the next event to be considered
+	filter    message.TypeSet
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// Next returns the next relevant event in the stream, moving towards the
+// start of the stream.
+//
+// It returns ErrStartOfStream if there are no more relevant events.
+func (c *reverseMemoryCursor) Next(ctx context.Context) (Envelope, error) {
+	select {
+	case <-ctx.Done():
+		return Envelope{}, ctx.Err()
+	case <-c.closed:
+		return Envelope{}, errCursorClosed
+	default:
+	}
+
+	c.stream.m.RLock()
+	defer c.stream.m.RUnlock()
+
+	for c.end > c.stream.first {
+		c.end--
+		env := c.stream.messages[c.end-c.stream.first]
+
+		if c.filter != nil && !c.filter.HasM(env.Message) {
+			continue
+		}
+
+		env.MessageID = MessageID(c.stream.StreamID, env.Offset)
+
+		return env, nil
 	}
 
-	return Envelope{}, c.stream.ready, nil
+	return Envelope{}, ErrStartOfStream
+}
+
+// Close stops the cursor.
+//
+// Any future calls to Next() return a non-nil error.
+func (c *reverseMemoryCursor) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+
+	return nil
 }
@@ -2,6 +2,8 @@ package ordered_test
 
 import (
 	"context"
+	"errors"
+	"math"
 	"time"
 
 	. "github.com/dogmatiq/aperture/ordered"
@@ -65,9 +67,10 @@ var _ = Describe("type MemoryStream", func() {
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(env).To(Equal(
 				Envelope{
-					2,
-					now,
-					MessageA2,
+					Offset:     2,
+					RecordedAt: now,
+					Message:    MessageA2,
+					MessageID:  "<id>@2",
 				},
 			))
 
@@ -75,9 +78,10 @@ var _ = Describe("type MemoryStream", func() {
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(env).To(Equal(
 				Envelope{
-					3,
-					now,
-					MessageB2,
+					Offset:     3,
+					RecordedAt: now,
+					Message:    MessageB2,
+					MessageID:  "<id>@3",
 				},
 			))
 		})
@@ -91,9 +95,10 @@ var _ = Describe("type MemoryStream", func() {
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(env).To(Equal(
 				Envelope{
-					0,
-					now,
-					MessageA1,
+					Offset:     0,
+					RecordedAt: now,
+					Message:    MessageA1,
+					MessageID:  "<id>@0",
 				},
 			))
 
@@ -101,13 +106,37 @@ var _ = Describe("type MemoryStream", func() {
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(env).To(Equal(
 				Envelope{
-					2,
-					now,
-					MessageA2,
+					Offset:     2,
+					RecordedAt: now,
+					Message:    MessageA2,
+					MessageID:  "<id>@2",
 				},
 			))
 		})
 
+		It("does not return any events when the filter is FilterNone", func() {
+			ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			defer cancel()
+
+			cur, err := stream.Open(ctx, 0, FilterNone)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			_, err = cur.Next(ctx)
+			Expect(err).To(Equal(context.DeadlineExceeded))
+		})
+
+		It("returns ErrStreamSealed when the filter is FilterNone and the stream is sealed", func() {
+			cur, err := stream.Open(ctx, 0, FilterNone)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			stream.Seal()
+
+			_, err = cur.Next(ctx)
+			Expect(err).To(MatchError(ErrStreamSealed))
+		})
+
 		Context("when the stream is sealed", func() {
 			It("returns a cursor if the offset is already on the stream", func() {
 				stream.Seal()
@@ -121,11 +150,273 @@ var _ = Describe("type MemoryStream", func() {
 				stream.Seal()
 
 				_, err := stream.Open(ctx, 4, []dogma.Message{MessageB{}})
-				Expect(err).To(Equal(ErrStreamSealed))
+				Expect(err).To(MatchError(ErrStreamSealed))
+			})
+
+			It("returns a *SealedError that describes the end of the stream", func() {
+				stream.Seal()
+
+				_, err := stream.Open(ctx, 10, nil)
+				Expect(err).To(Equal(
+					&SealedError{
+						RequestedOffset: 10,
+						LastOffset:      3,
+					},
+				))
+				Expect(err).To(MatchError(
+					"stream sealed: can not open at offset 10, the last offset is 3",
+				))
 			})
 		})
 	})
 
+	Describe("func Head()", func() {
+		It("returns the offset of the next event", func() {
+			offset, final, err := stream.Head(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(offset).To(BeNumerically("==", 4))
+			Expect(final).To(BeFalse())
+		})
+
+		It("reports that the head is final if the stream is sealed", func() {
+			stream.Seal()
+
+			offset, final, err := stream.Head(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(offset).To(BeNumerically("==", 4))
+			Expect(final).To(BeTrue())
+		})
+	})
+
+	Describe("func IsSealed()", func() {
+		It("returns false if the stream is not sealed", func() {
+			sealed, err := stream.IsSealed(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(sealed).To(BeFalse())
+		})
+
+		It("returns true if the stream is sealed", func() {
+			stream.Seal()
+
+			sealed, err := stream.IsSealed(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(sealed).To(BeTrue())
+		})
+	})
+
+	Describe("func Bounds()", func() {
+		It("returns the offsets of the first and next events", func() {
+			first, next, err := stream.Bounds(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(first).To(BeNumerically("==", 0))
+			Expect(next).To(BeNumerically("==", 4))
+		})
+
+		It("excludes truncated events", func() {
+			stream.Truncate(3)
+
+			first, next, err := stream.Bounds(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(first).To(BeNumerically("==", 3))
+			Expect(next).To(BeNumerically("==", 4))
+		})
+	})
+
+	Describe("func Count()", func() {
+		It("returns the number of events on the stream", func() {
+			count, exact := stream.Count(ctx)
+			Expect(count).To(BeNumerically("==", 4))
+			Expect(exact).To(BeTrue())
+		})
+
+		It("excludes truncated events", func() {
+			stream.Truncate(3)
+
+			count, exact := stream.Count(ctx)
+			Expect(count).To(BeNumerically("==", 1))
+			Expect(exact).To(BeTrue())
+		})
+	})
+
+	Describe("func Len()", func() {
+		It("returns the number of retained events", func() {
+			Expect(stream.Len()).To(Equal(4))
+		})
+
+		It("excludes truncated events", func() {
+			stream.Truncate(3)
+			Expect(stream.Len()).To(Equal(1))
+		})
+	})
+
+	Describe("func FirstOffset()", func() {
+		It("returns zero if no events have been truncated", func() {
+			Expect(stream.FirstOffset()).To(BeNumerically("==", 0))
+		})
+
+		It("returns the offset of the first retained event", func() {
+			stream.Truncate(3)
+			Expect(stream.FirstOffset()).To(BeNumerically("==", 3))
+		})
+
+		It("returns the next offset if every event has been truncated", func() {
+			stream.Truncate(4)
+			Expect(stream.FirstOffset()).To(Equal(stream.NextOffset()))
+		})
+	})
+
+	Describe("func NextOffset()", func() {
+		It("returns the offset of the next event to be appended", func() {
+			Expect(stream.NextOffset()).To(BeNumerically("==", 4))
+
+			stream.Append(now, MessageC1)
+			Expect(stream.NextOffset()).To(BeNumerically("==", 5))
+		})
+	})
+
+	Describe("func OpenTail()", func() {
+		It("returns a cursor positioned at the head of the stream", func() {
+			cur, offset, err := stream.OpenTail(ctx, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			Expect(offset).To(BeNumerically("==", 4))
+
+			stream.Append(now, MessageA3)
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env).To(Equal(
+				Envelope{
+					Offset:     4,
+					RecordedAt: now,
+					Message:    MessageA3,
+					MessageID:  "<id>@4",
+				},
+			))
+		})
+
+		It("applies the message type filter", func() {
+			cur, _, err := stream.OpenTail(ctx, []dogma.Message{MessageB{}})
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			stream.Append(now, MessageA3, MessageB3)
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Message).To(Equal(MessageB3))
+		})
+
+		It("returns ErrStreamSealed if the stream is sealed", func() {
+			stream.Seal()
+
+			_, _, err := stream.OpenTail(ctx, nil)
+			Expect(err).To(MatchError(ErrStreamSealed))
+		})
+	})
+
+	Describe("func OpenReverse()", func() {
+		messages := func(cur Cursor) []dogma.Message {
+			var result []dogma.Message
+			for {
+				env, err := cur.Next(ctx)
+				if err == ErrStartOfStream {
+					return result
+				}
+				Expect(err).ShouldNot(HaveOccurred())
+				result = append(result, env.Message)
+			}
+		}
+
+		It("returns events in descending offset order", func() {
+			cur, err := stream.OpenReverse(ctx, 2, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env).To(Equal(
+				Envelope{
+					Offset:     2,
+					RecordedAt: now,
+					Message:    MessageA2,
+					MessageID:  "<id>@2",
+				},
+			))
+
+			Expect(messages(cur)).To(Equal(
+				[]dogma.Message{
+					MessageB1,
+					MessageA1,
+				},
+			))
+		})
+
+		It("begins with the last event if the offset is beyond the end of the stream", func() {
+			cur, err := stream.OpenReverse(ctx, math.MaxUint64, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			Expect(messages(cur)).To(Equal(
+				[]dogma.Message{
+					MessageB2,
+					MessageA2,
+					MessageB1,
+					MessageA1,
+				},
+			))
+		})
+
+		It("applies the message type filter", func() {
+			cur, err := stream.OpenReverse(ctx, 3, []dogma.Message{MessageB{}})
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			Expect(messages(cur)).To(Equal(
+				[]dogma.Message{
+					MessageB2,
+					MessageB1,
+				},
+			))
+		})
+
+		It("stops at the first event that has not been truncated", func() {
+			stream.Truncate(2)
+
+			cur, err := stream.OpenReverse(ctx, 3, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			Expect(messages(cur)).To(Equal(
+				[]dogma.Message{
+					MessageB2,
+					MessageA2,
+				},
+			))
+		})
+
+		It("does not block if the stream is empty", func() {
+			stream = &MemoryStream{StreamID: "<id>"}
+
+			cur, err := stream.OpenReverse(ctx, 0, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			_, err = cur.Next(ctx)
+			Expect(err).To(Equal(ErrStartOfStream))
+		})
+
+		It("returns an error if the cursor is closed", func() {
+			cur, err := stream.OpenReverse(ctx, 3, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			cur.Close()
+
+			_, err = cur.Next(ctx)
+			Expect(err).To(MatchError("cursor is closed"))
+		})
+	})
+
 	Describe("func Append()", func() {
 		It("wakes waiting consumers", func() {
 			g, ctx := errgroup.WithContext(ctx)
@@ -148,9 +439,10 @@ var _ = Describe("type MemoryStream", func() {
 
 				Expect(env).To(Equal(
 					Envelope{
-						5,
-						now,
-						MessageB3,
+						Offset:     5,
+						RecordedAt: now,
+						Message:    MessageB3,
+						MessageID:  "<id>@5",
 					},
 				))
 
@@ -169,6 +461,41 @@ var _ = Describe("type MemoryStream", func() {
 			Expect(err).ShouldNot(HaveOccurred())
 		})
 
+		It("wakes only those consumers with a matching filter", func() {
+			next := func(filter dogma.Message) <-chan any {
+				cur, err := stream.Open(ctx, 4, []dogma.Message{filter})
+				Expect(err).ShouldNot(HaveOccurred())
+				DeferCleanup(cur.Close)
+
+				result := make(chan any, 1)
+				go func() {
+					env, err := cur.Next(ctx)
+					if err != nil {
+						result <- err
+					} else {
+						result <- env.Message
+					}
+				}()
+
+				return result
+			}
+
+			resultA := next(MessageA{})
+			resultB := next(MessageB{})
+			resultC := next(MessageC{})
+
+			stream.Append(now, MessageA3)
+			Eventually(resultA).Should(Receive(Equal(MessageA3)))
+
+			stream.Append(now, MessageB3)
+			Eventually(resultB).Should(Receive(Equal(MessageB3)))
+
+			Consistently(resultC, 50*time.Millisecond).ShouldNot(Receive())
+
+			stream.Seal()
+			Eventually(resultC).Should(Receive(MatchError(ErrStreamSealed)))
+		})
+
 		It("panics if the stream is sealed", func() {
 			stream.Seal()
 
@@ -184,7 +511,197 @@ var _ = Describe("type MemoryStream", func() {
 		})
 	})
 
+	Context("when MaxLen is set", func() {
+		BeforeEach(func() {
+			stream.MaxLen = 3
+		})
+
+		It("truncates the oldest events after each append", func() {
+			stream.Append(now, MessageC1)
+
+			Expect(stream.Len()).To(Equal(3))
+			Expect(stream.FirstOffset()).To(BeNumerically("==", 2))
+			Expect(stream.NextOffset()).To(BeNumerically("==", 5))
+
+			cur, err := stream.Open(ctx, 2, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Message).To(Equal(MessageA2))
+		})
+
+		It("calls OnTruncate with the new first offset and the number of truncated events", func() {
+			var first, count uint64
+			stream.OnTruncate = func(f, c uint64) {
+				first, count = f, c
+			}
+
+			stream.Append(now, MessageC1, MessageC2)
+
+			Expect(first).To(BeNumerically("==", 3))
+			Expect(count).To(BeNumerically("==", 3))
+		})
+
+		It("does not truncate events if the stream is within the limit", func() {
+			stream.MaxLen = 10
+			stream.Append(now, MessageC1)
+
+			Expect(stream.Len()).To(Equal(5))
+			Expect(stream.FirstOffset()).To(BeNumerically("==", 0))
+		})
+
+		It("causes cursors that fall behind to return an error", func() {
+			cur, err := stream.Open(ctx, 1, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			stream.Append(now, MessageC1, MessageC2)
+
+			_, err = cur.Next(ctx)
+			Expect(err).To(MatchError(
+				"can not read truncated event at offset 1, the first available offset is 3",
+			))
+		})
+
+		It("does not cause waiting cursors to fall behind when truncating events they do not match", func() {
+			cur, err := stream.Open(ctx, 4, []dogma.Message{MessageC{}})
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			result := make(chan any, 1)
+			go func() {
+				env, err := cur.Next(ctx)
+				if err != nil {
+					result <- err
+				} else {
+					result <- env.Offset
+				}
+			}()
+
+			time.Sleep(50 * time.Millisecond) // allow the cursor to start waiting
+			stream.Append(now, MessageA3)
+			stream.Append(now, MessageB3)
+			stream.Append(now, MessageD1)
+			stream.Append(now, MessageC1)
+
+			Eventually(result).Should(Receive(BeNumerically("==", 7)))
+		})
+	})
+
+	Describe("func AppendEnvelopes()", func() {
+		It("retains the time at which each event was recorded", func() {
+			then := now.Add(-time.Hour)
+
+			stream.AppendEnvelopes(
+				Envelope{RecordedAt: then, Message: MessageA3},
+				Envelope{RecordedAt: now, Message: MessageB3},
+			)
+
+			cur, err := stream.Open(ctx, 4, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env).To(Equal(
+				Envelope{
+					Offset:     4,
+					RecordedAt: then,
+					Message:    MessageA3,
+					MessageID:  "<id>@4",
+				},
+			))
+
+			env, err = cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env).To(Equal(
+				Envelope{
+					Offset:     5,
+					RecordedAt: now,
+					Message:    MessageB3,
+					MessageID:  "<id>@5",
+				},
+			))
+		})
+
+		It("overwrites the offset of each envelope", func() {
+			stream.AppendEnvelopes(
+				Envelope{Offset: 100, RecordedAt: now, Message: MessageA3},
+			)
+
+			cur, err := stream.Open(ctx, 4, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Offset).To(BeEquivalentTo(4))
+		})
+
+		It("panics if the stream is sealed", func() {
+			stream.Seal()
+
+			Expect(func() {
+				stream.AppendEnvelopes(Envelope{RecordedAt: now, Message: MessageA1})
+			}).To(Panic())
+		})
+
+		It("panics if any of the envelopes has a nil message", func() {
+			Expect(func() {
+				stream.AppendEnvelopes(Envelope{RecordedAt: now})
+			}).To(Panic())
+		})
+	})
+
 	Describe("func Truncate()", func() {
+		It("does not cause waiting cursors to fall behind when truncating events they do not match", func() {
+			cur, err := stream.Open(ctx, 4, []dogma.Message{MessageC{}})
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			result := make(chan any, 1)
+			go func() {
+				env, err := cur.Next(ctx)
+				if err != nil {
+					result <- err
+				} else {
+					result <- env.Offset
+				}
+			}()
+
+			time.Sleep(50 * time.Millisecond) // allow the cursor to start waiting
+			stream.Append(now, MessageA3, MessageB3)
+			stream.Truncate(6)
+			stream.Append(now, MessageC1)
+
+			Eventually(result).Should(Receive(BeNumerically("==", 6)))
+		})
+
+		It("calls OnTruncate with the new first offset and the number of truncated events", func() {
+			var first, count uint64
+			stream.OnTruncate = func(f, c uint64) {
+				first, count = f, c
+			}
+
+			stream.Truncate(1)
+			stream.Truncate(3)
+
+			Expect(first).To(BeEquivalentTo(3))
+			Expect(count).To(BeEquivalentTo(2))
+		})
+
+		It("does not call OnTruncate if no events are truncated", func() {
+			stream.Truncate(2)
+
+			stream.OnTruncate = func(uint64, uint64) {
+				Fail("unexpected call to OnTruncate()")
+			}
+
+			stream.Truncate(2)
+		})
+
 		It("truncates events before the given offset", func() {
 			stream.Truncate(2)
 
@@ -193,6 +710,11 @@ var _ = Describe("type MemoryStream", func() {
 
 			_, err = cur.Next(ctx)
 			Expect(err).To(MatchError("can not read truncated event at offset 1, the first available offset is 2"))
+
+			var truncErr *TruncatedError
+			Expect(errors.As(err, &truncErr)).To(BeTrue())
+			Expect(truncErr.RequestedOffset).To(BeNumerically("==", 1))
+			Expect(truncErr.FirstOffset).To(BeNumerically("==", 2))
 		})
 
 		It("does not truncate events after the given offset", func() {
@@ -205,9 +727,10 @@ var _ = Describe("type MemoryStream", func() {
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(env).To(Equal(
 				Envelope{
-					2,
-					now,
-					MessageA2,
+					Offset:     2,
+					RecordedAt: now,
+					Message:    MessageA2,
+					MessageID:  "<id>@2",
 				},
 			))
 
@@ -215,9 +738,10 @@ var _ = Describe("type MemoryStream", func() {
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(env).To(Equal(
 				Envelope{
-					3,
-					now,
-					MessageB2,
+					Offset:     3,
+					RecordedAt: now,
+					Message:    MessageB2,
+					MessageID:  "<id>@3",
 				},
 			))
 		})
@@ -257,6 +781,157 @@ var _ = Describe("type MemoryStream", func() {
 	})
 
 	Describe("type memoryCursor", func() {
+		Describe("func TryNext()", func() {
+			It("returns the next relevant event if one is available", func() {
+				cur, err := stream.Open(ctx, 0, []dogma.Message{MessageB{}})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				env, ok, err := cur.(NonBlockingCursor).TryNext(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(ok).To(BeTrue())
+				Expect(env.Message).To(Equal(MessageB1))
+			})
+
+			It("returns false without blocking at the end of the stream", func() {
+				cur, err := stream.Open(ctx, 4, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				_, ok, err := cur.(NonBlockingCursor).TryNext(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(ok).To(BeFalse())
+			})
+
+			It("returns ErrStreamSealed at the end of a sealed stream", func() {
+				stream.Seal()
+
+				cur, err := stream.Open(ctx, 3, []dogma.Message{MessageA{}})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				_, _, err = cur.(NonBlockingCursor).TryNext(ctx)
+				Expect(err).To(Equal(ErrStreamSealed))
+			})
+
+			It("returns an error if the cursor is closed", func() {
+				cur, err := stream.Open(ctx, 0, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				cur.Close()
+
+				_, _, err = cur.(NonBlockingCursor).TryNext(ctx)
+				Expect(err).Should(HaveOccurred())
+			})
+		})
+
+		Describe("func SetFilter()", func() {
+			It("applies the new filter from the cursor's current position", func() {
+				cur, err := stream.Open(ctx, 0, []dogma.Message{MessageA{}})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				env, err := cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Message).To(Equal(MessageA1))
+
+				cur.(FilterCursor).SetFilter([]dogma.Message{MessageB{}})
+
+				env, err = cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Message).To(Equal(MessageB1))
+
+				cur.(FilterCursor).SetFilter(nil)
+
+				env, err = cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Message).To(Equal(MessageA2))
+			})
+
+			It("does not re-read events that were excluded by the previous filter", func() {
+				cur, err := stream.Open(ctx, 0, []dogma.Message{MessageB{}})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				env, err := cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Message).To(Equal(MessageB1))
+
+				cur.(FilterCursor).SetFilter([]dogma.Message{MessageA{}})
+
+				env, err = cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Message).To(Equal(MessageA2))
+			})
+		})
+
+		Describe("func SetPredicate()", func() {
+			It("skips events that match the filter but not the predicate", func() {
+				cur, err := stream.Open(ctx, 0, []dogma.Message{MessageA{}})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				cur.(PredicateCursor).SetPredicate(func(env Envelope) bool {
+					Expect(env.MessageID).To(Equal(MessageID("<id>", env.Offset)))
+					return env.Message != MessageA1
+				})
+
+				env, err := cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Offset).To(BeNumerically("==", 2))
+				Expect(env.Message).To(Equal(MessageA2))
+			})
+
+			It("does not skip any events if the predicate is nil", func() {
+				cur, err := stream.Open(ctx, 0, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				cur.(PredicateCursor).SetPredicate(func(Envelope) bool { return false })
+				cur.(PredicateCursor).SetPredicate(nil)
+
+				env, err := cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Message).To(Equal(MessageA1))
+			})
+		})
+
+		Describe("func Peek()", func() {
+			It("returns the next relevant event without advancing the cursor", func() {
+				cur, err := stream.Open(ctx, 0, []dogma.Message{MessageB{}})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				peeked, err := cur.(PeekCursor).Peek(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(peeked.Offset).To(BeNumerically("==", 1))
+				Expect(peeked.Message).To(Equal(MessageB1))
+
+				env, err := cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env).To(Equal(peeked))
+
+				env, err = cur.Next(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Message).To(Equal(MessageB2))
+			})
+
+			It("blocks until an event is appended at the end of the stream", func() {
+				cur, err := stream.Open(ctx, 4, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer cur.Close()
+
+				go func() {
+					time.Sleep(20 * time.Millisecond)
+					stream.Append(now, MessageA3)
+				}()
+
+				env, err := cur.(PeekCursor).Peek(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(env.Message).To(Equal(MessageA3))
+			})
+		})
+
 		Describe("func Next()", func() {
 			It("returns the correct message after truncation ", func() {
 				stream.Truncate(2)
@@ -269,9 +944,10 @@ var _ = Describe("type MemoryStream", func() {
 				Expect(err).ShouldNot(HaveOccurred())
 				Expect(env).To(Equal(
 					Envelope{
-						2,
-						now,
-						MessageA2,
+						Offset:     2,
+						RecordedAt: now,
+						Message:    MessageA2,
+						MessageID:  "<id>@2",
 					},
 				))
 			})
@@ -328,7 +1004,7 @@ var _ = Describe("type MemoryStream", func() {
 					stream.Seal()
 
 					_, err = cur.Next(ctx)
-					Expect(err).To(Equal(ErrStreamSealed))
+					Expect(err).To(MatchError(ErrStreamSealed))
 				})
 
 				It("returns ErrStreamSealed if sealed while Next() is blocking", func() {
@@ -342,7 +1018,7 @@ var _ = Describe("type MemoryStream", func() {
 					}()
 
 					_, err = cur.Next(ctx)
-					Expect(err).To(Equal(ErrStreamSealed))
+					Expect(err).To(MatchError(ErrStreamSealed))
 				})
 
 				It("does not hang when filtering historical events", func() {
@@ -364,7 +1040,7 @@ var _ = Describe("type MemoryStream", func() {
 					Expect(err).ShouldNot(HaveOccurred())
 
 					_, err = cur.Next(ctx)
-					Expect(err).To(Equal(ErrStreamSealed))
+					Expect(err).To(MatchError(ErrStreamSealed))
 				})
 			})
 		})
@@ -0,0 +1,3 @@
+// Package streamkit provides helpers for consuming an ordered.Stream without
+// managing cursors directly.
+package streamkit
@@ -0,0 +1,77 @@
+package streamkit
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+)
+
+// Tail reads events from s in a separate goroutine, beginning at offset, and
+// sends them on the returned envelope channel.
+//
+// The offset and filter parameters have the same semantics as for
+// ordered.Stream.Open(). It reads events until the stream is sealed, ctx is canceled
+// or some other error occurs, at which point the cursor is closed and both
+// channels are closed.
+//
+// If reading stops for any reason other than the stream being sealed, the
+// error is sent on the error channel before it is closed. The error channel is
+// buffered, so the caller need only read from it once the envelope channel has
+// been closed.
+func Tail(
+	ctx context.Context,
+	s ordered.Stream,
+	offset uint64,
+	filter []dogma.Message,
+) (<-chan ordered.Envelope, <-chan error) {
+	envs := make(chan ordered.Envelope)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(envs)
+
+		if err := tail(ctx, s, offset, filter, envs); err != nil {
+			errs <- err
+		}
+	}()
+
+	return envs, errs
+}
+
+// tail is the implementation of Tail(). It returns nil if the stream is
+// sealed.
+func tail(
+	ctx context.Context,
+	s ordered.Stream,
+	offset uint64,
+	filter []dogma.Message,
+	envs chan<- ordered.Envelope,
+) error {
+	cur, err := s.Open(ctx, offset, filter)
+	if err != nil {
+		if errors.Is(err, ordered.ErrStreamSealed) {
+			return nil
+		}
+		return err
+	}
+	defer cur.Close()
+
+	for {
+		env, err := cur.Next(ctx)
+		if err != nil {
+			if errors.Is(err, ordered.ErrStreamSealed) {
+				return nil
+			}
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case envs <- env:
+		}
+	}
+}
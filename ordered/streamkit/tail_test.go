@@ -0,0 +1,104 @@
+package streamkit_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/dogmatiq/aperture/ordered"
+	. "github.com/dogmatiq/aperture/ordered/streamkit"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("func Tail()", func() {
+	var (
+		now    time.Time
+		ctx    context.Context
+		cancel func()
+		stream *MemoryStream
+	)
+
+	BeforeEach(func() {
+		now = time.Now()
+
+		ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+
+		stream = &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		stream.Append(
+			now,
+			MessageA1,
+			MessageB1,
+			MessageA2,
+		)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("sends the filtered events and closes the channels when the stream is sealed", func() {
+		envs, errs := Tail(ctx, stream, 0, []dogma.Message{MessageA{}})
+
+		Expect(<-envs).To(Equal(
+			Envelope{Offset: 0, RecordedAt: now, Message: MessageA1, MessageID: "<id>@0"},
+		))
+		Expect(<-envs).To(Equal(
+			Envelope{Offset: 2, RecordedAt: now, Message: MessageA2, MessageID: "<id>@2"},
+		))
+
+		stream.Seal()
+
+		Eventually(envs).Should(BeClosed())
+		Eventually(errs).Should(BeClosed())
+	})
+
+	It("sends events that are appended after it is called", func() {
+		envs, _ := Tail(ctx, stream, 3, nil)
+
+		stream.Append(now, MessageC1)
+
+		Expect(<-envs).To(Equal(
+			Envelope{Offset: 3, RecordedAt: now, Message: MessageC1, MessageID: "<id>@3"},
+		))
+	})
+
+	It("closes the channels if the offset is beyond the end of a sealed stream", func() {
+		stream.Seal()
+
+		envs, errs := Tail(ctx, stream, 10, nil)
+
+		Eventually(envs).Should(BeClosed())
+		Eventually(errs).Should(BeClosed())
+	})
+
+	It("sends the context error if ctx is canceled", func() {
+		envs, errs := Tail(ctx, stream, 3, nil)
+
+		cancel()
+
+		Eventually(envs).Should(BeClosed())
+		Expect(<-errs).To(Equal(context.Canceled))
+	})
+
+	It("sends the error if the stream can not be opened", func() {
+		envs, errs := Tail(ctx, &errorStream{}, 0, nil)
+
+		Eventually(envs).Should(BeClosed())
+		Expect(<-errs).To(MatchError("<error>"))
+	})
+})
+
+// errorStream is a Stream that fails to open.
+type errorStream struct {
+	Stream
+}
+
+func (*errorStream) Open(context.Context, uint64, []dogma.Message) (Cursor, error) {
+	return nil, errors.New("<error>")
+}
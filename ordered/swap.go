@@ -0,0 +1,89 @@
+package ordered
+
+import (
+	"fmt"
+
+	"github.com/dogmatiq/configkit"
+	"github.com/dogmatiq/configkit/message"
+	"github.com/dogmatiq/dodeca/logging"
+	"github.com/dogmatiq/dogma"
+)
+
+// handlerState is the handler currently used by a projector, along with the
+// event types that it consumes.
+type handlerState struct {
+	handler dogma.ProjectionMessageHandler
+	types   message.TypeCollection
+}
+
+// consumes returns true if the handler consumes the event in env.
+func (s *handlerState) consumes(env Envelope) bool {
+	return s.types.HasM(env.Message)
+}
+
+// SwapHandler replaces the projector's handler with h, without restarting the
+// projector.
+//
+// h must have the same identity as the current handler, as it is expected to
+// share the same projection resources. It must consume the same event types as
+// the current handler, or a subset of them, otherwise an error is returned and
+// the handler is not replaced. A handler that consumes additional event types
+// can not be swapped in, as it would never see those events that occurred
+// before the swap.
+//
+// If the projector is running, the swap takes effect between events. Any event
+// (or batch of events) that is already being handled is applied using the
+// previous handler; subsequent events are applied using h.
+//
+// If h consumes fewer event types than the current handler, the next event
+// that h does not consume causes the stream to be re-opened at the projection's
+// current offset with a filter that includes only the types consumed by h.
+//
+// The Handler field is not modified.
+func (p *Projector) SwapHandler(h dogma.ProjectionMessageHandler) (err error) {
+	defer configkit.Recover(&err)
+
+	p.prepare()
+
+	cfg := configkit.FromProjection(h)
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	current := p.state.Load()
+
+	if id := cfg.Identity(); id.Name != p.name || id.Key != p.key {
+		return fmt.Errorf(
+			"unable to swap the handler for the '%s' projection: the new handler has a different identity (%s)",
+			p.name,
+			cfg.Identity(),
+		)
+	}
+
+	types := cfg.MessageTypes().Consumed
+	if !message.IsSubsetT(types, current.types) {
+		return fmt.Errorf(
+			"unable to swap the handler for the '%s' projection: the new handler consumes event types that are not consumed by the current handler",
+			p.name,
+		)
+	}
+
+	p.state.Store(&handlerState{
+		handler: h,
+		types:   types,
+	})
+
+	return nil
+}
+
+// logReopen logs about the stream being re-opened because the event at the
+// given offset is not consumed by the current handler.
+func (p *Projector) logReopen(offset uint64) {
+	logging.Log(
+		p.Logger,
+		"[%s %s@%d] the handler has been swapped, re-opening the stream",
+		p.name,
+		p.resource,
+		offset,
+	)
+}
@@ -0,0 +1,192 @@
+package ordered_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dodeca/logging"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type Projector (swapping handlers)", func() {
+	var (
+		ctx      context.Context
+		cancel   func()
+		stream   *MemoryStream
+		handler1 *ProjectionMessageHandler
+		handler2 *ProjectionMessageHandler
+		logger   *logging.BufferedLogger
+		proj     *Projector
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), DefaultTimeout*2)
+
+		stream = &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		stream.Append(
+			time.Now(),
+			MessageA1,
+			MessageB1,
+			MessageA2,
+		)
+
+		handler1 = &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageA{})
+				c.ConsumesEventType(MessageB{})
+			},
+		}
+
+		handler2 = &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageA{})
+			},
+		}
+
+		logger = &logging.BufferedLogger{}
+
+		proj = &Projector{
+			Stream:  stream,
+			Handler: handler1,
+			Logger:  logger,
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Describe("func SwapHandler()", func() {
+		It("uses the new handler for subsequent events", func() {
+			var (
+				version              []byte
+				messages1, messages2 []dogma.Message
+			)
+
+			resourceVersion := func(context.Context, []byte) ([]byte, error) {
+				return version, nil
+			}
+			handler1.ResourceVersionFunc = resourceVersion
+			handler2.ResourceVersionFunc = resourceVersion
+
+			handler1.HandleEventFunc = func(
+				_ context.Context,
+				_, _, n []byte,
+				_ dogma.ProjectionEventScope,
+				m dogma.Message,
+			) (bool, error) {
+				version = append([]byte(nil), n...)
+				messages1 = append(messages1, m)
+
+				if len(messages1) == 1 {
+					err := proj.SwapHandler(handler2)
+					Expect(err).ShouldNot(HaveOccurred())
+				}
+
+				return true, nil
+			}
+
+			handler2.HandleEventFunc = func(
+				_ context.Context,
+				_, _, n []byte,
+				_ dogma.ProjectionEventScope,
+				m dogma.Message,
+			) (bool, error) {
+				version = append([]byte(nil), n...)
+				messages2 = append(messages2, m)
+				cancel()
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+			Expect(messages1).To(Equal([]dogma.Message{MessageA1}))
+			Expect(messages2).To(Equal([]dogma.Message{MessageA2}))
+			Expect(logger.Messages()).To(ContainElement(
+				logging.BufferedLogMessage{
+					Message: "[<proj> <id>@1] the handler has been swapped, re-opening the stream",
+				},
+			))
+		})
+
+		It("does not re-open the stream if the consumed types are unchanged", func() {
+			handler2.ConfigureFunc = handler1.ConfigureFunc
+
+			var messages2 []dogma.Message
+
+			handler1.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				err := proj.SwapHandler(handler2)
+				Expect(err).ShouldNot(HaveOccurred())
+				return true, nil
+			}
+
+			handler2.HandleEventFunc = func(
+				_ context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				m dogma.Message,
+			) (bool, error) {
+				messages2 = append(messages2, m)
+
+				if len(messages2) == 2 {
+					cancel()
+				}
+
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+			Expect(messages2).To(Equal([]dogma.Message{MessageB1, MessageA2}))
+
+			for _, m := range logger.Messages() {
+				Expect(m.Message).NotTo(ContainSubstring("re-opening"))
+			}
+		})
+
+		It("returns an error if the new handler consumes additional event types", func() {
+			err := proj.SwapHandler(handler2)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = proj.SwapHandler(handler1)
+			Expect(err).To(MatchError(
+				"unable to swap the handler for the '<proj>' projection: the new handler consumes event types that are not consumed by the current handler",
+			))
+		})
+
+		It("returns an error if the new handler has a different identity", func() {
+			handler2.ConfigureFunc = func(c dogma.ProjectionConfigurer) {
+				c.Identity("<other>", "d3a0b1c4-55a6-4f4e-9f55-7f4bd5f4ad60")
+				c.ConsumesEventType(MessageA{})
+			}
+
+			err := proj.SwapHandler(handler2)
+			Expect(err).To(MatchError(
+				"unable to swap the handler for the '<proj>' projection: the new handler has a different identity (<other>/d3a0b1c4-55a6-4f4e-9f55-7f4bd5f4ad60)",
+			))
+		})
+
+		It("returns an error if the new handler configuration is invalid", func() {
+			handler2.ConfigureFunc = nil
+
+			err := proj.SwapHandler(handler2)
+			Expect(err).To(MatchError(
+				"*fixtures.ProjectionMessageHandler is configured without an identity, Identity() must be called exactly once within Configure()",
+			))
+		})
+	})
+})
@@ -0,0 +1,137 @@
+package ordered
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dogmatiq/dogma"
+	"github.com/dogmatiq/linger"
+)
+
+// ThrottledStream is a Stream that wraps another stream and limits the rate at
+// which events are read from it.
+//
+// The limit is shared by every cursor opened on the ThrottledStream, so it
+// enforces a read budget for all consumers of the stream, not just a single
+// projector. It is implemented as a token bucket; each event read consumes one
+// token, and tokens are replenished at Rate tokens per second up to a maximum
+// of Burst.
+type ThrottledStream struct {
+	// Stream is the underlying stream.
+	Stream Stream
+
+	// Rate is the maximum average number of events read per second. If it is
+	// non-positive, reads are not throttled.
+	Rate float64
+
+	// Burst is the maximum number of events that may be read in quick
+	// succession, without waiting. If it is less than 1, a burst of 1 is used.
+	Burst int
+
+	m      sync.Mutex
+	init   bool
+	tokens float64
+	last   time.Time
+}
+
+// ID returns a unique identifier for the stream.
+//
+// It is the same as the underlying stream's ID.
+func (s *ThrottledStream) ID() string {
+	return s.Stream.ID()
+}
+
+// Open returns a cursor used to read events from this stream.
+//
+// The offset and filter parameters have the same semantics as for the
+// underlying stream.
+func (s *ThrottledStream) Open(
+	ctx context.Context,
+	offset uint64,
+	filter []dogma.Message,
+) (Cursor, error) {
+	c, err := s.Stream.Open(ctx, offset, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &throttledCursor{c, s}, nil
+}
+
+// reserve takes a token from the bucket, and returns the time to wait before
+// it may be used.
+func (s *ThrottledStream) reserve() time.Duration {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	burst := float64(s.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	now := time.Now()
+
+	if s.init {
+		s.tokens += now.Sub(s.last).Seconds() * s.Rate
+		if s.tokens > burst {
+			s.tokens = burst
+		}
+	} else {
+		s.init = true
+		s.tokens = burst
+	}
+
+	s.last = now
+	s.tokens--
+
+	if s.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-s.tokens / s.Rate * float64(time.Second))
+}
+
+// refund returns a token that was reserved but not used.
+func (s *ThrottledStream) refund() {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.tokens++
+}
+
+// throttledCursor is a Cursor that waits for a token from its stream's token
+// bucket before reading each event.
+type throttledCursor struct {
+	cursor Cursor
+	stream *ThrottledStream
+}
+
+// Next returns the next relevant event in the stream.
+//
+// It blocks until the stream's rate limit permits another event to be read.
+// The token is taken before the event is read so that the cursor's position
+// is not advanced if ctx is canceled while waiting.
+func (c *throttledCursor) Next(ctx context.Context) (Envelope, error) {
+	if c.stream.Rate <= 0 {
+		return c.cursor.Next(ctx)
+	}
+
+	if err := linger.Sleep(ctx, c.stream.reserve()); err != nil {
+		c.stream.refund()
+		return Envelope{}, err
+	}
+
+	env, err := c.cursor.Next(ctx)
+	if err != nil {
+		c.stream.refund()
+		return Envelope{}, err
+	}
+
+	return env, nil
+}
+
+// Close stops the cursor.
+func (c *throttledCursor) Close() error {
+	return c.cursor.Close()
+}
@@ -0,0 +1,134 @@
+package ordered_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/dogmatiq/aperture/ordered"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ Stream = (*ThrottledStream)(nil)
+
+var _ = Describe("type ThrottledStream", func() {
+	var (
+		ctx    context.Context
+		cancel func()
+		mem    *MemoryStream
+		stream *ThrottledStream
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+
+		mem = &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		mem.Append(
+			time.Now(),
+			MessageA1,
+			MessageA2,
+			MessageA3,
+			MessageB1,
+			MessageB2,
+			MessageB3,
+		)
+
+		stream = &ThrottledStream{
+			Stream: mem,
+			Rate:   100,
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	// read reads n events from cur and returns the time taken.
+	read := func(cur Cursor, n int) time.Duration {
+		start := time.Now()
+
+		for i := 0; i < n; i++ {
+			_, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+
+		return time.Since(start)
+	}
+
+	Describe("func ID()", func() {
+		It("returns the ID of the underlying stream", func() {
+			Expect(stream.ID()).To(Equal("<id>"))
+		})
+	})
+
+	Describe("func Open()", func() {
+		It("limits the rate at which events are read", func() {
+			cur, err := stream.Open(ctx, 0, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			Expect(read(cur, 6)).To(BeNumerically(">=", 50*time.Millisecond))
+		})
+
+		It("allows a burst of events to be read without waiting", func() {
+			stream.Burst = 6
+
+			cur, err := stream.Open(ctx, 0, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			Expect(read(cur, 6)).To(BeNumerically("<", 40*time.Millisecond))
+		})
+
+		It("does not limit the rate if Rate is not positive", func() {
+			stream.Rate = 0
+
+			cur, err := stream.Open(ctx, 0, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			Expect(read(cur, 6)).To(BeNumerically("<", 40*time.Millisecond))
+		})
+
+		It("shares the limit between all cursors", func() {
+			cur1, err := stream.Open(ctx, 0, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur1.Close()
+
+			cur2, err := stream.Open(ctx, 0, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur2.Close()
+
+			start := time.Now()
+			read(cur1, 3)
+			read(cur2, 3)
+			Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+		})
+
+		It("does not advance the cursor if the context is canceled while waiting", func() {
+			stream.Rate = 10
+
+			cur, err := stream.Open(ctx, 0, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer cur.Close()
+
+			env, err := cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Message).To(Equal(MessageA1))
+
+			wctx, wcancel := context.WithTimeout(ctx, 10*time.Millisecond)
+			defer wcancel()
+
+			_, err = cur.Next(wctx)
+			Expect(err).To(Equal(context.DeadlineExceeded))
+
+			env, err = cur.Next(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(env.Message).To(Equal(MessageA2))
+		})
+	})
+})
@@ -0,0 +1,308 @@
+package ordered_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanNamed returns the recorded span with the given name.
+func spanNamed(recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	for _, s := range recorder.Ended() {
+		if s.Name() == name {
+			return s
+		}
+	}
+
+	Fail("no span named " + name)
+	return nil
+}
+
+var _ = Describe("type Projector (tracing)", func() {
+	var (
+		ctx      context.Context
+		cancel   func()
+		recorder *tracetest.SpanRecorder
+		provider *sdktrace.TracerProvider
+		handler  *ProjectionMessageHandler
+		proj     *Projector
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), DefaultTimeout*2)
+
+		recorder = tracetest.NewSpanRecorder()
+		provider = sdktrace.NewTracerProvider(
+			sdktrace.WithSpanProcessor(recorder),
+		)
+		tracer := provider.Tracer("<tracer>")
+
+		stream := &MemoryStream{
+			StreamID: "<id>",
+		}
+
+		stream.Append(
+			time.Now(),
+			MessageA1,
+			MessageA2,
+		)
+
+		handler = &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageA{})
+			},
+		}
+
+		proj = &Projector{
+			Stream:  stream,
+			Handler: handler,
+			Tracer:  tracer,
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Describe("func Run()", func() {
+		It("records the resume offset on the open span", func() {
+			handler.ResourceVersionFunc = func(context.Context, []byte) ([]byte, error) {
+				return []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, nil
+			}
+
+			handler.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				cancel()
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+
+			span := spanNamed(recorder, "aperture.open")
+			Expect(span.Attributes()).To(ConsistOf(
+				attribute.String("aperture.handler.name", "<proj>"),
+				attribute.String("aperture.stream.id", "<id>"),
+				attribute.Int64("aperture.stream.offset", 1),
+			))
+		})
+
+		It("marks the open span as failed if the cursor can not be opened", func() {
+			handler.ResourceVersionFunc = func(context.Context, []byte) ([]byte, error) {
+				return nil, errors.New("<error>")
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).Should(HaveOccurred())
+
+			span := spanNamed(recorder, "aperture.open")
+			Expect(span.Status().Code).To(Equal(codes.Error))
+			Expect(span.Status().Description).To(Equal("<error>"))
+		})
+
+		It("records a span for each event passed to the handler", func() {
+			handler.HandleEventFunc = func(
+				_ context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				m dogma.Message,
+			) (bool, error) {
+				if m == MessageA2 {
+					cancel()
+				}
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+
+			var offsets []int64
+			for _, s := range recorder.Ended() {
+				if s.Name() == "aperture.handle" {
+					Expect(s.Status().Code).To(Equal(codes.Unset))
+					Expect(s.Attributes()).To(ContainElements(
+						attribute.String("aperture.handler.name", "<proj>"),
+						attribute.String("aperture.handler.key", "45804515-8b41-4d23-97b1-0cda5a0d782c"),
+						attribute.String("aperture.stream.id", "<id>"),
+						attribute.String("aperture.message.type", "fixtures.MessageA"),
+					))
+
+					for _, kv := range s.Attributes() {
+						if kv.Key == "aperture.stream.offset" {
+							offsets = append(offsets, kv.Value.AsInt64())
+						}
+					}
+				}
+			}
+
+			Expect(offsets).To(Equal([]int64{0, 1}))
+		})
+
+		It("passes the span's context to the handler", func() {
+			handler.HandleEventFunc = func(
+				ctx context.Context,
+				_, _, _ []byte,
+				_ dogma.ProjectionEventScope,
+				_ dogma.Message,
+			) (bool, error) {
+				Expect(trace.SpanFromContext(ctx).SpanContext().IsValid()).To(BeTrue())
+				cancel()
+				return true, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+		})
+
+		It("marks the handle span as failed if the handler returns an error", func() {
+			handler.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				return false, errors.New("<error>")
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).Should(HaveOccurred())
+
+			span := spanNamed(recorder, "aperture.handle")
+			Expect(span.Status().Code).To(Equal(codes.Error))
+			Expect(span.Status().Description).To(Equal("<error>"))
+		})
+
+		It("marks the handle span as failed if an optimistic concurrency conflict occurs", func() {
+			handler.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				cancel()
+				return false, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+
+			span := spanNamed(recorder, "aperture.handle")
+			Expect(span.Status().Code).To(Equal(codes.Error))
+			Expect(span.Status().Description).To(Equal("optimistic concurrency conflict"))
+		})
+
+		It("records a span event if an optimistic concurrency conflict occurs", func() {
+			handler.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				cancel()
+				return false, nil
+			}
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+
+			span := spanNamed(recorder, "aperture.handle")
+			events := span.Events()
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].Name).To(Equal("occ conflict, restarting consumer"))
+			Expect(events[0].Attributes).To(ConsistOf(
+				attribute.Int64("aperture.stream.offset", 0),
+			))
+		})
+
+		It("uses the tracer provider of the span in the context if the tracer is nil", func() {
+			proj.Tracer = nil
+
+			handler.HandleEventFunc = func(
+				context.Context,
+				[]byte, []byte, []byte,
+				dogma.ProjectionEventScope,
+				dogma.Message,
+			) (bool, error) {
+				cancel()
+				return true, nil
+			}
+
+			parentCtx, parent := provider.Tracer("<parent>").Start(ctx, "<parent>")
+			err := proj.Run(parentCtx)
+			parent.End()
+			Expect(err).To(Equal(context.Canceled))
+
+			span := spanNamed(recorder, "aperture.handle")
+			Expect(span.Parent().TraceID()).To(Equal(parent.SpanContext().TraceID()))
+			Expect(span.InstrumentationScope().Name).To(Equal("github.com/dogmatiq/aperture"))
+		})
+
+		It("records a span for each batch passed to the handler", func() {
+			bh := &batchHandler{
+				ProjectionMessageHandler: *handler,
+				HandleEventBatchFunc: func(
+					ctx context.Context,
+					_, _, _ []byte,
+					_ []BatchEvent,
+				) (bool, error) {
+					Expect(trace.SpanFromContext(ctx).SpanContext().IsValid()).To(BeTrue())
+					cancel()
+					return true, nil
+				},
+			}
+
+			proj.Handler = bh
+			proj.BatchSize = 2
+
+			err := proj.Run(ctx)
+			Expect(err).To(Equal(context.Canceled))
+
+			span := spanNamed(recorder, "aperture.handle.batch")
+			Expect(span.Status().Code).To(Equal(codes.Unset))
+			Expect(span.Attributes()).To(ConsistOf(
+				attribute.String("aperture.handler.name", "<proj>"),
+				attribute.String("aperture.handler.key", "45804515-8b41-4d23-97b1-0cda5a0d782c"),
+				attribute.String("aperture.stream.id", "<id>"),
+				attribute.Int64("aperture.stream.offset", 0),
+				attribute.Int("aperture.batch.size", 2),
+			))
+		})
+
+		It("marks the batch span as failed if the handler returns an error", func() {
+			proj.Handler = &batchHandler{
+				ProjectionMessageHandler: *handler,
+				HandleEventBatchFunc: func(
+					context.Context,
+					[]byte, []byte, []byte,
+					[]BatchEvent,
+				) (bool, error) {
+					return false, errors.New("<error>")
+				},
+			}
+			proj.BatchSize = 2
+
+			err := proj.Run(ctx)
+			Expect(err).Should(HaveOccurred())
+
+			span := spanNamed(recorder, "aperture.handle.batch")
+			Expect(span.Status().Code).To(Equal(codes.Error))
+			Expect(span.Status().Description).To(Equal("<error>"))
+		})
+	})
+})
@@ -0,0 +1,46 @@
+package ordered
+
+import (
+	"fmt"
+
+	"github.com/dogmatiq/configkit"
+	"github.com/dogmatiq/configkit/message"
+	"github.com/dogmatiq/dogma"
+)
+
+// Validate checks that the projection handler h consumes at least one of the
+// event types produced by the stream s.
+//
+// A handler that consumes none of the stream's event types never receives any
+// events, which usually indicates a configuration mistake. The check is only
+// performed if s implements TypedStream, otherwise Validate() only checks that
+// h is configured correctly.
+func Validate(h dogma.ProjectionMessageHandler, s Stream) (err error) {
+	defer configkit.Recover(&err)
+
+	cfg := configkit.FromProjection(h)
+
+	ts, ok := s.(TypedStream)
+	if !ok {
+		return nil
+	}
+
+	produced := ts.Types()
+	consumed := cfg.MessageTypes().Consumed
+
+	found := false
+	consumed.Range(func(t message.Type) bool {
+		found = produced.Has(t)
+		return !found
+	})
+
+	if found {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"the '%s' projection does not consume any of the event types produced by the '%s' stream",
+		cfg.Identity().Name,
+		s.ID(),
+	)
+}
@@ -0,0 +1,72 @@
+package ordered_test
+
+import (
+	. "github.com/dogmatiq/aperture/ordered"
+	"github.com/dogmatiq/configkit/message"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/dogma/fixtures"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// typedStream is a TypedStream used for testing.
+type typedStream struct {
+	MemoryStream
+	types message.TypeCollection
+}
+
+func (s *typedStream) Types() message.TypeCollection {
+	return s.types
+}
+
+var _ TypedStream = (*typedStream)(nil)
+
+var _ = Describe("func Validate()", func() {
+	var handler *ProjectionMessageHandler
+
+	BeforeEach(func() {
+		handler = &ProjectionMessageHandler{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<proj>", "45804515-8b41-4d23-97b1-0cda5a0d782c")
+				c.ConsumesEventType(MessageA{})
+				c.ConsumesEventType(MessageB{})
+			},
+		}
+	})
+
+	It("returns nil if the handler consumes some of the stream's event types", func() {
+		stream := &typedStream{
+			MemoryStream: MemoryStream{StreamID: "<id>"},
+			types:        message.TypesOf(MessageB{}, MessageC{}),
+		}
+
+		err := Validate(handler, stream)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("returns an error if the handler consumes none of the stream's event types", func() {
+		stream := &typedStream{
+			MemoryStream: MemoryStream{StreamID: "<id>"},
+			types:        message.TypesOf(MessageC{}),
+		}
+
+		err := Validate(handler, stream)
+		Expect(err).To(MatchError(
+			"the '<proj>' projection does not consume any of the event types produced by the '<id>' stream",
+		))
+	})
+
+	It("returns nil if the stream does not report its event types", func() {
+		stream := &MemoryStream{StreamID: "<id>"}
+
+		err := Validate(handler, stream)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("returns an error if the handler is configured incorrectly", func() {
+		handler.ConfigureFunc = nil
+
+		err := Validate(handler, &MemoryStream{StreamID: "<id>"})
+		Expect(err).Should(HaveOccurred())
+	})
+})